@@ -1,27 +1,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	"url-shortener/internal/config"
 	"url-shortener/internal/database"
 	"url-shortener/internal/database/repo"
 	http_server "url-shortener/internal/http"
 	"url-shortener/internal/http/handler"
+	"url-shortener/internal/lib/botdetect"
+	"url-shortener/internal/lib/geoip"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/ratelimit"
 	"url-shortener/internal/service/auth"
 	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/live"
+	"url-shortener/internal/service/plan"
+	"url-shortener/internal/service/share"
+	"url-shortener/internal/service/transfer"
 	"url-shortener/internal/service/url"
 	"url-shortener/internal/service/user"
+	"url-shortener/internal/service/webhook"
 
-	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
+	_ "time/tzdata"        // stats can be requested in any time zone
 	_ "url-shortener/docs" // docs is generated by Swag CLI, you have to import it.
 )
 
@@ -59,11 +72,62 @@ func main() {
 	userRepo := repo.NewUserRepo(db)
 	urlRepo := repo.NewUrlRepo(db)
 	clickStatRepo := repo.NewClickStatRepo(db)
-	userService := user.New(userRepo, log)
+	transferRepo := repo.NewUrlTransferRepo(db)
+	usageRepo := repo.NewUsageRepo(db)
+	liveRepo := repo.NewLiveRepo(db)
+	shareRepo := repo.NewStatsShareRepo(db)
+	webhookRepo := repo.NewWebhookRepo(db)
+	userService := user.New(userRepo, log, user.WithAdmins(cfg.Admin.Emails))
 	jwtService := auth.NewJWTService(cfg.JwtSecret, time.Hour)
 	authService := auth.New(userService, jwtService, log)
-	urlService := url.New(urlRepo, log)
-	clickStatService := clickstat.New(clickStatRepo, log)
+	planService := plan.New(usageRepo, cfg.Plans, log)
+	webhookService := webhook.New(webhookRepo, cfg.Webhooks, log)
+	urlService := url.New(urlRepo, log, url.WithAnonymousTTL(cfg.Anonymous.TTL), url.WithMaxLinkLength(cfg.Links.MaxLength), url.WithQuota(planService), url.WithEventPublisher(webhookService))
+	liveService := live.New(liveRepo, cfg.Live, log)
+	clickSinks, closeClickSinks, err := newClickSinks(cfg.ClickSinks, clickStatRepo, log)
+	if err != nil {
+		log.Error("failed to init click sinks", sl.Err(err))
+		return
+	}
+	// deferred calls run after the click recorder is drained
+	defer closeClickSinks()
+	clickStatOpts := []clickstat.Option{
+		clickstat.WithClickSinks(clickSinks...),
+		clickstat.WithClickQuota(planService),
+		clickstat.WithClickPublisher(liveService),
+		clickstat.WithClickPublisher(webhookService),
+		clickstat.WithVisitorSecret(cfg.VisitorSecret),
+		clickstat.WithRetention(cfg.Retention, cfg.Plans),
+		clickstat.WithDedup(cfg.Dedup.Window),
+		clickstat.WithConversionWindow(cfg.Conversions.Window),
+	}
+	if cfg.GeoIP.DatabasePath != "" {
+		locator, err := geoip.Open(cfg.GeoIP.DatabasePath)
+		if err != nil {
+			log.Error("failed to open geoip database", sl.Err(err))
+			return
+		}
+		defer locator.Close()
+		clickStatOpts = append(clickStatOpts, clickstat.WithCountryLocator(locator))
+	}
+	if cfg.Bots.PatternsPath != "" {
+		bots, err := botdetect.Load(cfg.Bots.PatternsPath)
+		if err != nil {
+			log.Error("failed to load bot patterns", sl.Err(err))
+			return
+		}
+		clickStatOpts = append(clickStatOpts, clickstat.WithBotDetector(bots))
+	}
+	clickStatService := clickstat.New(clickStatRepo, log, clickStatOpts...)
+	transferService := transfer.New(transferRepo, userService, log, transfer.WithQuota(planService))
+	shareService := share.New(shareRepo, clickStatService, log)
+	clickRecorder := clickstat.NewBatchRecorder(clickStatService, cfg.ClickQueue, log)
+
+	// clicks of the next months need partitions before the first cleanup runs
+	if _, err := clickStatService.EnsureClickPartitions(); err != nil {
+		log.Error("failed to create click partitions", sl.Err(err))
+		return
+	}
 
 	// init click stats cleanup
 	_, err = clickStatService.CleanupStaleRecords()
@@ -72,16 +136,108 @@ func main() {
 		return
 	}
 
+	// init total hits reconciliation
+	if cfg.Reconcile.Schedule != "" {
+		_, err = clickStatService.ReconcileHitsOnSchedule(cfg.Reconcile.Schedule)
+		if err != nil {
+			log.Error("failed to schedule reconciliation job", sl.Err(err))
+			return
+		}
+	}
+
+	// init expired anonymous urls cleanup
+	_, err = urlService.CleanupExpired()
+	if err != nil {
+		log.Error("failed to schedule cleanup job", sl.Err(err))
+		return
+	}
+
+	// clicks recorded by other instances are streamed too
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	go liveService.Listen(listenCtx)
+
+	// webhook deliveries left in the queue are delivered after a restart
+	deliverCtx, stopDelivering := context.WithCancel(context.Background())
+	defer stopDelivering()
+	go webhookService.Run(deliverCtx)
+
+	var anonymousLimiter *ratelimit.Limiter
+	if cfg.Anonymous.Enabled {
+		anonymousLimiter = ratelimit.New(cfg.Anonymous.RateLimit.Requests, cfg.Anonymous.RateLimit.Window)
+	}
+
+	deps := &handler.Dependencies{JwtService: jwtService, UserService: userService, AuthService: authService, UrlService: urlService, ClickStatService: clickStatService, ClickRecorder: clickRecorder, TransferService: transferService, PlanService: planService, LiveService: liveService, ShareService: shareService, WebhookService: webhookService, AnonymousLimiter: anonymousLimiter}
+
+	// metrics are served on their own address, so the public server doesn't expose them
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Error("failed to get database pool", sl.Err(err))
+			return
+		}
+		registry := prometheus.NewRegistry()
+		registerMetrics(registry, sqlDB, clickRecorder, clickStatService)
+		deps.Metrics = registry
+		metricsServer = NewMetricsServer(&cfg.Metrics, registry)
+	}
+
 	// init http server
-	router := http_server.NewRouter(log, &handler.Dependencies{JwtService: jwtService, UserService: userService, AuthService: authService, UrlService: urlService, ClickStatService: clickStatService})
+	router := http_server.NewRouter(log, deps)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if err := router.SetTrustedProxies(cfg.HTTPServer.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", sl.Err(err))
+		return
+	}
 
 	server := NewServer(&cfg.HTTPServer, router)
+	// live streams don't end by themselves, so shutdown would wait for them
+	server.RegisterOnShutdown(liveService.Close)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		log.Info(fmt.Sprintf("Starting server at %s:%s", cfg.HTTPServer.Host, cfg.HTTPServer.Port))
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("server failed: %v", sl.Err(err))
+			// queued clicks are still saved
+			stop <- syscall.SIGTERM
+		}
+	}()
 
-	log.Info(fmt.Sprintf("Starting server at %s:%s", cfg.HTTPServer.Host, cfg.HTTPServer.Port))
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Error("server failed: %v", sl.Err(err))
+	if metricsServer != nil {
+		go func() {
+			log.Info(fmt.Sprintf("Serving metrics at %s", cfg.Metrics.Address))
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				// the service works without metrics
+				log.Error("metrics server failed", sl.Err(err))
+			}
+		}()
 	}
+
+	<-stop
+	log.Info("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.Timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("failed to shut down server", sl.Err(err))
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shut down metrics server", sl.Err(err))
+		}
+	}
+
+	// redirects are done, so no more clicks are coming
+	ctx, cancelDrain := context.WithTimeout(context.Background(), cfg.ClickQueue.DrainTimeout)
+	defer cancelDrain()
+	if err := clickRecorder.Close(ctx); err != nil {
+		log.Error("failed to save queued clicks", sl.Err(err), slog.Int("queued", clickRecorder.Metrics().Queued))
+	}
+	log.Info("server stopped")
 }
 
 func NewServer(cfg *config.HTTPServer, handler http.Handler) *http.Server {
@@ -94,11 +250,15 @@ func NewServer(cfg *config.HTTPServer, handler http.Handler) *http.Server {
 	}
 }
 
-func NewRouter() *gin.Engine {
-	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
-
-	return router
+// NewMetricsServer serves the metrics of the registry at /metrics
+func NewMetricsServer(cfg *config.Metrics, reg *prometheus.Registry) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"url-shortener/internal/model/dto"
+	clickstat "url-shortener/internal/service/click-stat"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// registerMetrics exposes stats kept by the services and the runtime. They are read on every scrape
+func registerMetrics(reg prometheus.Registerer, db *sql.DB, recorder *clickstat.BatchRecorder, clickStatService *clickstat.ClickStatService) {
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	factory := promauto.With(reg)
+	gauge := func(name, help string, value func() float64) {
+		factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, value)
+	}
+	counter := func(name, help string, value func() float64) {
+		factory.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, value)
+	}
+
+	// click recording queue
+	gauge("click_queue_depth", "Clicks waiting in the queue to be recorded.", func() float64 {
+		return float64(recorder.Metrics().Queued)
+	})
+	gauge("click_queue_capacity", "Capacity of the click queue.", func() float64 {
+		return float64(recorder.Metrics().Capacity)
+	})
+	counter("click_queue_enqueued_total", "Clicks put into the queue.", func() float64 {
+		return float64(recorder.Metrics().Enqueued)
+	})
+	counter("click_queue_dropped_total", "Clicks dropped because the queue was full.", func() float64 {
+		return float64(recorder.Metrics().Dropped)
+	})
+	counter("click_queue_recorded_total", "Clicks saved by the queue workers.", func() float64 {
+		return float64(recorder.Metrics().Recorded)
+	})
+	counter("click_queue_failed_total", "Clicks rejected by the quota or failed to be saved.", func() float64 {
+		return float64(recorder.Metrics().Failed)
+	})
+	counter("click_sink_failed_total", "Recorded clicks that secondary click sinks failed to write.", func() float64 {
+		return float64(recorder.Metrics().SinkFailed)
+	})
+	counter("click_queue_batches_total", "Batches of clicks saved by the queue workers.", func() float64 {
+		return float64(recorder.Metrics().Batches)
+	})
+
+	// database pool
+	gauge("db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	gauge("db_open_connections", "Established connections to the database, in use and idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	gauge("db_in_use_connections", "Connections to the database currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	gauge("db_idle_connections", "Idle connections to the database.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	counter("db_wait_count_total", "Connections waited for.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	counter("db_wait_duration_seconds_total", "Time spent waiting for connections.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+	counter("db_max_idle_closed_total", "Connections closed because of the idle connections limit.", func() float64 {
+		return float64(db.Stats().MaxIdleClosed)
+	})
+	counter("db_max_idle_time_closed_total", "Connections closed because of the idle time limit.", func() float64 {
+		return float64(db.Stats().MaxIdleTimeClosed)
+	})
+	counter("db_max_lifetime_closed_total", "Connections closed because of the lifetime limit.", func() float64 {
+		return float64(db.Stats().MaxLifetimeClosed)
+	})
+
+	// click stats cleanup job
+	counter("cleanup_succeeded_runs_total", "Succeeded runs of the click stats cleanup.", func() float64 {
+		return float64(clickStatService.CleanupStatus().SucceededRuns)
+	})
+	counter("cleanup_failed_runs_total", "Failed runs of the click stats cleanup.", func() float64 {
+		return float64(clickStatService.CleanupStatus().FailedRuns)
+	})
+	lastRun := func(value func(run *dto.CleanupRun) float64) func() float64 {
+		return func() float64 {
+			run := clickStatService.CleanupStatus().LastRun
+			if run == nil || run.FinishedAt == nil {
+				return 0
+			}
+			return value(run)
+		}
+	}
+	gauge("cleanup_last_run_finished_timestamp_seconds", "Time the last cleanup run finished, 0 until it finishes once.", lastRun(func(run *dto.CleanupRun) float64 {
+		return float64(run.FinishedAt.Unix())
+	}))
+	gauge("cleanup_last_run_success", "Whether the last finished cleanup run succeeded.", lastRun(func(run *dto.CleanupRun) float64 {
+		if run.Status == dto.CleanupSucceeded {
+			return 1
+		}
+		return 0
+	}))
+	gauge("cleanup_last_run_duration_seconds", "Duration of the last finished cleanup run.", lastRun(func(run *dto.CleanupRun) float64 {
+		return run.FinishedAt.Sub(run.StartedAt).Seconds()
+	}))
+	gauge("cleanup_last_run_rolled_up_clicks", "Raw clicks rolled up by the last finished cleanup run.", lastRun(func(run *dto.CleanupRun) float64 {
+		return float64(run.RolledUpClicks)
+	}))
+	gauge("cleanup_last_run_deleted_sketches", "Visitor sketches deleted by the last finished cleanup run.", lastRun(func(run *dto.CleanupRun) float64 {
+		return float64(run.DeletedSketches)
+	}))
+}
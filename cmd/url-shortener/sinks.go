@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/sl"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/clicksink"
+)
+
+// newClickSinks creates the sinks enabled in the config in their order. The returned function closes them,
+// it has to be called after the click recorder is closed
+func newClickSinks(cfg config.ClickSinks, repo clickstat.ClickStatRepo, log *slog.Logger) ([]clickstat.ClickSink, func(), error) {
+	var sinks []clickstat.ClickSink
+	var files []*clicksink.File
+	closeSinks := func() {
+		for _, file := range files {
+			if err := file.Close(); err != nil {
+				log.Error("failed to close click file", sl.Err(err))
+			}
+		}
+	}
+
+	for _, name := range cfg.Enabled {
+		switch name {
+		case "postgres":
+			sinks = append(sinks, clickstat.NewPostgresSink(repo))
+		case "file":
+			file, err := clicksink.NewFile(cfg.File, log)
+			if err != nil {
+				closeSinks()
+				return nil, nil, err
+			}
+			files = append(files, file)
+			sinks = append(sinks, file)
+		case "memory":
+			sinks = append(sinks, clicksink.NewMemory(cfg.MemoryLimit))
+		default:
+			closeSinks()
+			return nil, nil, fmt.Errorf("unknown click sink %q", name)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, nil, errors.New("no click sinks are enabled")
+	}
+
+	return sinks, closeSinks, nil
+}
@@ -15,44 +15,36 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
-            "post": {
-                "consumes": [
-                    "application/json"
+        "/admin/cleanup": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
                 ],
+                "description": "The last run since the start of the server with numbers of rolled up clicks and deleted visitor sketches",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Authorizes the user",
-                "parameters": [
-                    {
-                        "description": "Login credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/login.Request"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Get status of the click cleanup job",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.SuccessResponse"
+                            "$ref": "#/definitions/cleanup.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -60,86 +52,83 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/me": {
+        "/admin/reconcile": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
+                "description": "Total hits are compared with raw and rolled up clicks without bots. Nothing is repaired, at most 100 urls are listed",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Get user data by token",
+                "summary": "Find urls whose total hits drifted from their clicks",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/me.SuccessResponse"
+                            "$ref": "#/definitions/drift.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/register": {
+            },
             "post": {
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "Bearer": []
+                    }
                 ],
+                "description": "Total hits of drifted urls are set to their raw and rolled up clicks without bots, the same repair is run by the scheduled job",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Registers the user",
-                "parameters": [
-                    {
-                        "description": "Register credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "Repair total hits of urls",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/register.Request"
+                            "$ref": "#/definitions/reconcile.SuccessResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/register.SuccessResponse"
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -147,67 +136,85 @@ const docTemplate = `{
                 }
             }
         },
-        "/url": {
+        "/anonymous/{id}": {
             "get": {
-                "security": [
-                    {
-                        "Bearer": []
-                    }
-                ],
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "anonymous"
                 ],
-                "summary": "Get user's short urls",
+                "summary": "Get an anonymous short url with its stats",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "offset",
-                        "name": "offset",
-                        "in": "query"
+                        "type": "string",
+                        "description": "claim token returned on creation",
+                        "name": "X-Claim-Token",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.PublicUrl"
-                            }
+                            "$ref": "#/definitions/manage.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "anonymous"
+                ],
+                "summary": "Delete an anonymous short url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    {
+                        "type": "string",
+                        "description": "claim token returned on creation",
+                        "name": "X-Claim-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/login": {
             "post": {
-                "security": [
-                    {
-                        "Bearer": []
-                    }
-                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -215,25 +222,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "auth"
                 ],
-                "summary": "Create a short url",
+                "summary": "Authorizes the user",
                 "parameters": [
                     {
-                        "description": "alias is optional",
+                        "description": "Login credentials",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/create.Request"
+                            "$ref": "#/definitions/login.Request"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/create.SuccessResponse"
+                            "$ref": "#/definitions/login.SuccessResponse"
                         }
                     },
                     "400": {
@@ -242,20 +249,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -263,37 +258,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/url/{id}": {
+        "/auth/me": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
+                "description": "Includes usage of the user's plan. Limit 0 means unlimited",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "url"
-                ],
-                "summary": "Get user's url stats",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "short url id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Get user data by token",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/repo.DailyCount"
-                            }
+                            "$ref": "#/definitions/me.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
                     "404": {
@@ -303,35 +299,46 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "Bearer": []
-                    }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "auth"
                 ],
-                "summary": "Remove user's short url",
+                "summary": "Registers the user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "short url id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Register credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/register.Request"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/register.SuccessResponse"
+                        }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -339,12 +346,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/{alias}": {
+        "/c/{alias}": {
             "get": {
+                "description": "Landing pages embed the pixel after a signup or another goal, e.g. \u003cimg src=\"/c/{alias}?click_token={token}\"\u003e.\nThe conversion is attributed to the click by the token passed to the landing page at the redirect.\nThe pixel is returned even if the conversion isn't recorded, so it never breaks the page",
                 "produces": [
-                    "application/json"
+                    "image/gif"
                 ],
-                "summary": "Redirect",
+                "tags": [
+                    "conversion"
+                ],
+                "summary": "Conversion pixel",
                 "parameters": [
                     {
                         "type": "string",
@@ -352,11 +363,302 @@ const docTemplate = `{
                         "name": "alias",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "click token from the query of the landing page",
+                        "name": "click_token",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Found"
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "description": "The API of the conversion pixel for scripts of landing pages, e.g. navigator.sendBeacon(\"/c/{alias}?click_token={token}\").\nThe conversion is attributed to the click by the token passed to the landing page at the redirect,\na click converts only once. Visitors without the token aren't attributed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "conversion"
+                ],
+                "summary": "Record conversion",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "alias for long url",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "click token from the query of the landing page",
+                        "name": "click_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/track.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/stats/{token}": {
+            "get": {
+                "description": "The same series as the owner gets from /url/{id}, authorized by the share token instead of login",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "Get shared url stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets and dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.DailyCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/stats/{token}/breakdown/{dimension}": {
+            "get": {
+                "description": "Forbidden when the owner hid breakdowns of the share",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "Get clicks of shared url stats by dimension",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "referrer",
+                            "browser",
+                            "os",
+                            "device",
+                            "language",
+                            "country"
+                        ],
+                        "type": "string",
+                        "description": "click dimension",
+                        "name": "dimension",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.DimensionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/compare": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Zero-filled series of clicks and unique visitors of every url with the same buckets, totals and shares of clicks\nin percents. Ranges are resolved like by the url stats, the last 30 days by day are returned by default",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Compare stats of user's urls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "comma separated short url ids, at most 10",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets and dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/compare.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
                     },
                     "404": {
                         "description": "Not Found",
@@ -366,125 +668,2551 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.ErrorResponse": {
+        },
+        "/stats/export": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Series of clicks by buckets or raw clicks with all their dimensions as CSV or NDJSON, streamed as they're read.\nOnly clicks younger than the retention of the plan are available raw, older ones are in the series only.\nSeries of all urls have no uniques",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Export stats of user's url or of all user's urls",
+                "parameters": [
+                    {
+                        "enum": [
+                            "series",
+                            "clicks"
+                        ],
+                        "type": "string",
+                        "default": "series",
+                        "description": "series or raw clicks",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size of the series",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets, dates and click times",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/live": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Server-sent events named \"click\" with the click as json data are pushed as clicks are recorded.\nClicks recorded before the stream is opened aren't sent, a slow client misses clicks",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Stream clicks of user's url or of all user's urls",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LiveClick"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/overview": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Total clicks, zero-filled series of clicks, top urls by clicks and numbers of created urls in the range\ncompared with the previous period of the same length. The last 30 days by day are returned by default",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get stats of all user's urls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets and dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "number of top urls, at most 100",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/overview.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfer": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Get user's incoming and outgoing pending transfers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicTransfer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Ownership changes only after the recipient accepts the transfer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Offer user's short urls to another user",
+                "parameters": [
+                    {
+                        "description": "recipient's email and url ids",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/offer.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/offer.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfer/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Cancel an outgoing or decline an incoming transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "transfer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfer/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Urls become owned by the current user together with their click stats",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Accept an incoming transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "transfer id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/accept.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get user's short urls",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicUrl"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Without authorization (if enabled) creates an expiring url and returns its claim token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Create a short url",
+                "parameters": [
+                    {
+                        "description": "alias is optional",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/create.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/create.AnonymousSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Zero-filled series of clicks and unique visitors. The last 30 days by day are returned by default.\nClicks older than the retention of the plan are kept as daily rollups, so by hour they aren't available.\nUniques are counted by UTC days and aren't available by hour",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get user's url stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets and dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.DailyCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Remove user's short url",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The change is appended to the url's history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Change the destination of user's short url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new destination",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/update.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/update.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/breakdown/{dimension}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Most popular values first. Clicks without a value are counted as \"unknown\"",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get clicks of user's url by dimension",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "referrer",
+                            "browser",
+                            "os",
+                            "device",
+                            "language",
+                            "country"
+                        ],
+                        "type": "string",
+                        "description": "click dimension",
+                        "name": "dimension",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repo.DimensionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/claim": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The url stops expiring and its claim token stops working",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Claim an anonymous short url into user's account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "claim token returned on anonymous creation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/claim.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/claim.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/dedup": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Clicks of the same visitor within the window after a counted click are duplicates. They're redirected and counted only as raw clicks.\nThe window is in seconds, 0 disables de-duplication and an empty window resets it to the global one.\nThe change is recorded in the url history, the global window is an empty value",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Set the de-duplication window of user's short url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "window in seconds",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dedup.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dedup.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/export": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Series of clicks by buckets or raw clicks with all their dimensions as CSV or NDJSON, streamed as they're read.\nOnly clicks younger than the retention of the plan are available raw, older ones are in the series only.\nSeries of all urls have no uniques",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Export stats of user's url or of all user's urls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id, only for /url/{id}/export",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "enum": [
+                            "series",
+                            "clicks"
+                        ],
+                        "type": "string",
+                        "default": "series",
+                        "description": "series or raw clicks",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "bucket size of the series",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of buckets, dates and click times",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/heatmap": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "7×24 clicks by weekday from Monday and hour in tz. The last 30 days are returned by default.\nClicks older than the retention of the plan are kept as daily rollups, so they aren't counted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get clicks of user's url by weekday and hour",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of weekdays, hours and dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/heatmap.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Newest changes first. Destination changes include clicks made while they were live, rolled up days of clicks count in the destination live at the end of the UTC day",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get the change history of user's short url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicUrlHistory"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/history/{historyId}/rollback": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Roll back user's short url to a previous destination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "history entry id whose destination becomes live",
+                        "name": "historyId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rollback.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/live": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Server-sent events named \"click\" with the click as json data are pushed as clicks are recorded.\nClicks recorded before the stream is opened aren't sent, a slow client misses clicks",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Stream clicks of user's url or of all user's urls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id, only for /url/{id}/live",
+                        "name": "id",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LiveClick"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/share": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Newest shares first. Tokens aren't returned",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "share"
+                ],
+                "summary": "Get shares of user's url stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicShare"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The token opens /public/stats/{token} without login until the share is revoked.\nIt's returned only once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "share"
+                ],
+                "summary": "Share stats of user's url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "whether breakdowns by dimension are hidden",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/grant.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/grant.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/share/{shareId}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "share"
+                ],
+                "summary": "Revoke a share of user's url stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "share id",
+                        "name": "shareId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{id}/top": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Most popular values in the range first. Clicks without a value are counted as \"unknown\".\nThe last 30 days are returned by default",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get top referrers and countries of user's url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short url id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC 3339 time or date in tz",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC 3339 time or date in tz (inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone of dates",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "count clicks of bots, link unfurlers, monitors and prefetching",
+                        "name": "include_bots",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "number of values of each dimension, at most 100",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/top.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Newest webhooks first. Secrets aren't returned",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get user's webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicWebhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Events of user's urls are posted to the endpoint as JSON. The X-Webhook-Signature header is\n\"sha256=\" and hex HMAC-SHA256 of X-Webhook-Timestamp, a dot and the body keyed by the secret.\nThe secret is returned only once. Failed deliveries are retried with exponential backoff",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "endpoint and events: url.created, url.updated, url.deleted, click.recorded, click.milestone",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscribe.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/subscribe.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Queued deliveries are dropped with the delivery log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Newest deliveries first. Pending deliveries are retried at nextAttemptAt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get the delivery log of user's webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "number of deliveries, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PublicWebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "A new delivery of the same payload is queued, the log of the original one is kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Deliver the event of a delivery again",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "delivery id",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/redeliver.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}": {
+            "get": {
+                "description": "HEAD and prefetch requests are redirected too, their clicks are recorded as bots.\nGET requests pass the click token to the destination in the click_token query parameter,\nlanding pages report conversions with it, see /c/{alias}",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Redirect",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "alias for long url",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "accept.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "urlIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "claim.Request": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "claim.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "cleanup.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "failedRuns": {
+                    "type": "integer"
+                },
+                "lastRun": {
+                    "description": "LastRun is empty until the job runs once after the start",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.CleanupRun"
+                        }
+                    ]
+                },
+                "nextRunAt": {
+                    "description": "NextRunAt is empty until the job is scheduled",
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "succeededRuns": {
+                    "description": "SucceededRuns and FailedRuns are counted since the start",
+                    "type": "integer"
+                }
+            }
+        },
+        "compare.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "granularity": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "totalClicks": {
+                    "type": "integer"
+                },
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ComparedUrl"
+                    }
+                }
+            }
+        },
+        "create.AnonymousSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "claimToken": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "create.Request": {
+            "type": "object",
+            "required": [
+                "link"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "maxLength": 16
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow overrides the global de-duplication window of clicks in seconds, 0 disables de-duplication",
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 0
+                },
+                "link": {
+                    "type": "string"
+                }
+            }
+        },
+        "create.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dedup.Request": {
+            "type": "object",
+            "properties": {
+                "window": {
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 0
+                }
+            }
+        },
+        "dedup.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "drift.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "driftedUrls": {
+                    "type": "integer"
+                },
+                "urls": {
+                    "description": "Urls are the first drifted urls by alias",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.HitsDrift"
+                    }
+                }
+            }
+        },
+        "dto.CleanupRun": {
+            "type": "object",
+            "properties": {
+                "createdPartitions": {
+                    "description": "CreatedPartitions and DroppedPartitions are monthly partitions of raw clicks",
+                    "type": "integer"
+                },
+                "deletedSketches": {
+                    "type": "integer"
+                },
+                "droppedPartitions": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "rolledUpClicks": {
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ComparedUrl": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "conversionRate": {
+                    "description": "ConversionRate can exceed 100, conversions aren't filtered out like clicks of bots and duplicates",
+                    "type": "number"
+                },
+                "conversions": {
+                    "type": "integer"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DailyCount"
+                    }
+                },
+                "share": {
+                    "type": "number"
+                },
+                "totalClicks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.CreateUser": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                }
+            }
+        },
+        "dto.LiveClick": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "bot": {
+                    "type": "boolean"
+                },
+                "browser": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "device": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "referrer": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PlanUsage": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "$ref": "#/definitions/dto.UsageLimit"
+                },
+                "links": {
+                    "$ref": "#/definitions/dto.UsageLimit"
+                },
+                "monthlyClicks": {
+                    "$ref": "#/definitions/dto.UsageLimit"
+                },
+                "plan": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PublicShare": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "hideBreakdowns": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "urlId": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PublicTransfer": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "urlIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.PublicUrl": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.PublicUrlHistory": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "newValue": {
+                    "type": "string"
+                },
+                "oldValue": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PublicUser": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.PublicUrl"
+                    }
+                }
+            }
+        },
+        "dto.PublicWebhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PublicWebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastAttemptAt": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "description": "NextAttemptAt is set only for pending deliveries",
+                    "type": "string"
+                },
+                "responseStatus": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UsageLimit": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "grant.Request": {
+            "type": "object",
+            "properties": {
+                "hideBreakdowns": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "grant.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "hideBreakdowns": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "urlId": {
+                    "type": "string"
+                }
+            }
+        },
+        "heatmap.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "tz": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.Request": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.SuccessResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "token": {
                     "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/dto.PublicUser"
                 }
             }
         },
-        "create.Request": {
+        "manage.SuccessResponse": {
             "type": "object",
-            "required": [
-                "link"
-            ],
             "properties": {
-                "alias": {
-                    "type": "string",
-                    "maxLength": 16
+                "stats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DailyCount"
+                    }
                 },
-                "link": {
-                    "type": "string"
+                "url": {
+                    "$ref": "#/definitions/dto.PublicUrl"
                 }
             }
         },
-        "create.SuccessResponse": {
+        "me.SuccessResponse": {
             "type": "object",
             "properties": {
-                "alias": {
-                    "type": "string"
-                },
-                "link": {
-                    "type": "string"
+                "usage": {
+                    "$ref": "#/definitions/dto.PlanUsage"
                 },
-                "totalHits": {
-                    "type": "integer"
+                "user": {
+                    "$ref": "#/definitions/dto.PublicUser"
                 }
             }
         },
-        "dto.CreateUser": {
+        "offer.Request": {
             "type": "object",
             "required": [
                 "email",
-                "password"
+                "urlIDs"
             ],
             "properties": {
                 "email": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string",
-                    "maxLength": 72,
-                    "minLength": 8
+                "urlIDs": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "dto.PublicUrl": {
+        "offer.SuccessResponse": {
             "type": "object",
             "properties": {
-                "alias": {
+                "createdAt": {
                     "type": "string"
                 },
-                "link": {
+                "from": {
                     "type": "string"
                 },
-                "totalHits": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dto.PublicUser": {
-            "type": "object",
-            "properties": {
-                "email": {
+                "id": {
                     "type": "string"
                 },
-                "id": {
+                "status": {
                     "type": "string"
                 },
-                "urls": {
+                "to": {
+                    "type": "string"
+                },
+                "urlIds": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dto.PublicUrl"
+                        "type": "string"
                     }
                 }
             }
         },
-        "login.Request": {
+        "overview.SuccessResponse": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "clicksChange": {
+                    "type": "number"
+                },
+                "from": {
                     "type": "string"
                 },
-                "password": {
+                "newUrls": {
+                    "type": "integer"
+                },
+                "newUrlsChange": {
+                    "type": "number"
+                },
+                "previousNewUrls": {
+                    "type": "integer"
+                },
+                "previousTotalClicks": {
+                    "type": "integer"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DailyCount"
+                    }
+                },
+                "to": {
                     "type": "string"
+                },
+                "topUrls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.UrlClicks"
+                    }
+                },
+                "totalClicks": {
+                    "type": "integer"
                 }
             }
         },
-        "login.SuccessResponse": {
+        "reconcile.SuccessResponse": {
             "type": "object",
             "properties": {
-                "token": {
-                    "type": "string"
+                "repairedUrls": {
+                    "type": "integer"
                 },
-                "user": {
-                    "$ref": "#/definitions/dto.PublicUser"
+                "urls": {
+                    "description": "Urls are the first repaired urls by alias with hits before the repair",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.HitsDrift"
+                    }
                 }
             }
         },
-        "me.SuccessResponse": {
+        "redeliver.SuccessResponse": {
             "type": "object",
             "properties": {
-                "user": {
-                    "$ref": "#/definitions/dto.PublicUser"
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastAttemptAt": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "description": "NextAttemptAt is set only for pending deliveries",
+                    "type": "string"
+                },
+                "responseStatus": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
                 }
             }
         },
@@ -513,11 +3241,190 @@ const docTemplate = `{
         "repo.DailyCount": {
             "type": "object",
             "properties": {
+                "conversions": {
+                    "type": "integer"
+                },
                 "count": {
                     "type": "integer"
                 },
                 "day": {
                     "type": "string"
+                },
+                "raw": {
+                    "type": "integer"
+                },
+                "uniques": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.DimensionCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "repo.HitsDrift": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "baseline": {
+                    "description": "Baseline is the part of total hits counted before their clicks were kept, see BaselineHits",
+                    "type": "integer"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repo.UrlClicks": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "link": {
+                    "type": "string"
+                }
+            }
+        },
+        "rollback.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscribe.Request": {
+            "type": "object",
+            "required": [
+                "endpoint",
+                "events"
+            ],
+            "properties": {
+                "endpoint": {
+                    "type": "string",
+                    "maxLength": 2048
+                },
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "subscribe.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "top.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DimensionCount"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "referrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DimensionCount"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "track.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "attributed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "update.Request": {
+            "type": "object",
+            "required": [
+                "link"
+            ],
+            "properties": {
+                "link": {
+                    "type": "string"
+                }
+            }
+        },
+        "update.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedupWindow": {
+                    "description": "DedupWindow is empty when the global window is used",
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "totalHits": {
+                    "type": "integer"
                 }
             }
         }
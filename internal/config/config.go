@@ -10,10 +10,28 @@ import (
 )
 
 type Config struct {
-	Env        string     `yaml:"env" env-default:"local"`
-	JwtSecret  string     `env:"JWT_SECRET" env-required:"true"`
-	Postgres   Postgres   `yaml:"postgres"`
-	HTTPServer HTTPServer `yaml:"http_server"`
+	Env       string `yaml:"env" env-default:"local"`
+	JwtSecret string `env:"JWT_SECRET" env-required:"true"`
+	// VisitorSecret keys hashes of unique visitors and click tokens. A random one is used when it's empty,
+	// so restarts overcount uniques of the day and drop conversions of earlier clicks
+	VisitorSecret string      `env:"VISITOR_SECRET"`
+	Postgres      Postgres    `yaml:"postgres"`
+	HTTPServer    HTTPServer  `yaml:"http_server"`
+	Anonymous     Anonymous   `yaml:"anonymous"`
+	Plans         Plans       `yaml:"plans"`
+	Links         Links       `yaml:"links"`
+	GeoIP         GeoIP       `yaml:"geoip"`
+	ClickQueue    ClickQueue  `yaml:"click_queue"`
+	ClickSinks    ClickSinks  `yaml:"click_sinks"`
+	Retention     Retention   `yaml:"retention"`
+	Reconcile     Reconcile   `yaml:"reconcile"`
+	Admin         Admin       `yaml:"admin"`
+	Live          Live        `yaml:"live"`
+	Bots          Bots        `yaml:"bots"`
+	Dedup         Dedup       `yaml:"dedup"`
+	Conversions   Conversions `yaml:"conversions"`
+	Webhooks      Webhooks    `yaml:"webhooks"`
+	Metrics       Metrics     `yaml:"metrics"`
 }
 
 type Postgres struct {
@@ -30,6 +48,147 @@ type HTTPServer struct {
 	Port        string        `yaml:"port" env-default:"8080"`
 	Timeout     time.Duration `yaml:"timeout" env-required:"true"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-required:"true"`
+	// TrustedProxies are addresses or networks of proxies whose X-Forwarded-For sets client ips.
+	// The header is spoofable, so no proxy is trusted by default
+	TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" env-separator:","`
+}
+
+type Links struct {
+	// MaxLength is the limit of destinations in characters
+	MaxLength int `yaml:"max_length" env-default:"2048"`
+}
+
+// GeoIP configures resolving countries of clicks. Countries aren't recorded without a database
+type GeoIP struct {
+	// DatabasePath is a path to a MaxMind-format database, e.g. GeoLite2-Country.mmdb
+	DatabasePath string `yaml:"database_path" env:"GEOIP_DATABASE_PATH"`
+}
+
+// ClickQueue configures recording of clicks in batches off the redirect path
+type ClickQueue struct {
+	Size          int           `yaml:"size" env-default:"10000"`
+	BatchSize     int           `yaml:"batch_size" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+	Workers       int           `yaml:"workers" env-default:"2"`
+	// DrainTimeout limits saving of queued clicks on shutdown
+	DrainTimeout time.Duration `yaml:"drain_timeout" env-default:"10s"`
+}
+
+// ClickSinks selects where recorded clicks are written, any combination of postgres, file and memory.
+// Stats, unique visitors and total hits are only kept with postgres
+type ClickSinks struct {
+	Enabled []string `yaml:"enabled" env-default:"postgres"`
+	File    FileSink `yaml:"file"`
+	// MemoryLimit is the number of the latest clicks kept by the memory sink, 0 keeps all of them
+	MemoryLimit int `yaml:"memory_limit" env-default:"10000"`
+}
+
+// FileSink writes clicks to NDJSON files in the directory. A file is completed when it reaches the size or the age,
+// files still being written have the .part suffix
+type FileSink struct {
+	Dir     string        `yaml:"dir" env-default:"data/clicks"`
+	MaxSize int64         `yaml:"max_size" env-default:"67108864"`
+	MaxAge  time.Duration `yaml:"max_age" env-default:"1h"`
+}
+
+// Retention configures cleanup of raw clicks. Clicks are rolled up by day before they're deleted.
+// Raw clicks are partitioned by month, partitions older than the longest retention are dropped as a whole
+type Retention struct {
+	// Schedule is a cron expression of the cleanup job
+	Schedule  string        `yaml:"schedule" env-default:"0 2 * * *"`
+	RawClicks time.Duration `yaml:"raw_clicks" env-default:"720h"`
+	// BatchSize limits rows deleted by one statement, so locks are held briefly
+	BatchSize int `yaml:"batch_size" env-default:"10000"`
+	// PartitionsAhead is the number of monthly partitions of clicks created ahead of the current month
+	PartitionsAhead int `yaml:"partitions_ahead" env-default:"2"`
+}
+
+// Reconcile configures the job repairing total hits of urls that drifted from their recorded clicks
+type Reconcile struct {
+	// Schedule is a cron expression of the job, empty disables it. Drift is reported by the admin api without the job
+	Schedule string `yaml:"schedule"`
+}
+
+// Live configures streaming of clicks to their owners. Instances share clicks through the Postgres channel
+type Live struct {
+	Channel string `yaml:"channel" env-default:"live_clicks"`
+	// Buffer is the number of clicks a slow stream can fall behind before it misses clicks
+	Buffer int `yaml:"buffer" env-default:"100"`
+	// Heartbeat keeps idle streams open through proxies
+	Heartbeat time.Duration `yaml:"heartbeat" env-default:"15s"`
+}
+
+// Bots configures classification of clicks by user agents in addition to HEAD and prefetch requests
+type Bots struct {
+	// PatternsPath is a file of user agent patterns, see config/bot-patterns.txt. Only obvious bots are detected without it
+	PatternsPath string `yaml:"patterns_path" env:"BOT_PATTERNS_PATH"`
+}
+
+// Dedup configures de-duplication of repeated clicks of the same visitor. Duplicates are redirected and counted only as raw clicks
+type Dedup struct {
+	// Window is the global window, urls can override it. 0 counts every click unless the url sets its own window
+	Window time.Duration `yaml:"window" env-default:"0s"`
+}
+
+// Conversions configures attribution of conversions reported by landing pages to clicks
+type Conversions struct {
+	// Window is the lifetime of click tokens passed to destinations at redirects, later conversions aren't attributed
+	Window time.Duration `yaml:"window" env-default:"720h"`
+}
+
+// Webhooks configures delivery of events to endpoints of users. Deliveries are queued in Postgres,
+// so they survive restarts, and failed ones are retried with exponential backoff
+type Webhooks struct {
+	Workers      int           `yaml:"workers" env-default:"2"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	// BatchSize is the number of deliveries a worker claims at once
+	BatchSize   int           `yaml:"batch_size" env-default:"20"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"8"`
+	// Backoff is the delay of the first retry, it doubles with every attempt up to MaxBackoff
+	Backoff    time.Duration `yaml:"backoff" env-default:"30s"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"6h"`
+	// Milestones are total hits of a url reported by click.milestone events
+	Milestones []int64 `yaml:"milestones" env-default:"100,1000,10000,100000,1000000"`
+}
+
+// Metrics configures the Prometheus /metrics endpoint. It isn't authorized, so it's served on its own address
+// that shouldn't be reachable from the internet
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED" env-default:"false"`
+	Address string `yaml:"address" env:"METRICS_ADDRESS" env-default:"localhost:9090"`
+}
+
+// Admin users are allowed to use /admin endpoints
+type Admin struct {
+	Emails []string `yaml:"emails" env:"ADMIN_EMAILS" env-separator:","`
+}
+
+// Anonymous configures creation of urls without an account
+type Anonymous struct {
+	Enabled   bool          `yaml:"enabled" env-default:"false"`
+	TTL       time.Duration `yaml:"ttl" env-default:"720h"`
+	RateLimit RateLimit     `yaml:"rate_limit"`
+}
+
+type RateLimit struct {
+	Requests int           `yaml:"requests" env-default:"5"`
+	Window   time.Duration `yaml:"window" env-default:"1h"`
+}
+
+// Plans are assigned to users by users.plan. Users without a known plan get the default one
+type Plans struct {
+	Default string          `yaml:"default" env-default:"free"`
+	Tiers   map[string]Plan `yaml:"tiers"`
+}
+
+// Plan limits, 0 means unlimited
+type Plan struct {
+	MaxLinks         int64 `yaml:"max_links"`
+	MaxAliases       int64 `yaml:"max_aliases"`
+	MaxMonthlyClicks int64 `yaml:"max_monthly_clicks"`
+	// RawClickRetention overrides Retention.RawClicks for urls of the plan's users, 0 means the global one
+	RawClickRetention time.Duration `yaml:"raw_click_retention"`
 }
 
 func MustLoad() *Config {
@@ -1,13 +1,140 @@
 package database
 
 import (
+	"fmt"
+	"time"
+	"url-shortener/internal/database/repo"
 	"url-shortener/internal/model"
 
 	"gorm.io/gorm"
 )
 
+type migration struct {
+	id string
+	up func(tx *gorm.DB) error
+}
+
+// migrations run once each after AutoMigrate, in order. Append only
+var migrations = []migration{
+	{
+		// AutoMigrate created urls.link as varchar(255) before
+		id: "0001_urls_link_text",
+		up: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE urls ALTER COLUMN link TYPE text").Error
+		},
+	},
+	{
+		// urls.created_at was added with the time of the migration, the creation is in the history
+		id: "0002_urls_created_at",
+		up: func(tx *gorm.DB) error {
+			return tx.Exec(`UPDATE urls SET created_at = url_histories.created_at
+				FROM url_histories
+				WHERE url_histories.url_id = urls.id AND url_histories.action = ?`, model.HistoryCreate).Error
+		},
+	},
+	{
+		// AutoMigrate adds click_rollups.bot without adding it to the existing primary key
+		id: "0003_click_rollups_bot_key",
+		up: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE click_rollups DROP CONSTRAINT click_rollups_pkey,
+				ADD PRIMARY KEY (url_id, day, referrer_host, browser, os, device, language, country, bot)`).Error
+		},
+	},
+	{
+		// click_stats is partitioned by month, so retention drops old partitions instead of deleting rows.
+		// The index and the foreign key keep their names, so AutoMigrate finds them on the partitioned table
+		id: "0004_click_stats_partitioned",
+		up: func(tx *gorm.DB) error {
+			var oldest *time.Time
+			if err := tx.Raw("SELECT MIN(created_at) FROM click_stats").Scan(&oldest).Error; err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			if oldest == nil || oldest.After(now) {
+				oldest = &now
+			}
+
+			for _, stmt := range []string{
+				"ALTER TABLE click_stats RENAME TO click_stats_unpartitioned",
+				"ALTER INDEX idx_url_created RENAME TO idx_url_created_unpartitioned",
+				"ALTER TABLE click_stats_unpartitioned RENAME CONSTRAINT fk_urls_click_stats TO fk_urls_click_stats_unpartitioned",
+				"CREATE TABLE click_stats (LIKE click_stats_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (created_at)",
+				"CREATE INDEX idx_url_created ON click_stats (url_id, created_at)",
+				"ALTER TABLE click_stats ADD CONSTRAINT fk_urls_click_stats FOREIGN KEY (url_id) REFERENCES urls(id) ON DELETE CASCADE",
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+
+			// the cleanup creates partitions of the next months later
+			if _, err := repo.NewClickStatRepo(tx).EnsureClickPartitions(*oldest, now.AddDate(0, 1, 0)); err != nil {
+				return err
+			}
+
+			if err := tx.Exec("INSERT INTO click_stats SELECT * FROM click_stats_unpartitioned").Error; err != nil {
+				return err
+			}
+			return tx.Exec("DROP TABLE click_stats_unpartitioned").Error
+		},
+	},
+	{
+		// total hits counted bots and clicks were cleaned up without rollups, the reconciliation keeps those hits
+		id: "0005_urls_hits_baseline",
+		up: func(tx *gorm.DB) error {
+			return repo.NewClickStatRepo(tx).BaselineHits()
+		},
+	},
+	{
+		// conversions were counted for every click before
+		id: "0006_conversions_counted",
+		up: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE conversions SET counted = true").Error
+		},
+	},
+}
+
 func Migrate(db *gorm.DB) error {
-	db.AutoMigrate(&model.User{}, &model.Url{}, &model.ClickStat{})
+	err := db.AutoMigrate(
+		&model.User{},
+		&model.Url{},
+		&model.ClickStat{},
+		&model.VisitorSketch{},
+		&model.ClickRollup{},
+		&model.Conversion{},
+		&model.UrlHistory{},
+		&model.UrlTransfer{},
+		&model.UrlTransferItem{},
+		&model.StatsShare{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.MonthlyUsage{},
+		&model.SchemaMigration{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Where("id = ?", m.id).Limit(1).Find(&model.SchemaMigration{})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				return nil
+			}
+
+			if err := m.up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&model.SchemaMigration{ID: m.id}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.id, err)
+		}
+	}
 
 	return nil
 }
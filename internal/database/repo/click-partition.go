@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const clickPartitionPrefix = "click_stats_"
+
+// ClickPartition is a monthly partition of click_stats with clicks created in [From, To)
+type ClickPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// clickPartitionOf returns the partition of the UTC month of the time
+func clickPartitionOf(t time.Time) ClickPartition {
+	t = t.UTC()
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return ClickPartition{
+		Name: clickPartitionPrefix + from.Format("200601"),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+// ClickPartitions returns the monthly partitions of click_stats from the oldest one
+func (r *ClickStatRepo) ClickPartitions() ([]ClickPartition, error) {
+	var names []string
+	err := r.db.Raw(`SELECT child.relname FROM pg_inherits
+		JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+		WHERE pg_inherits.inhparent = to_regclass('click_stats')
+		ORDER BY 1`,
+	).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]ClickPartition, 0, len(names))
+	for _, name := range names {
+		// partitions attached by hand aren't managed
+		month, err := time.Parse("200601", strings.TrimPrefix(name, clickPartitionPrefix))
+		if err != nil || clickPartitionOf(month).Name != name {
+			continue
+		}
+		partitions = append(partitions, clickPartitionOf(month))
+	}
+
+	return partitions, nil
+}
+
+// EnsureClickPartitions creates missing partitions of the months in [from, to] and returns the number of created ones
+func (r *ClickStatRepo) EnsureClickPartitions(from, to time.Time) (int, error) {
+	existing, err := r.ClickPartitions()
+	if err != nil {
+		return 0, err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		exists[p.Name] = true
+	}
+
+	var created int
+	for p := clickPartitionOf(from); !p.From.After(to); p = clickPartitionOf(p.To) {
+		if exists[p.Name] {
+			continue
+		}
+		// bounds can't be bound parameters of DDL, they're formatted from the month
+		err := r.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF click_stats FOR VALUES FROM ('%s') TO ('%s')",
+			p.Name, p.From.Format(time.DateTime), p.To.Format(time.DateTime),
+		)).Error
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// DropClickPartition rolls up all clicks of the partition and drops it in one transaction, so every click is either raw or rolled up.
+// The partition is expected to be older than the retention, so no clicks are added to it meanwhile
+func (r *ClickStatRepo) DropClickPartition(partition ClickPartition) (int64, error) {
+	// the name is derived from the month, so it's safe to put into the queries
+	name := clickPartitionOf(partition.From).Name
+
+	var moved int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`WITH moved AS (
+				SELECT url_id, created_at, referrer_host, browser, os, device, language, country, bot, duplicate FROM ` + name + `
+			), rolled_up AS (` + rollupClicks("moved") + `)
+			SELECT COUNT(*) FROM moved`,
+		).Scan(&moved).Error
+		if err != nil {
+			return err
+		}
+
+		// the lock of the parent table is only held until the commit
+		return tx.Exec("DROP TABLE " + name).Error
+	})
+
+	return moved, err
+}
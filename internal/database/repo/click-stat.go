@@ -1,16 +1,76 @@
 package repo
 
 import (
-	"log"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
 	"time"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/lib/timebucket"
 	"url-shortener/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// StatsRange selects clicks created in [From, To) grouped into buckets of the granularity in the location
+type StatsRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Location    *time.Location
+	IncludeBots bool
+}
+
+// DailyCount is a bucket of the series. Day is the start of the bucket, which is a day unless another granularity is requested.
+// Count has no duplicate clicks, Raw counts them too. Conversions are counted in buckets of their clicks
 type DailyCount struct {
-	Day   time.Time
-	Count int64
+	Day         time.Time
+	Count       int64
+	Raw         int64
+	Uniques     int64
+	Conversions int64
+}
+
+type DimensionCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// HeatmapCell is the number of clicks in the hour of the weekday, 0 is Monday
+type HeatmapCell struct {
+	Weekday int
+	Hour    int
+	Count   int64
+}
+
+// HitsDrift is a url whose total hits differ from the number of its recorded clicks of humans without duplicates
+type HitsDrift struct {
+	ID        string `json:"alias"`
+	TotalHits int64  `json:"totalHits"`
+	// Baseline is the part of total hits counted before their clicks were kept, see BaselineHits
+	Baseline int64 `json:"baseline"`
+	Clicks   int64 `json:"clicks"`
+}
+
+// UrlClicks is the number of clicks of a url
+type UrlClicks struct {
+	ID     string `json:"alias"`
+	Link   string `json:"link"`
+	Clicks int64  `json:"clicks"`
+}
+
+var ErrUnknownDimension = errors.New("unknown dimension")
+
+// Dimensions maps names of click dimensions to their columns
+var Dimensions = map[string]string{
+	"referrer": "referrer_host",
+	"browser":  "browser",
+	"os":       "os",
+	"device":   "device",
+	"language": "language",
+	"country":  "country",
 }
 
 type ClickStatRepo struct {
@@ -21,45 +81,527 @@ func NewClickStatRepo(db *gorm.DB) *ClickStatRepo {
 	return &ClickStatRepo{db}
 }
 
+// Create saves the click and increments total hits of its url in one transaction unless the click is a bot's
 func (r *ClickStatRepo) Create(ClickStat *model.ClickStat) error {
-	return r.db.Create(ClickStat).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ClickStat).Error; err != nil {
+			return err
+		}
+		return addHits(tx, []*model.ClickStat{ClickStat})
+	})
+}
+
+// CreateBatch saves the clicks with a single multi-row insert and increments total hits of their urls in the same transaction
+func (r *ClickStatRepo) CreateBatch(clicks []*model.ClickStat) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&clicks).Error; err != nil {
+			return err
+		}
+		return addHits(tx, clicks)
+	})
+}
+
+// addHits increments total hits of urls by their clicks of humans that aren't duplicates, so total hits always match the default stats.
+// Urls are updated in the order of ids, so concurrent batches don't deadlock
+func addHits(tx *gorm.DB, clicks []*model.ClickStat) error {
+	hits := make(map[string]int64)
+	for _, click := range clicks {
+		if !click.Bot && !click.Duplicate {
+			hits[click.UrlID]++
+		}
+	}
+
+	for _, urlID := range slices.Sorted(maps.Keys(hits)) {
+		err := tx.Model(&model.Url{}).
+			Where("id = ?", urlID).
+			Update("total_hits", gorm.Expr("total_hits + ?", hits[urlID])).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DedupWindows returns de-duplication windows of the urls that override the global one
+func (r *ClickStatRepo) DedupWindows(urlIDs []string) (map[string]time.Duration, error) {
+	var urls []model.Url
+	err := r.db.Select("id", "dedup_window").Where("id IN ? AND dedup_window IS NOT NULL", urlIDs).Find(&urls).Error
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make(map[string]time.Duration, len(urls))
+	for _, url := range urls {
+		windows[url.ID] = time.Duration(*url.DedupWindow) * time.Second
+	}
+	return windows, nil
+}
+
+// hitsDrift is the query of urls whose total hits differ from their baseline and their raw and rolled up clicks of humans
+// without duplicates. The condition filters url ids in every table, so a batch of urls doesn't count clicks of others
+func hitsDrift(idCondition string) string {
+	return `WITH clicks AS (
+		SELECT url_id, SUM(clicks) AS clicks FROM (
+			SELECT url_id, COUNT(*) AS clicks FROM click_stats WHERE NOT bot AND NOT duplicate AND url_id ` + idCondition + ` GROUP BY 1
+			UNION ALL
+			SELECT url_id, SUM(clicks) FROM click_rollups WHERE NOT bot AND url_id ` + idCondition + ` GROUP BY 1
+		) AS clicks
+		GROUP BY 1
+	), drift AS (
+		SELECT urls.id, urls.total_hits, urls.hits_baseline AS baseline, COALESCE(clicks.clicks, 0) AS clicks
+		FROM urls LEFT JOIN clicks ON clicks.url_id = urls.id
+		WHERE urls.id ` + idCondition + ` AND urls.total_hits <> urls.hits_baseline + COALESCE(clicks.clicks, 0)
+	)`
+}
+
+// HitsDrift returns at most limit urls whose total hits differ from their recorded clicks and the number of all such urls
+func (r *ClickStatRepo) HitsDrift(limit int) ([]HitsDrift, int64, error) {
+	var drifted []struct {
+		HitsDrift
+		Total int64
+	}
+
+	err := r.db.Raw(hitsDrift("IS NOT NULL")+`
+		SELECT id, total_hits, baseline, clicks, COUNT(*) OVER () AS total FROM drift ORDER BY id LIMIT ?`, limit,
+	).Scan(&drifted).Error
+	if err != nil || len(drifted) == 0 {
+		return []HitsDrift{}, 0, err
+	}
+
+	results := make([]HitsDrift, len(drifted))
+	for i, d := range drifted {
+		results[i] = d.HitsDrift
+	}
+	return results, drifted[0].Total, nil
+}
+
+// BaselineHits keeps the current drift of every url as its baseline. Total hits counted bots and clicks were cleaned up
+// without rollups before, so those hits have no recorded clicks and aren't drift
+func (r *ClickStatRepo) BaselineHits() error {
+	return r.db.Exec(hitsDrift("IS NOT NULL") + `
+		UPDATE urls SET hits_baseline = drift.total_hits - drift.clicks
+		FROM drift
+		WHERE urls.id = drift.id`,
+	).Error
+}
+
+// RepairHits sets total hits of drifted urls among at most limit urls after the id to their baseline and recorded clicks.
+// It returns the repaired urls with their previous hits and the id to continue from, which is empty after the last url.
+// Urls hit while the clicks were counted are skipped, they're repaired by the next run
+func (r *ClickStatRepo) RepairHits(afterID string, limit int) ([]HitsDrift, string, error) {
+	var ids []string
+	err := r.db.Model(&model.Url{}).Where("id > ?", afterID).Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return []HitsDrift{}, "", err
+	}
+
+	repaired := []HitsDrift{}
+	err = r.db.Raw(hitsDrift("IN ?")+`
+		UPDATE urls SET total_hits = drift.baseline + drift.clicks
+		FROM drift
+		WHERE urls.id = drift.id AND urls.total_hits = drift.total_hits
+		RETURNING urls.id, drift.total_hits, drift.baseline, drift.clicks`, ids, ids, ids,
+	).Scan(&repaired).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	slices.SortFunc(repaired, func(a, b HitsDrift) int { return strings.Compare(a.ID, b.ID) })
+	if len(ids) < limit {
+		return repaired, "", nil
+	}
+	return repaired, ids[len(ids)-1], nil
+}
+
+// ByUrlID returns the series of the user's url. The url that doesn't belong to the user isn't found
+func (r *ClickStatRepo) ByUrlID(urlID, userID string, rng StatsRange) ([]DailyCount, error) {
+	err := r.db.Model(&model.Url{}).Select("id").Where("id = ? AND user_id = ?", urlID, userID).First(&model.Url{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ByUrlIDUnchecked(urlID, rng)
+}
+
+// ByUrlIDUnchecked doesn't check the url owner, so callers have to authorize access themselves.
+// Every bucket of the range is returned, buckets without clicks are zero
+func (r *ClickStatRepo) ByUrlIDUnchecked(urlID string, rng StatsRange) ([]DailyCount, error) {
+	return r.series(rng, func(q *gorm.DB, table string) *gorm.DB {
+		return q.Where(table+".url_id = ?", urlID)
+	})
+}
+
+// ByUserID returns the series of clicks of all the user's urls. Every bucket of the range is returned
+func (r *ClickStatRepo) ByUserID(userID string, rng StatsRange) ([]DailyCount, error) {
+	return r.series(rng, ofUser(userID))
+}
+
+// TopUrls returns at most limit of the user's urls with the most clicks in the range
+func (r *ClickStatRepo) TopUrls(userID string, rng StatsRange, limit int) ([]UrlClicks, error) {
+	results := []UrlClicks{}
+
+	byUser := ofUser(userID)
+	raw := byUser(r.db.Model(&model.ClickStat{}), "click_stats").
+		Select("click_stats.url_id, COUNT(*) FILTER (WHERE NOT click_stats.duplicate) AS clicks").
+		Scopes(rawClicksIn(rng)).
+		Group("1")
+	clicks := r.db.Table("(?) AS clicks", raw)
+	if rng.Granularity != timebucket.Hour {
+		rolledUp := byUser(r.db.Model(&model.ClickRollup{}), "click_rollups").
+			Select("click_rollups.url_id, SUM(click_rollups.clicks) AS clicks").
+			Scopes(rollupsIn(rng)).
+			Group("1")
+		clicks = r.db.Table("(? UNION ALL ?) AS clicks", raw, rolledUp)
+	}
+
+	err := clicks.
+		Select("urls.id, urls.link, SUM(clicks.clicks) AS clicks").
+		Joins("JOIN urls ON urls.id = clicks.url_id").
+		Group("urls.id, urls.link").
+		Order("SUM(clicks.clicks) DESC, urls.id").
+		Limit(limit).
+		Scan(&results).Error
+
+	return results, err
+}
+
+// EachClick streams raw clicks of the user's url, or of all the user's urls when the url id is empty, from the oldest one.
+// The url that doesn't belong to the user isn't found. Clicks are read row by row, so the callback should be fast
+func (r *ClickStatRepo) EachClick(urlID, userID string, rng StatsRange, each func(click *model.ClickStat) error) error {
+	q := ofUser(userID)(r.db.Model(&model.ClickStat{}), "click_stats").
+		Select("click_stats.*").
+		Scopes(rawClicksIn(rng))
+	if urlID != "" {
+		err := r.db.Model(&model.Url{}).Select("id").Where("id = ? AND user_id = ?", urlID, userID).First(&model.Url{}).Error
+		if err != nil {
+			return err
+		}
+		q = q.Where("click_stats.url_id = ?", urlID)
+	}
+
+	rows, err := q.Order("click_stats.created_at").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var click model.ClickStat
+		if err := r.db.ScanRows(rows, &click); err != nil {
+			return err
+		}
+		if err := each(&click); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CreatedUrls counts urls of the user created in [from, to)
+func (r *ClickStatRepo) CreatedUrls(userID string, from, to time.Time) (int64, error) {
+	var count int64
+
+	err := r.db.Model(&model.Url{}).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, from.UTC(), to.UTC()).
+		Count(&count).Error
+
+	return count, err
 }
 
-func (r *ClickStatRepo) ByUrlID(urlID, userID string) ([]DailyCount, error) {
+// series returns the zero-filled series of raw and rolled up clicks and conversions of the urls selected by the filter.
+// The filter gets the query and the name of its table
+func (r *ClickStatRepo) series(rng StatsRange, urls func(q *gorm.DB, table string) *gorm.DB) ([]DailyCount, error) {
 	var results []DailyCount
 
-	err := r.db.Model(&model.ClickStat{}).
-		Select("date_trunc('day', click_stats.created_at) AS day, COUNT(*) AS count").
-		Joins("JOIN urls ON urls.id = click_stats.url_id").
-		Where("click_stats.url_id = ? AND urls.user_id = ?", urlID, userID).
+	// click times are stored in UTC
+	err := urls(r.db.Model(&model.ClickStat{}), "click_stats").
+		Select("date_trunc(?, click_stats.created_at AT TIME ZONE 'UTC' AT TIME ZONE ?) AS day, COUNT(*) FILTER (WHERE NOT click_stats.duplicate) AS count, COUNT(*) AS raw",
+			rng.Granularity, rng.Location.String()).
+		Scopes(rawClicksIn(rng)).
 		Group("day").
 		Order("day").
 		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
 
-	return results, err
+	// rollups have only days, so they aren't split into hours
+	if rng.Granularity != timebucket.Hour {
+		var rolledUp []DailyCount
+		err := urls(r.db.Model(&model.ClickRollup{}), "click_rollups").
+			Select("date_trunc(?, click_rollups.day::timestamp) AS day, SUM(click_rollups.clicks) AS count, SUM(click_rollups.clicks + click_rollups.duplicates) AS raw", rng.Granularity).
+			Scopes(rollupsIn(rng)).
+			Group("1").
+			Scan(&rolledUp).Error
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rolledUp...)
+	}
+
+	var converted []DailyCount
+	err = urls(r.db.Model(&model.Conversion{}), "conversions").
+		Select("date_trunc(?, conversions.clicked_at AT TIME ZONE 'UTC' AT TIME ZONE ?) AS day, COUNT(*) AS conversions",
+			rng.Granularity, rng.Location.String()).
+		Where("conversions.counted AND conversions.clicked_at >= ? AND conversions.clicked_at < ?", rng.From.UTC(), rng.To.UTC()).
+		Group("day").
+		Scan(&converted).Error
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, converted...)
+
+	return fillEmptyBuckets(results, rng), nil
 }
 
-func (r *ClickStatRepo) CleanupStaleRecords() error {
-	result := r.db.Where("created_at < now() - interval '30 days'").Delete(&model.ClickStat{})
-	log.Printf("Deleted %d old events\n", result.RowsAffected)
+// ofUser filters clicks of the table by the owner of their urls
+func ofUser(userID string) func(q *gorm.DB, table string) *gorm.DB {
+	return func(q *gorm.DB, table string) *gorm.DB {
+		return q.Joins("JOIN urls ON urls.id = "+table+".url_id").Where("urls.user_id = ?", userID)
+	}
+}
+
+// rawClicksIn filters raw clicks by the range. Bots are filtered out unless the range includes them
+func rawClicksIn(rng StatsRange) func(q *gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		q = q.Where("click_stats.created_at >= ? AND click_stats.created_at < ?", rng.From.UTC(), rng.To.UTC())
+		if !rng.IncludeBots {
+			q = q.Where("NOT click_stats.bot")
+		}
+		return q
+	}
+}
+
+// rollupsIn filters rollups by days of the range like rawClicksIn
+func rollupsIn(rng StatsRange) func(q *gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		fromDay, toDay := rollupDays(rng)
+		q = q.Where("click_rollups.day >= ? AND click_rollups.day < ?", fromDay, toDay)
+		if !rng.IncludeBots {
+			q = q.Where("NOT click_rollups.bot")
+		}
+		return q
+	}
+}
+
+// rollupDays returns [from, to) dates of rollups in the range. A rollup day is placed at its midnight in the range location
+func rollupDays(rng StatsRange) (string, string) {
+	ceilDay := func(t time.Time) string {
+		t = t.In(rng.Location)
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, rng.Location)
+		if midnight.Before(t) {
+			midnight = midnight.AddDate(0, 0, 1)
+		}
+		return midnight.Format(time.DateOnly)
+	}
+
+	return ceilDay(rng.From), ceilDay(rng.To)
+}
+
+// Breakdown counts clicks of the user's url by the dimension, most popular values first. Clicks without a value are counted as "unknown".
+// Bots are counted only if they're included, duplicate clicks aren't counted
+func (r *ClickStatRepo) Breakdown(urlID, userID, dimension string, includeBots bool) ([]DimensionCount, error) {
+	return r.breakdown(urlID, userID, dimension, func(raw, rolledUp *gorm.DB) (*gorm.DB, *gorm.DB) {
+		if !includeBots {
+			return raw.Where("NOT click_stats.bot"), rolledUp.Where("NOT click_rollups.bot")
+		}
+		return raw, rolledUp
+	}, 0)
+}
+
+// TopValues returns at most limit most popular values of the dimension in clicks of the user's url in the range like Breakdown.
+// Rollups are counted by their days in the range location
+func (r *ClickStatRepo) TopValues(urlID, userID, dimension string, rng StatsRange, limit int) ([]DimensionCount, error) {
+	return r.breakdown(urlID, userID, dimension, func(raw, rolledUp *gorm.DB) (*gorm.DB, *gorm.DB) {
+		return raw.Scopes(rawClicksIn(rng)), rolledUp.Scopes(rollupsIn(rng))
+	}, limit)
+}
+
+// breakdown counts raw and rolled up clicks filtered by the scope by the dimension. The limit is ignored when it's 0
+func (r *ClickStatRepo) breakdown(urlID, userID, dimension string, scope func(raw, rolledUp *gorm.DB) (*gorm.DB, *gorm.DB), limit int) ([]DimensionCount, error) {
+	column, ok := Dimensions[dimension]
+	if !ok {
+		return nil, ErrUnknownDimension
+	}
+
+	results := []DimensionCount{}
+
+	raw := r.db.Model(&model.ClickStat{}).
+		Select("COALESCE(click_stats."+column+", '') AS value, COUNT(*) AS count").
+		Where("click_stats.url_id = ? AND NOT click_stats.duplicate", urlID).
+		Group("1")
+	rolledUp := r.db.Model(&model.ClickRollup{}).
+		Select("click_rollups."+column+" AS value, SUM(click_rollups.clicks) AS count").
+		Where("click_rollups.url_id = ? AND click_rollups.clicks > 0", urlID).
+		Group("1")
+	raw, rolledUp = scope(raw, rolledUp)
+
+	q := r.db.Table("(? UNION ALL ?) AS clicks", raw, rolledUp).
+		Select("COALESCE(NULLIF(clicks.value, ''), 'unknown') AS value, SUM(clicks.count) AS count").
+		Where("EXISTS (SELECT 1 FROM urls WHERE urls.id = ? AND urls.user_id = ?)", urlID, userID).
+		Group("1").
+		Order("count DESC, value")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	return results, q.Scan(&results).Error
+}
+
+// Heatmap counts clicks of the user's url in the range by weekday and hour in the range location. Weekdays are from 0 on Monday.
+// Rollups have no hours, so only raw clicks are counted. The url that doesn't belong to the user isn't found
+func (r *ClickStatRepo) Heatmap(urlID, userID string, rng StatsRange) ([]HeatmapCell, error) {
+	err := r.db.Model(&model.Url{}).Select("id").Where("id = ? AND user_id = ?", urlID, userID).First(&model.Url{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	const local = "click_stats.created_at AT TIME ZONE 'UTC' AT TIME ZONE ?"
+	cells := []HeatmapCell{}
+	err = r.db.Model(&model.ClickStat{}).
+		Select("EXTRACT(ISODOW FROM "+local+")::int - 1 AS weekday, EXTRACT(HOUR FROM "+local+")::int AS hour, COUNT(*) AS count",
+			rng.Location.String(), rng.Location.String()).
+		Where("click_stats.url_id = ? AND NOT click_stats.duplicate", urlID).
+		Scopes(rawClicksIn(rng)).
+		Group("1, 2").
+		Order("1, 2").
+		Scan(&cells).Error
 
-	return result.Error
+	return cells, err
 }
 
-// func fillEmptyDays(stats []DailyCount) []DailyCount {
-// 	startDate := time.Now().AddDate(0, 0, -29).Truncate(24 * time.Hour).UTC()
-// 	endDate := time.Now().Truncate(24 * time.Hour).UTC()
+// AddVisitors merges the sketch into the stored sketch of the url's day
+func (r *ClickStatRepo) AddVisitors(urlID string, day time.Time, sketch *hll.Sketch) error {
+	empty, err := hll.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
 
-// 	dateMap := make(map[time.Time]int64)
-// 	for _, r := range stats {
-// 		dateMap[r.Day] = r.Count
-// 	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		stored := model.VisitorSketch{UrlID: urlID, Day: day, Sketch: empty}
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&stored).Error
+		if err != nil {
+			return err
+		}
 
-// 	filledStats := make([]DailyCount, 0, 30)
-// 	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
-// 		count := dateMap[d]
-// 		filledStats = append(filledStats, DailyCount{Day: d, Count: count})
-// 	}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("url_id = ? AND day = ?", urlID, day).
+			First(&stored).Error
+		if err != nil {
+			return err
+		}
 
-// 	return filledStats
-// }
+		merged := hll.New()
+		if err := merged.UnmarshalBinary(stored.Sketch); err != nil {
+			return err
+		}
+		merged.Merge(sketch)
+		data, err := merged.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&model.VisitorSketch{}).Where("url_id = ? AND day = ?", urlID, day).Update("sketch", data).Error
+	})
+}
+
+// VisitorSketches returns daily sketches of the url between the days inclusive
+func (r *ClickStatRepo) VisitorSketches(urlID string, from, to time.Time) ([]model.VisitorSketch, error) {
+	var sketches []model.VisitorSketch
+
+	err := r.db.Where("url_id = ? AND day BETWEEN ? AND ?", urlID, from, to).
+		Order("day").
+		Find(&sketches).Error
+
+	return sketches, err
+}
+
+// RetentionScope selects stale data of urls by plans of their owners: urls of the plans or, with Exclude, of any other plan.
+// Anonymous urls and owners without a plan are on the DefaultPlan
+type RetentionScope struct {
+	Cutoff      time.Time
+	Plans       []string
+	Exclude     bool
+	DefaultPlan string
+}
+
+// RollupStaleClicks moves at most limit clicks created before the cutoff into daily rollups and returns the number of moved clicks.
+// The clicks are deleted and rolled up by one statement, so every click is either raw or rolled up.
+// Row ids are only unique within a partition, so rows are selected by their partition too
+func (r *ClickStatRepo) RollupStaleClicks(scope RetentionScope, limit int) (int64, error) {
+	urls, urlsArgs := scopeUrls(scope)
+
+	var moved int64
+	err := r.db.Raw(`WITH moved AS (
+			DELETE FROM click_stats WHERE created_at < ? AND (tableoid, ctid) IN (
+				SELECT tableoid, ctid FROM click_stats WHERE created_at < ? AND url_id IN (`+urls+`) LIMIT ?
+			)
+			RETURNING url_id, created_at, referrer_host, browser, os, device, language, country, bot, duplicate
+		), rolled_up AS (`+rollupClicks("moved")+`)
+		SELECT COUNT(*) FROM moved`,
+		append(append([]any{scope.Cutoff, scope.Cutoff}, urlsArgs...), limit)...,
+	).Scan(&moved).Error
+
+	return moved, err
+}
+
+// rollupClicks returns the statement adding clicks of the source to their daily rollups
+func rollupClicks(source string) string {
+	return `INSERT INTO click_rollups (url_id, day, referrer_host, browser, os, device, language, country, bot, clicks, duplicates)
+		SELECT url_id, created_at::date, COALESCE(referrer_host, ''), COALESCE(browser, ''), COALESCE(os, ''),
+			COALESCE(device, ''), COALESCE(language, ''), COALESCE(country, ''), bot,
+			COUNT(*) FILTER (WHERE NOT duplicate), COUNT(*) FILTER (WHERE duplicate)
+		FROM ` + source + `
+		GROUP BY 1, 2, 3, 4, 5, 6, 7, 8, 9
+		ON CONFLICT (url_id, day, referrer_host, browser, os, device, language, country, bot)
+		DO UPDATE SET clicks = click_rollups.clicks + EXCLUDED.clicks, duplicates = click_rollups.duplicates + EXCLUDED.duplicates`
+}
+
+// DeleteStaleSketches deletes at most limit visitor sketches of days before the cutoff and returns the number of deleted ones
+func (r *ClickStatRepo) DeleteStaleSketches(scope RetentionScope, limit int) (int64, error) {
+	urls, urlsArgs := scopeUrls(scope)
+
+	res := r.db.Exec(`DELETE FROM visitor_sketches WHERE ctid IN (
+			SELECT ctid FROM visitor_sketches WHERE day < ? AND url_id IN (`+urls+`) LIMIT ?
+		)`,
+		append(append([]any{scope.Cutoff}, urlsArgs...), limit)...,
+	)
+
+	return res.RowsAffected, res.Error
+}
+
+// scopeUrls returns the query of ids of the scope's urls with its arguments
+func scopeUrls(scope RetentionScope) (string, []any) {
+	const urls = "SELECT urls.id FROM urls LEFT JOIN users ON users.id = urls.user_id"
+
+	switch {
+	case scope.Exclude && len(scope.Plans) == 0:
+		return urls, nil
+	case scope.Exclude:
+		return urls + " WHERE COALESCE(users.plan, ?) NOT IN ?", []any{scope.DefaultPlan, scope.Plans}
+	default:
+		return urls + " WHERE COALESCE(users.plan, ?) IN ?", []any{scope.DefaultPlan, scope.Plans}
+	}
+}
+
+// fillEmptyBuckets returns every bucket of the range with counts of the found ones. Found buckets are wall clock times of the range location
+func fillEmptyBuckets(stats []DailyCount, rng StatsRange) []DailyCount {
+	counts := make(map[time.Time]DailyCount, len(stats))
+	for _, r := range stats {
+		b := r.Day
+		b = time.Date(b.Year(), b.Month(), b.Day(), b.Hour(), 0, 0, 0, rng.Location)
+		count := counts[b]
+		count.Count += r.Count
+		count.Raw += r.Raw
+		count.Conversions += r.Conversions
+		counts[b] = count
+	}
+
+	filled := []DailyCount{}
+	for b := timebucket.Truncate(rng.From.In(rng.Location), rng.Granularity); b.Before(rng.To); b = timebucket.Next(b, rng.Granularity) {
+		filled = append(filled, DailyCount{Day: b, Count: counts[b].Count, Raw: counts[b].Raw, Conversions: counts[b].Conversions})
+	}
+
+	return filled
+}
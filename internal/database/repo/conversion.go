@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"time"
+	"url-shortener/internal/model"
+)
+
+// clickSaveDelay bounds the time between the click token and the saved click, which is queued at the redirect
+const clickSaveDelay = time.Minute
+
+// CreateConversion saves the conversion unless its click is already converted and reports whether it's saved.
+// The conversion is counted if its click is already saved as counted, otherwise the click counts it when it's saved
+func (r *ClickStatRepo) CreateConversion(conversion *model.Conversion) (bool, error) {
+	res := r.db.Exec(`INSERT INTO conversions (url_id, click_id, clicked_at, created_at, counted)
+		VALUES (?, ?, ?, ?, EXISTS (
+			SELECT 1 FROM click_stats
+			WHERE url_id = ? AND click_id = ? AND created_at >= ? AND created_at < ?
+		))
+		ON CONFLICT DO NOTHING`,
+		conversion.UrlID, conversion.ClickID, conversion.ClickedAt, conversion.CreatedAt,
+		conversion.UrlID, conversion.ClickID, conversion.ClickedAt.Add(-time.Second), conversion.ClickedAt.Add(clickSaveDelay),
+	)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountConversions counts conversions of the saved clicks reported before the clicks were saved
+func (r *ClickStatRepo) CountConversions(clickIDs []string) error {
+	return r.db.Model(&model.Conversion{}).
+		Where("click_id IN ? AND NOT counted", clickIDs).
+		Update("counted", true).Error
+}
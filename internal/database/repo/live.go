@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"url-shortener/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+type LiveRepo struct {
+	db *gorm.DB
+}
+
+func NewLiveRepo(db *gorm.DB) *LiveRepo {
+	return &LiveRepo{db}
+}
+
+// Owners returns owners of the urls by their ids. Anonymous and deleted urls are missing
+func (r *LiveRepo) Owners(urlIDs []string) (map[string]string, error) {
+	var urls []model.Url
+	err := r.db.Select("id", "user_id").Where("id IN ? AND user_id IS NOT NULL", urlIDs).Find(&urls).Error
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string, len(urls))
+	for _, url := range urls {
+		owners[url.ID] = url.UserID
+	}
+	return owners, nil
+}
+
+// IsOwner checks that the url belongs to the user
+func (r *LiveRepo) IsOwner(urlID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Url{}).Where("id = ? AND user_id = ?", urlID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// Notify sends the payload to listeners of the channel of all the database sessions
+func (r *LiveRepo) Notify(channel, payload string) error {
+	return r.db.Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// Listen calls the handler with payloads of the channel's notifications until the context is done or the connection fails.
+// It holds a connection of the pool while listening
+func (r *LiveRepo) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		// the connection goes back to the pool unless it's closed by the cancellation
+		defer pgConn.Exec(context.Background(), "UNLISTEN *")
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			handle(notification.Payload)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type StatsShareRepo struct {
+	db *gorm.DB
+}
+
+func NewStatsShareRepo(db *gorm.DB) *StatsShareRepo {
+	return &StatsShareRepo{db}
+}
+
+// Create saves the share if its url belongs to the user, otherwise returns gorm.ErrRecordNotFound
+func (r *StatsShareRepo) Create(share *model.StatsShare, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkUrlOwner(tx, share.UrlID, userID); err != nil {
+			return err
+		}
+
+		return tx.Omit("Url").Create(share).Error
+	})
+}
+
+// ByUrlID returns shares of the user's url, newest first
+func (r *StatsShareRepo) ByUrlID(urlID, userID string) ([]model.StatsShare, error) {
+	if err := checkUrlOwner(r.db, urlID, userID); err != nil {
+		return nil, err
+	}
+
+	var shares []model.StatsShare
+	return shares, r.db.Where("url_id = ?", urlID).Order("created_at DESC, id").Find(&shares).Error
+}
+
+// ByTokenHash returns the share with its url, so stats can be read on behalf of the url owner
+func (r *StatsShareRepo) ByTokenHash(tokenHash string) (*model.StatsShare, error) {
+	var share model.StatsShare
+
+	return &share, r.db.Preload("Url").Where("token_hash = ?", tokenHash).First(&share).Error
+}
+
+// Delete revokes the share of the user's url
+func (r *StatsShareRepo) Delete(id, urlID, userID string) error {
+	res := r.db.
+		Where("id = ? AND url_id = ?", id, urlID).
+		Where("url_id IN (?)", r.db.Model(&model.Url{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&model.StatsShare{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func checkUrlOwner(tx *gorm.DB, urlID, userID string) error {
+	var owned int64
+	err := tx.Model(&model.Url{}).Where("id = ? AND user_id = ?", urlID, userID).Count(&owned).Error
+	if err != nil {
+		return err
+	}
+	if owned == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
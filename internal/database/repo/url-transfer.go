@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"errors"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrUrlsNotOwned = errors.New("some urls are not owned by the sender")
+
+type UrlTransferRepo struct {
+	db *gorm.DB
+}
+
+func NewUrlTransferRepo(db *gorm.DB) *UrlTransferRepo {
+	return &UrlTransferRepo{db}
+}
+
+// Create checks that every url of the transfer belongs to the sender and saves the transfer with its items
+func (r *UrlTransferRepo) Create(transfer *model.UrlTransfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkOwnership(tx, transfer); err != nil {
+			return err
+		}
+
+		return tx.Omit("FromUser", "ToUser", "Items.Url").Create(transfer).Error
+	})
+}
+
+func (r *UrlTransferRepo) ByID(id string) (*model.UrlTransfer, error) {
+	var transfer model.UrlTransfer
+
+	return &transfer, r.db.Preload("FromUser").Preload("ToUser").Preload("Items").
+		Where("id = ?", id).First(&transfer).Error
+}
+
+// PendingByUserID returns incoming and outgoing pending transfers of the user
+func (r *UrlTransferRepo) PendingByUserID(userID string) ([]model.UrlTransfer, error) {
+	var transfers []model.UrlTransfer
+
+	return transfers, r.db.Preload("FromUser").Preload("ToUser").Preload("Items").
+		Where("(from_user_id = ? OR to_user_id = ?) AND status = ?", userID, userID, model.TransferPending).
+		Order("created_at DESC").
+		Find(&transfers).Error
+}
+
+// Accept moves the urls to the recipient. Click stats and total hits are keyed by url id, so they move with the url.
+// Stats shares are granted by the sender, so they're revoked. The transfer isn't accepted when the recipient would go over the limits
+func (r *UrlTransferRepo) Accept(id, toUserID string, limits LinkLimits) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var transfer model.UrlTransfer
+		err := tx.Preload("Items").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND to_user_id = ? AND status = ?", id, toUserID, model.TransferPending).
+			First(&transfer).Error
+		if err != nil {
+			return err
+		}
+
+		if err := checkOwnership(tx, &transfer); err != nil {
+			return err
+		}
+
+		res := tx.Model(&model.Url{}).
+			Where("id IN ? AND user_id = ?", itemUrlIDs(&transfer), transfer.FromUserID).
+			Update("user_id", transfer.ToUserID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if err := checkMovedLinks(tx, transfer.ToUserID, itemUrlIDs(&transfer), limits); err != nil {
+			return err
+		}
+		if err := tx.Where("url_id IN ?", itemUrlIDs(&transfer)).Delete(&model.StatsShare{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&transfer).Update("status", model.TransferAccepted).Error
+	})
+}
+
+// Close sets the final status of a pending transfer. Both the sender and the recipient can close it
+func (r *UrlTransferRepo) Close(id, userID, status string) error {
+	res := r.db.Model(&model.UrlTransfer{}).
+		Where("id = ? AND (from_user_id = ? OR to_user_id = ?) AND status = ?", id, userID, userID, model.TransferPending).
+		Update("status", status)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func checkOwnership(tx *gorm.DB, transfer *model.UrlTransfer) error {
+	var owned int64
+	err := tx.Model(&model.Url{}).
+		Where("id IN ? AND user_id = ?", itemUrlIDs(transfer), transfer.FromUserID).
+		Count(&owned).Error
+	if err != nil {
+		return err
+	}
+	if owned != int64(len(transfer.Items)) {
+		return ErrUrlsNotOwned
+	}
+
+	return nil
+}
+
+func itemUrlIDs(transfer *model.UrlTransfer) []string {
+	ids := make([]string, len(transfer.Items))
+	for i, item := range transfer.Items {
+		ids[i] = item.UrlID
+	}
+	return ids
+}
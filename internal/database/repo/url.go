@@ -1,11 +1,20 @@
 package repo
 
 import (
+	"strconv"
+	"time"
 	"url-shortener/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+type UrlHistoryEntry struct {
+	model.UrlHistory `gorm:"embedded"`
+	// Clicks made while the entry's link was the destination, rolled up days are counted by their end
+	Clicks int64
+}
+
 type UrlRepo struct {
 	db *gorm.DB
 }
@@ -14,12 +23,171 @@ func NewUrlRepo(db *gorm.DB) *UrlRepo {
 	return &UrlRepo{db}
 }
 
-func (r *UrlRepo) Create(url *model.Url) error {
-	return r.db.Create(url).Error
+// Create also starts the url's history with its initial destination at the url's creation time.
+// The url isn't created when its owner would go over the limits
+func (r *UrlRepo) Create(url *model.Url, limits LinkLimits) error {
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now().UTC()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(url).Error; err != nil {
+			return err
+		}
+
+		err := tx.Create(&model.UrlHistory{
+			UrlID:     url.ID,
+			UserID:    url.UserID,
+			Action:    model.HistoryCreate,
+			Field:     "link",
+			NewValue:  url.Link,
+			CreatedAt: url.CreatedAt,
+		}).Error
+		if err != nil || url.UserID == "" {
+			return err
+		}
+
+		return checkMovedLinks(tx, url.UserID, []string{url.ID}, limits)
+	})
+}
+
+// UpdateLink changes the destination of user's url and appends the change to its history
+func (r *UrlRepo) UpdateLink(id, userID, link, action string) (*model.Url, error) {
+	var url model.Url
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&url).Error
+		if err != nil {
+			return err
+		}
+		if url.Link == link {
+			return nil
+		}
+
+		err = tx.Create(&model.UrlHistory{
+			UrlID:     id,
+			UserID:    userID,
+			Action:    action,
+			Field:     "link",
+			OldValue:  url.Link,
+			NewValue:  link,
+			CreatedAt: time.Now().UTC(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		url.Link = link
+		return tx.Model(&url).Update("link", link).Error
+	})
+
+	return &url, err
+}
+
+// History returns changes of user's url from the newest one.
+// Each link change gets raw clicks recorded until the next link change. Rolled up clicks have only UTC days,
+// so a rolled up day is counted in the change that was live at the end of the day
+func (r *UrlRepo) History(id, userID string) ([]UrlHistoryEntry, error) {
+	var entries []UrlHistoryEntry
+
+	err := r.db.Raw(`
+	WITH periods AS (
+		SELECT url_histories.*,
+			LEAD(url_histories.created_at) OVER (
+				PARTITION BY url_histories.field ORDER BY url_histories.created_at, url_histories.id
+			) AS live_to
+		FROM url_histories
+		JOIN urls ON urls.id = url_histories.url_id
+		WHERE url_histories.url_id = ? AND urls.user_id = ?
+	)
+	SELECT periods.id, periods.url_id, periods.user_id, periods.action, periods.field,
+		periods.old_value, periods.new_value, periods.created_at,
+		CASE WHEN periods.field = 'link' THEN (
+			SELECT COUNT(*) FROM click_stats
+			WHERE click_stats.url_id = periods.url_id
+				AND click_stats.created_at >= periods.created_at
+				AND (periods.live_to IS NULL OR click_stats.created_at < periods.live_to)
+		) + (
+			SELECT COALESCE(SUM(click_rollups.clicks + click_rollups.duplicates), 0) FROM click_rollups
+			WHERE click_rollups.url_id = periods.url_id
+				AND (click_rollups.day + 1)::timestamp > periods.created_at
+				AND (periods.live_to IS NULL OR (click_rollups.day + 1)::timestamp <= periods.live_to)
+		) ELSE 0 END AS clicks
+	FROM periods
+	ORDER BY periods.created_at DESC, periods.id DESC;
+`, id, userID).Scan(&entries).Error
+
+	return entries, err
+}
+
+// HistoryByID returns the entry of user's url or gorm.ErrRecordNotFound if the user has no such url
+func (r *UrlRepo) HistoryByID(historyID int64, urlID, userID string) (*model.UrlHistory, error) {
+	var entry model.UrlHistory
+
+	return &entry, r.db.
+		Joins("JOIN urls ON urls.id = url_histories.url_id").
+		Where("url_histories.id = ? AND url_histories.url_id = ? AND urls.user_id = ?", historyID, urlID, userID).
+		First(&entry).Error
+}
+
+// Delete returns the deleted url or gorm.ErrRecordNotFound if the user has no such url
+func (r *UrlRepo) Delete(id string, userID string) (*model.Url, error) {
+	var urls []model.Url
+	res := r.db.Clauses(clause.Returning{}).Where("id = ? AND user_id = ?", id, userID).Delete(&urls)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(urls) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &urls[0], nil
+}
+
+// SetDedupWindow sets the de-duplication window of the user's url, nil resets it to the global one.
+// The change is appended to the url's history, the global window is an empty value
+func (r *UrlRepo) SetDedupWindow(id, userID string, window *int) (*model.Url, error) {
+	var url model.Url
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&url).Error
+		if err != nil {
+			return err
+		}
+		if dedupValue(url.DedupWindow) == dedupValue(window) {
+			return nil
+		}
+
+		err = tx.Create(&model.UrlHistory{
+			UrlID:     id,
+			UserID:    userID,
+			Action:    model.HistoryUpdate,
+			Field:     "dedup_window",
+			OldValue:  dedupValue(url.DedupWindow),
+			NewValue:  dedupValue(window),
+			CreatedAt: time.Now().UTC(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		url.DedupWindow = window
+		return tx.Model(&url).Update("dedup_window", window).Error
+	})
+
+	return &url, err
 }
 
-func (r *UrlRepo) Delete(id string, userID string) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Url{}).Error
+// dedupValue is the history value of the window in seconds
+func dedupValue(window *int) string {
+	if window == nil {
+		return ""
+	}
+	return strconv.Itoa(*window)
 }
 
 func (r *UrlRepo) ByID(id string) (*model.Url, error) {
@@ -28,15 +196,14 @@ func (r *UrlRepo) ByID(id string) (*model.Url, error) {
 	return &url, r.db.Where("id = ?", id).First(&url).Error
 }
 
-// LinkByID also increment total hits
+// LinkByID returns the link of a not expired url. Total hits are incremented when the click is recorded
 func (r *UrlRepo) LinkByID(id string) (string, error) {
 	var link string
 
 	res := r.db.Raw(`
-	UPDATE urls
-	SET total_hits = total_hits + 1
-	WHERE id = ?
-	RETURNING link;
+	SELECT link
+	FROM urls
+	WHERE id = ? AND (expires_at IS NULL OR expires_at > now());
 `, id).Scan(&link)
 
 	if res.RowsAffected == 0 {
@@ -51,3 +218,49 @@ func (r *UrlRepo) ByUserID(id string, limit int, offset int) ([]model.Url, error
 
 	return urls, r.db.Where("user_id = ?", id).Limit(limit).Offset(offset).Find(&urls).Error
 }
+
+// ByClaimToken returns a not expired anonymous url if the token hash matches
+func (r *UrlRepo) ByClaimToken(id, tokenHash string) (*model.Url, error) {
+	var url model.Url
+
+	return &url, r.db.
+		Where("id = ? AND user_id IS NULL AND claim_token_hash = ?", id, tokenHash).
+		Where("expires_at IS NULL OR expires_at > now()").
+		First(&url).Error
+}
+
+// Claim makes an anonymous url owned by the user and permanent. The url isn't claimed when the user would go over the limits
+func (r *UrlRepo) Claim(id, tokenHash, userID string, limits LinkLimits) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Url{}).
+			Where("id = ? AND user_id IS NULL AND claim_token_hash = ?", id, tokenHash).
+			Where("expires_at IS NULL OR expires_at > now()").
+			Updates(map[string]any{"user_id": userID, "claim_token_hash": nil, "expires_at": nil})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return checkMovedLinks(tx, userID, []string{id}, limits)
+	})
+}
+
+func (r *UrlRepo) DeleteAnonymous(id, tokenHash string) error {
+	res := r.db.Where("id = ? AND user_id IS NULL AND claim_token_hash = ?", id, tokenHash).Delete(&model.Url{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *UrlRepo) DeleteExpired() (int64, error) {
+	res := r.db.Where("expires_at <= now()").Delete(&model.Url{})
+
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type Usage struct {
+	Plan          string
+	Links         int64
+	Aliases       int64
+	MonthlyClicks int64
+}
+
+// ClickUsage is the monthly usage of the owner of the url
+type ClickUsage struct {
+	UrlID         string
+	UserID        string
+	Plan          string
+	MonthlyClicks int64
+}
+
+type UsageRepo struct {
+	db *gorm.DB
+}
+
+func NewUsageRepo(db *gorm.DB) *UsageRepo {
+	return &UsageRepo{db}
+}
+
+func (r *UsageRepo) ByUserID(userID string) (*Usage, error) {
+	var usage Usage
+
+	res := r.db.Raw(`
+	SELECT COALESCE(users.plan, '') AS plan,
+		(SELECT COUNT(*) FROM urls WHERE urls.user_id = users.id) AS links,
+		(SELECT COUNT(*) FROM urls WHERE urls.user_id = users.id AND urls.custom_alias) AS aliases,
+		COALESCE((
+			SELECT monthly_usages.clicks FROM monthly_usages
+			WHERE monthly_usages.user_id = users.id AND monthly_usages.month = date_trunc('month', now())::date
+		), 0) AS monthly_clicks
+	FROM users
+	WHERE users.id = ?;
+`, userID).Scan(&usage)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &usage, nil
+}
+
+// ClickUsages returns the current month's clicks of owners of the urls. Anonymous urls have no owner, so they're skipped
+func (r *UsageRepo) ClickUsages(urlIDs []string) ([]ClickUsage, error) {
+	usages := []ClickUsage{}
+
+	err := r.db.Raw(`
+	SELECT urls.id AS url_id, urls.user_id, COALESCE(users.plan, '') AS plan, COALESCE(monthly_usages.clicks, 0) AS monthly_clicks
+	FROM urls
+	JOIN users ON users.id = urls.user_id
+	LEFT JOIN monthly_usages ON monthly_usages.user_id = users.id AND monthly_usages.month = date_trunc('month', now())::date
+	WHERE urls.id IN ?;
+`, urlIDs).Scan(&usages).Error
+
+	return usages, err
+}
+
+// AddClicks adds the numbers of clicks of the urls to their owners' current month in one statement.
+// Clicks of anonymous urls aren't counted
+func (r *UsageRepo) AddClicks(counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	values := make([][]any, 0, len(counts))
+	for urlID, clicks := range counts {
+		values = append(values, []any{urlID, clicks})
+	}
+
+	// owners are upserted in the same order by every batch, so concurrent batches don't deadlock
+	return r.db.Exec(`
+	INSERT INTO monthly_usages (user_id, month, clicks)
+	SELECT urls.user_id, date_trunc('month', now())::date, SUM(counts.clicks::bigint)
+	FROM (VALUES ?) AS counts(url_id, clicks)
+	JOIN urls ON urls.id = counts.url_id
+	WHERE urls.user_id IS NOT NULL
+	GROUP BY urls.user_id
+	ORDER BY urls.user_id
+	ON CONFLICT (user_id, month) DO UPDATE SET clicks = monthly_usages.clicks + EXCLUDED.clicks;
+`, values).Error
+}
+
+var (
+	ErrLinkQuotaExceeded  = errors.New("link quota exceeded")
+	ErrAliasQuotaExceeded = errors.New("alias quota exceeded")
+)
+
+// LinkLimits are the url limits of the user's plan, 0 is unlimited
+type LinkLimits struct {
+	MaxLinks   int64
+	MaxAliases int64
+}
+
+// checkMovedLinks checks that the urls just moved to or created for the user don't make them go over the limits. It's called in
+// the transaction of the move after the update, so the move is rolled back by the error. The user row is locked, so concurrent moves are checked one by one
+func checkMovedLinks(tx *gorm.DB, userID string, urlIDs []string, limits LinkLimits) error {
+	if limits.MaxLinks <= 0 && limits.MaxAliases <= 0 {
+		return nil
+	}
+	if err := tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Error; err != nil {
+		return err
+	}
+
+	var usage struct {
+		Links        int64
+		Aliases      int64
+		MovedAliases int64
+	}
+	err := tx.Raw(`SELECT COUNT(*) AS links,
+			COUNT(*) FILTER (WHERE custom_alias) AS aliases,
+			COUNT(*) FILTER (WHERE custom_alias AND id IN ?) AS moved_aliases
+		FROM urls
+		WHERE user_id = ?`, urlIDs, userID,
+	).Scan(&usage).Error
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxLinks > 0 && usage.Links > limits.MaxLinks {
+		return ErrLinkQuotaExceeded
+	}
+	// users over the alias limit after a downgrade can still get urls without aliases
+	if limits.MaxAliases > 0 && usage.MovedAliases > 0 && usage.Aliases > limits.MaxAliases {
+		return ErrAliasQuotaExceeded
+	}
+	return nil
+}
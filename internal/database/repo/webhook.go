@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"slices"
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) *WebhookRepo {
+	return &WebhookRepo{db}
+}
+
+// UrlWebhook is a webhook of the url owner
+type UrlWebhook struct {
+	UrlID     string
+	WebhookID string
+}
+
+func (r *WebhookRepo) Create(webhook *model.Webhook) error {
+	return r.db.Omit("User").Create(webhook).Error
+}
+
+// ByUserID returns webhooks of the user, newest first
+func (r *WebhookRepo) ByUserID(userID string) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+
+	return webhooks, r.db.Where("user_id = ?", userID).Order("created_at DESC, id").Find(&webhooks).Error
+}
+
+// Delete deletes the user's webhook with its deliveries
+func (r *WebhookRepo) Delete(id, userID string) error {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Webhook{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Subscribers returns ids of the user's webhooks subscribed to the event
+func (r *WebhookRepo) Subscribers(userID, event string) ([]string, error) {
+	var ids []string
+
+	return ids, r.db.Model(&model.Webhook{}).
+		Where("user_id = ?", userID).
+		Scopes(subscribedTo(event)).
+		Order("id").
+		Pluck("id", &ids).Error
+}
+
+// UrlSubscribers returns webhooks subscribed to the event by owners of the urls
+func (r *WebhookRepo) UrlSubscribers(event string, urlIDs []string) ([]UrlWebhook, error) {
+	var subscribers []UrlWebhook
+
+	return subscribers, r.db.Model(&model.Webhook{}).
+		Select("urls.id AS url_id, webhooks.id AS webhook_id").
+		Joins("JOIN urls ON urls.user_id = webhooks.user_id").
+		Where("urls.id IN ?", urlIDs).
+		Scopes(subscribedTo(event)).
+		Order("urls.id, webhooks.id").
+		Scan(&subscribers).Error
+}
+
+// AdvanceMilestone moves the last reported milestone of the url to the highest one reached by its total hits.
+// It returns the url and milestones reached since the last report, so each milestone is reported once
+// even if several instances record clicks of the url
+func (r *WebhookRepo) AdvanceMilestone(urlID string, milestones []int64) (*model.Url, []int64, error) {
+	var url model.Url
+	if err := r.db.Where("id = ?", urlID).First(&url).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var reached []int64
+	for _, m := range milestones {
+		if m > url.LastMilestone && m <= url.TotalHits {
+			reached = append(reached, m)
+		}
+	}
+	if len(reached) == 0 {
+		return &url, nil, nil
+	}
+
+	res := r.db.Model(&model.Url{}).
+		Where("id = ? AND last_milestone = ?", urlID, url.LastMilestone).
+		Update("last_milestone", slices.Max(reached))
+	if res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// reported by another instance
+		return &url, nil, nil
+	}
+
+	return &url, reached, nil
+}
+
+// Enqueue saves pending deliveries
+func (r *WebhookRepo) Enqueue(deliveries []*model.WebhookDelivery) error {
+	return r.db.Omit("Webhook").CreateInBatches(deliveries, 100).Error
+}
+
+// ClaimDue takes up to limit pending deliveries due at now with their webhooks and counts their attempts.
+// Claimed deliveries aren't due again until the lease ends, so they're retried if the instance dies while delivering them
+func (r *WebhookRepo) ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var ids []int64
+	err := r.db.Raw(`
+	UPDATE webhook_deliveries
+	SET attempts = attempts + 1, next_attempt_at = ?
+	WHERE id IN (
+		SELECT id FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id;
+`, now.Add(lease), model.DeliveryPending, now, limit).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	return deliveries, r.db.Preload("Webhook").Where("id IN ?", ids).Order("id").Find(&deliveries).Error
+}
+
+// Finish saves the result of the delivery attempt
+func (r *WebhookRepo) Finish(delivery *model.WebhookDelivery) error {
+	return r.db.Model(delivery).
+		Select("status", "next_attempt_at", "last_attempt_at", "response_status", "error").
+		Updates(delivery).Error
+}
+
+// Deliveries returns the delivery log of the user's webhook, newest first
+func (r *WebhookRepo) Deliveries(webhookID, userID string, limit int) ([]model.WebhookDelivery, error) {
+	if err := r.db.Where("id = ? AND user_id = ?", webhookID, userID).First(&model.Webhook{}).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	return deliveries, r.db.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+}
+
+// Redeliver queues a copy of the delivery of the user's webhook, the log of the original one is kept
+func (r *WebhookRepo) Redeliver(id int64, webhookID, userID string, now time.Time) (*model.WebhookDelivery, error) {
+	var original model.WebhookDelivery
+	err := r.db.
+		Where("id = ? AND webhook_id IN (?)", id, r.db.Model(&model.Webhook{}).Select("id").Where("id = ? AND user_id = ?", webhookID, userID)).
+		First(&original).Error
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &model.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	return delivery, r.db.Omit("Webhook").Create(delivery).Error
+}
+
+func subscribedTo(event string) func(q *gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Where("? = ANY(string_to_array(webhooks.events, ','))", event)
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ClickTokenParam is the query parameter of the click token. Redirects append it to destinations, so landing pages
+// keep the token first-party and pass it to the conversion beacon of the url in the same parameter
+const ClickTokenParam = "click_token"
+
+// WithClickToken appends the click token to the query of the link. Other parameters and the fragment are kept as they are
+func WithClickToken(link, token string) string {
+	param := ClickTokenParam + "=" + url.QueryEscape(token)
+
+	link, fragment, hasFragment := strings.Cut(link, "#")
+	switch {
+	case !strings.Contains(link, "?"):
+		link += "?" + param
+	case strings.HasSuffix(link, "?") || strings.HasSuffix(link, "&"):
+		link += param
+	default:
+		link += "&" + param
+	}
+	if hasFragment {
+		link += "#" + fragment
+	}
+	return link
+}
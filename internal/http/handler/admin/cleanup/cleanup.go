@@ -0,0 +1,32 @@
+package cleanup
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.CleanupStatus
+
+type CleanupStatusGetter interface {
+	CleanupStatus() *dto.CleanupStatus
+}
+
+// @Summary Get status of the click cleanup job
+// @Description The last run since the start of the server with numbers of rolled up clicks and deleted visitor sketches
+// @Tags admin
+// @Produce  json
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Router /admin/cleanup [get]
+// @Security Bearer
+func New(log *slog.Logger, statusGetter CleanupStatusGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.admin.cleanup"))
+
+		c.JSON(http.StatusOK, statusGetter.CleanupStatus())
+	}
+}
@@ -0,0 +1,42 @@
+package drift
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.HitsDrift
+
+type DriftFinder interface {
+	HitsDrift() (*dto.HitsDrift, error)
+}
+
+// @Summary Find urls whose total hits drifted from their clicks
+// @Description Total hits are compared with raw and rolled up clicks without bots. Nothing is repaired, at most 100 urls are listed
+// @Tags admin
+// @Produce  json
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Failure 500  {object}  api.ErrorResponse
+// @Router /admin/reconcile [get]
+// @Security Bearer
+func New(log *slog.Logger, driftFinder DriftFinder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.admin.drift"))
+
+		drift, err := driftFinder.HitsDrift()
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		log.Info("drift found", slog.Int64("drifted_urls", drift.DriftedUrls))
+		c.JSON(http.StatusOK, drift)
+	}
+}
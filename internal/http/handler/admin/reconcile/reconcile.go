@@ -0,0 +1,42 @@
+package reconcile
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.HitsReconciliation
+
+type HitsReconciler interface {
+	ReconcileHits() (*dto.HitsReconciliation, error)
+}
+
+// @Summary Repair total hits of urls
+// @Description Total hits of drifted urls are set to their raw and rolled up clicks without bots, the same repair is run by the scheduled job
+// @Tags admin
+// @Produce  json
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Failure 500  {object}  api.ErrorResponse
+// @Router /admin/reconcile [post]
+// @Security Bearer
+func New(log *slog.Logger, reconciler HitsReconciler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.admin.reconcile"))
+
+		reconciliation, err := reconciler.ReconcileHits()
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		log.Info("total hits reconciled", slog.Int("repaired_urls", reconciliation.RepairedUrls))
+		c.JSON(http.StatusOK, reconciliation)
+	}
+}
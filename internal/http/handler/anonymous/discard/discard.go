@@ -0,0 +1,36 @@
+package discard
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UrlDeleter interface {
+	DeleteAnonymous(id, token string) error
+}
+
+// @Summary Delete an anonymous short url
+// @Tags anonymous
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param X-Claim-Token header string true "claim token returned on creation"
+// @Success 200
+// @Failure 403  {object}  api.ErrorResponse
+// @Router /anonymous/{id} [delete]
+func New(log *slog.Logger, urlDeleter UrlDeleter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.anonymous.discard"))
+
+		err := urlDeleter.DeleteAnonymous(c.Param("id"), c.GetHeader("X-Claim-Token"))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
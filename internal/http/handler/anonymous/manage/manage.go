@@ -0,0 +1,55 @@
+package manage
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse struct {
+	Url   *dto.PublicUrl    `json:"url"`
+	Stats []repo.DailyCount `json:"stats"`
+}
+
+type UrlGetter interface {
+	ByClaimToken(id, token string) (*model.Url, error)
+}
+
+type StatsGetter interface {
+	StatsByUrlID(urlID string) ([]repo.DailyCount, error)
+}
+
+// @Summary Get an anonymous short url with its stats
+// @Tags anonymous
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param X-Claim-Token header string true "claim token returned on creation"
+// @Success 200  {object}  SuccessResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Router /anonymous/{id} [get]
+func New(log *slog.Logger, urlGetter UrlGetter, statsGetter StatsGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.anonymous.manage"))
+
+		url, err := urlGetter.ByClaimToken(c.Param("id"), c.GetHeader("X-Claim-Token"))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		stats, err := statsGetter.StatsByUrlID(url.ID)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse{Url: dto.ToPublicUrl(url), Stats: stats})
+	}
+}
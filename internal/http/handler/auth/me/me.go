@@ -11,14 +11,20 @@ import (
 )
 
 type SuccessResponse struct {
-	User *dto.PublicUser `json:"user"`
+	User  *dto.PublicUser `json:"user"`
+	Usage *dto.PlanUsage  `json:"usage"`
 }
 
 type UserGetter interface {
 	ById(id string, withContext ...bool) (*model.User, error)
 }
 
+type UsageGetter interface {
+	Usage(userID string) (*dto.PlanUsage, error)
+}
+
 // @Summary Get user data by token
+// @Description Includes usage of the user's plan. Limit 0 means unlimited
 // @Tags auth
 // @Produce  json
 // @Success 200  {object}  SuccessResponse
@@ -27,7 +33,7 @@ type UserGetter interface {
 // @Failure 404  {object}  api.ErrorResponse
 // @Router /auth/me [get]
 // @Security Bearer
-func New(log *slog.Logger, userGetter UserGetter) gin.HandlerFunc {
+func New(log *slog.Logger, userGetter UserGetter, usageGetter UsageGetter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const op = "handler.auth.me"
 		log = log.With(slog.String("op", op))
@@ -43,6 +49,12 @@ func New(log *slog.Logger, userGetter UserGetter) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, SuccessResponse{User: dto.ToPublicUser(user)})
+		usage, err := usageGetter.Usage(user.ID)
+		if err != nil {
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse{User: dto.ToPublicUser(user), Usage: usage})
 	}
 }
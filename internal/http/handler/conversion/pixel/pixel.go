@@ -0,0 +1,46 @@
+package pixel
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gif is a transparent 1x1 GIF
+var gif = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+type Converter interface {
+	Convert(urlID, token string) (bool, error)
+}
+
+// @Summary Conversion pixel
+// @Description Landing pages embed the pixel after a signup or another goal, e.g. <img src="/c/{alias}?click_token={token}">.
+// @Description The conversion is attributed to the click by the token passed to the landing page at the redirect.
+// @Description The pixel is returned even if the conversion isn't recorded, so it never breaks the page
+// @Tags conversion
+// @Produce  image/gif
+// @Param alias path string true "alias for long url"
+// @Param click_token query string false "click token from the query of the landing page"
+// @Success 200
+// @Router /c/{alias} [get]
+func New(log *slog.Logger, converter Converter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.conversion.pixel"))
+
+		// visitors without the token didn't come from the url, so their conversions aren't attributed
+		token := c.Query(api.ClickTokenParam)
+		if _, err := converter.Convert(c.Param("alias"), token); err != nil {
+			log.Warn("conversion isn't recorded", sl.Err(err))
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, "image/gif", gif)
+	}
+}
@@ -0,0 +1,44 @@
+package track
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.ConversionResult
+
+type Converter interface {
+	Convert(urlID, token string) (bool, error)
+}
+
+// @Summary Record conversion
+// @Description The API of the conversion pixel for scripts of landing pages, e.g. navigator.sendBeacon("/c/{alias}?click_token={token}").
+// @Description The conversion is attributed to the click by the token passed to the landing page at the redirect,
+// @Description a click converts only once. Visitors without the token aren't attributed
+// @Tags conversion
+// @Produce  json
+// @Param alias path string true "alias for long url"
+// @Param click_token query string false "click token from the query of the landing page"
+// @Success 200  {object}  SuccessResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Failure 500  {object}  api.ErrorResponse
+// @Router /c/{alias} [post]
+func New(log *slog.Logger, converter Converter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.conversion.track"))
+
+		token := c.Query(api.ClickTokenParam)
+		attributed, err := converter.Convert(c.Param("alias"), token)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse{Attributed: attributed})
+	}
+}
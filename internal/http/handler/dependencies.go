@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"url-shortener/internal/lib/ratelimit"
 	"url-shortener/internal/service/auth"
 	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/live"
+	"url-shortener/internal/service/plan"
+	"url-shortener/internal/service/share"
+	"url-shortener/internal/service/transfer"
 	"url-shortener/internal/service/url"
 	"url-shortener/internal/service/user"
+	"url-shortener/internal/service/webhook"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Dependencies struct {
@@ -13,4 +21,15 @@ type Dependencies struct {
 	AuthService      *auth.AuthService
 	UrlService       *url.UrlService
 	ClickStatService *clickstat.ClickStatService
+	TransferService  *transfer.TransferService
+	PlanService      *plan.PlanService
+	LiveService      *live.LiveService
+	ShareService     *share.ShareService
+	WebhookService   *webhook.WebhookService
+	// ClickRecorder records clicks of redirects. ClickStatService records them synchronously when it's nil
+	ClickRecorder *clickstat.BatchRecorder
+	// AnonymousLimiter is nil when anonymous urls are disabled
+	AnonymousLimiter *ratelimit.Limiter
+	// Metrics registers metrics of requests and redirects, nil disables them. They're served by the metrics server
+	Metrics prometheus.Registerer
 }
@@ -0,0 +1,50 @@
+package shared_breakdown
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []repo.DimensionCount
+
+type SharedBreakdownGetter interface {
+	Breakdown(token, dimension string, includeBots bool) ([]repo.DimensionCount, error)
+}
+
+// @Summary Get clicks of shared url stats by dimension
+// @Description Forbidden when the owner hid breakdowns of the share
+// @Tags public
+// @Produce  json
+// @Param token path string true "share token"
+// @Param dimension path string true "click dimension" Enums(referrer, browser, os, device, language, country)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /public/stats/{token}/breakdown/{dimension} [get]
+func New(log *slog.Logger, breakdownGetter SharedBreakdownGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.public.shared_breakdown"))
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		breakdown, err := breakdownGetter.Breakdown(c.Param("token"), c.Param("dimension"), includeBots)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, breakdown)
+	}
+}
@@ -0,0 +1,61 @@
+package shared_stats
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []repo.DailyCount
+
+type SharedStatsGetter interface {
+	Stats(token string, query *dto.StatsQuery) ([]repo.DailyCount, error)
+}
+
+// @Summary Get shared url stats
+// @Description The same series as the owner gets from /url/{id}, authorized by the share token instead of login
+// @Tags public
+// @Produce  json
+// @Param token path string true "share token"
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param granularity query string false "bucket size" Enums(hour, day, week, month) default(day)
+// @Param tz query string false "IANA time zone of buckets and dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /public/stats/{token} [get]
+func New(log *slog.Logger, statsGetter SharedStatsGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.public.shared_stats"))
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		query := &dto.StatsQuery{
+			From:        c.Query("from"),
+			To:          c.Query("to"),
+			Granularity: c.Query("granularity"),
+			TZ:          c.Query("tz"),
+			IncludeBots: includeBots,
+		}
+
+		stats, err := statsGetter.Stats(c.Param("token"), query)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, stats)
+	}
+}
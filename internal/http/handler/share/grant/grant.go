@@ -0,0 +1,64 @@
+package grant
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.CreateShare
+type SuccessResponse = dto.CreatedShare
+
+type ShareCreator interface {
+	Create(urlID, userID string, shareDto *dto.CreateShare) (*model.StatsShare, string, error)
+}
+
+// @Summary Share stats of user's url
+// @Description The token opens /public/stats/{token} without login until the share is revoked.
+// @Description It's returned only once
+// @Tags share
+// @Accept  json
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param request body Request false "whether breakdowns by dimension are hidden"
+// @Success 201  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/share [post]
+// @Security Bearer
+func New(log *slog.Logger, shareCreator ShareCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.share.grant"))
+
+		var req Request
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				log.Info("invalid input", sl.Err(err))
+				c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+				return
+			}
+		}
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		share, token, err := shareCreator.Create(urlID, userID.(string), &req)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, dto.CreatedShare{PublicShare: *dto.ToPublicShare(share), Token: token})
+	}
+}
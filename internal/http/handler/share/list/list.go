@@ -0,0 +1,54 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []*dto.PublicShare
+
+type SharesGetter interface {
+	ByUrlID(urlID, userID string) ([]model.StatsShare, error)
+}
+
+// @Summary Get shares of user's url stats
+// @Description Newest shares first. Tokens aren't returned
+// @Tags share
+// @Produce  json
+// @Param id path string true "short url id"
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/share [get]
+// @Security Bearer
+func New(log *slog.Logger, sharesGetter SharesGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.share.list"))
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		shares, err := sharesGetter.ByUrlID(urlID, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		res := make(SuccessResponse, len(shares))
+		for i := range shares {
+			res[i] = dto.ToPublicShare(&shares[i])
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
@@ -0,0 +1,44 @@
+package revoke
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareRevoker interface {
+	Revoke(id, urlID, userID string) error
+}
+
+// @Summary Revoke a share of user's url stats
+// @Tags share
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param shareId path string true "share id"
+// @Success 200
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/share/{shareId} [delete]
+// @Security Bearer
+func New(log *slog.Logger, shareRevoker ShareRevoker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.share.revoke"))
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		if err := shareRevoker.Revoke(c.Param("shareId"), urlID, userID.(string)); err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,80 @@
+package compare
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.StatsComparison
+
+type Comparer interface {
+	Compare(userID string, query *dto.CompareQuery) (*dto.StatsComparison, error)
+}
+
+// @Summary Compare stats of user's urls
+// @Description Zero-filled series of clicks and unique visitors of every url with the same buckets, totals and shares of clicks
+// @Description in percents. Ranges are resolved like by the url stats, the last 30 days by day are returned by default
+// @Tags stats
+// @Produce  json
+// @Param ids query string true "comma separated short url ids, at most 10"
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param granularity query string false "bucket size" Enums(hour, day, week, month) default(day)
+// @Param tz query string false "IANA time zone of buckets and dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /stats/compare [get]
+// @Security Bearer
+func New(log *slog.Logger, comparer Comparer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.stats.compare"))
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		var urlIDs []string
+		for _, id := range strings.Split(c.Query("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				urlIDs = append(urlIDs, id)
+			}
+		}
+
+		query := &dto.CompareQuery{
+			StatsQuery: dto.StatsQuery{
+				From:        c.Query("from"),
+				To:          c.Query("to"),
+				Granularity: c.Query("granularity"),
+				TZ:          c.Query("tz"),
+				IncludeBots: includeBots,
+			},
+			UrlIDs: urlIDs,
+		}
+
+		comparison, err := comparer.Compare(userID.(string), query)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, comparison)
+	}
+}
@@ -0,0 +1,162 @@
+package export
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tabular"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of exports
+const (
+	KindSeries = "series"
+	KindClicks = "clicks"
+)
+
+var clickColumns = []string{"alias", "created_at", "referrer", "browser", "os", "device", "language", "country", "bot", "duplicate"}
+
+type Exporter interface {
+	ExportSeries(urlID, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error)
+	ExportClicks(urlID, userID string, query *dto.StatsQuery, each func(click *model.ClickStat) error) error
+}
+
+// @Summary Export stats of user's url or of all user's urls
+// @Description Series of clicks by buckets or raw clicks with all their dimensions as CSV or NDJSON, streamed as they're read.
+// @Description Only clicks younger than the retention of the plan are available raw, older ones are in the series only.
+// @Description Series of all urls have no uniques
+// @Tags stats
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param id path string false "short url id, only for /url/{id}/export"
+// @Param kind query string false "series or raw clicks" Enums(series, clicks) default(series)
+// @Param format query string false "format" Enums(csv, ndjson) default(csv)
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param granularity query string false "bucket size of the series" Enums(hour, day, week, month) default(day)
+// @Param tz query string false "IANA time zone of buckets, dates and click times" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {string}  string
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/export [get]
+// @Router /stats/export [get]
+// @Security Bearer
+func New(log *slog.Logger, exporter Exporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.stats.export"))
+
+		kind := c.DefaultQuery("kind", KindSeries)
+		if kind != KindSeries && kind != KindClicks {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `kind` is invalid"))
+			return
+		}
+		format := c.DefaultQuery("format", tabular.CSV)
+		if format != tabular.CSV && format != tabular.NDJSON {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `format` is invalid"))
+			return
+		}
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		urlID := c.Param("id")
+		query := &dto.StatsQuery{
+			From:        c.Query("from"),
+			To:          c.Query("to"),
+			Granularity: c.Query("granularity"),
+			TZ:          c.Query("tz"),
+			IncludeBots: includeBots,
+		}
+
+		var w *tabular.Writer
+		// the response starts with the first row, so errors before it are still returned as json
+		start := func(columns []string) error {
+			// large exports outlive the write timeout of the server
+			if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+				log.Warn("failed to disable write deadline", sl.Err(err))
+			}
+			name := "clicks"
+			if urlID != "" {
+				name += "-" + urlID
+			}
+			c.Header("Content-Disposition", `attachment; filename="`+name+"-"+kind+"."+format+`"`)
+			c.Header("Content-Type", tabular.ContentType(format))
+			c.Status(http.StatusOK)
+
+			var err error
+			w, err = tabular.New(c.Writer, format, columns)
+			return err
+		}
+
+		if kind == KindSeries {
+			series, err := exporter.ExportSeries(urlID, userID.(string), query)
+			if err != nil {
+				// no need for logs
+				c.JSON(api.ErrReponseFromServiceError(err))
+				return
+			}
+
+			columns := []string{"bucket", "clicks", "raw_clicks", "uniques"}
+			if urlID == "" {
+				columns = columns[:3]
+			}
+			err = start(columns)
+			for i := 0; err == nil && i < len(series); i++ {
+				if urlID == "" {
+					err = w.Write(series[i].Day, series[i].Count, series[i].Raw)
+				} else {
+					err = w.Write(series[i].Day, series[i].Count, series[i].Raw, series[i].Uniques)
+				}
+			}
+			if err == nil {
+				err = w.Flush()
+			}
+			if err != nil {
+				log.Warn("failed to write export", sl.Err(err))
+			}
+			return
+		}
+
+		err = exporter.ExportClicks(urlID, userID.(string), query, func(click *model.ClickStat) error {
+			if w == nil {
+				if err := start(clickColumns); err != nil {
+					return err
+				}
+			}
+			return w.Write(click.UrlID, click.CreatedAt, click.ReferrerHost, click.Browser, click.OS, click.Device, click.Language, click.Country, click.Bot, click.Duplicate)
+		})
+		if err != nil && w == nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+		if err == nil && w == nil {
+			err = start(clickColumns)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			// the response is already started, so it's cut short
+			log.Warn("failed to write export", sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package overview
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.StatsOverview
+
+type OverviewGetter interface {
+	Overview(userID string, query *dto.OverviewQuery) (*dto.StatsOverview, error)
+}
+
+// @Summary Get stats of all user's urls
+// @Description Total clicks, zero-filled series of clicks, top urls by clicks and numbers of created urls in the range
+// @Description compared with the previous period of the same length. The last 30 days by day are returned by default
+// @Tags stats
+// @Produce  json
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param granularity query string false "bucket size" Enums(hour, day, week, month) default(day)
+// @Param tz query string false "IANA time zone of buckets and dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Param top query int false "number of top urls, at most 100" default(10)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /stats/overview [get]
+// @Security Bearer
+func New(log *slog.Logger, overviewGetter OverviewGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.stats.overview"))
+
+		top, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `top` is invalid"))
+			return
+		}
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		query := &dto.OverviewQuery{
+			StatsQuery: dto.StatsQuery{
+				From:        c.Query("from"),
+				To:          c.Query("to"),
+				Granularity: c.Query("granularity"),
+				TZ:          c.Query("tz"),
+				IncludeBots: includeBots,
+			},
+			Top: top,
+		}
+
+		overview, err := overviewGetter.Overview(userID.(string), query)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, overview)
+	}
+}
@@ -0,0 +1,51 @@
+package accept
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = *dto.PublicTransfer
+
+type TransferAccepter interface {
+	Accept(id, userID string) (*model.UrlTransfer, error)
+}
+
+// @Summary Accept an incoming transfer
+// @Description Urls become owned by the current user together with their click stats
+// @Tags transfer
+// @Produce  json
+// @Param id path string true "transfer id"
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Failure 409  {object}  api.ErrorResponse
+// @Router /transfer/{id}/accept [post]
+// @Security Bearer
+func New(log *slog.Logger, transferAccepter TransferAccepter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.transfer.accept"))
+
+		id := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		transfer, err := transferAccepter.Accept(id, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToPublicTransfer(transfer))
+	}
+}
@@ -0,0 +1,43 @@
+package cancel
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferCloser interface {
+	Close(id, userID string) error
+}
+
+// @Summary Cancel an outgoing or decline an incoming transfer
+// @Tags transfer
+// @Produce  json
+// @Param id path string true "transfer id"
+// @Success 200
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /transfer/{id} [delete]
+// @Security Bearer
+func New(log *slog.Logger, transferCloser TransferCloser) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.transfer.cancel"))
+
+		id := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		if err := transferCloser.Close(id, userID.(string)); err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,60 @@
+package offer
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.CreateTransfer
+type SuccessResponse = *dto.PublicTransfer
+
+type TransferCreator interface {
+	Create(transferDto *dto.CreateTransfer, userID string) (*model.UrlTransfer, error)
+}
+
+// @Summary Offer user's short urls to another user
+// @Description Ownership changes only after the recipient accepts the transfer
+// @Tags transfer
+// @Accept  json
+// @Produce  json
+// @Param request body Request true "recipient's email and url ids"
+// @Success 201  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Failure 409  {object}  api.ErrorResponse
+// @Router /transfer [post]
+// @Security Bearer
+func New(log *slog.Logger, transferCreator TransferCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.transfer.offer"))
+
+		var req Request
+		if err := c.ShouldBind(&req); err != nil {
+			log.Info("invalid input", sl.Err(err))
+			c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		transfer, err := transferCreator.Create(&req, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, dto.ToPublicTransfer(transfer))
+	}
+}
@@ -0,0 +1,50 @@
+package pending
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []*dto.PublicTransfer
+
+type TransfersGetter interface {
+	Pending(userID string) ([]model.UrlTransfer, error)
+}
+
+// @Summary Get user's incoming and outgoing pending transfers
+// @Tags transfer
+// @Produce  json
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /transfer [get]
+// @Security Bearer
+func New(log *slog.Logger, transfersGetter TransfersGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.transfer.pending"))
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		transfers, err := transfersGetter.Pending(userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		publicTransfers := make([]*dto.PublicTransfer, len(transfers))
+		for i := range transfers {
+			publicTransfers[i] = dto.ToPublicTransfer(&transfers[i])
+		}
+
+		c.JSON(http.StatusOK, publicTransfers)
+	}
+}
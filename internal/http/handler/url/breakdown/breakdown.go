@@ -0,0 +1,57 @@
+package breakdown
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []repo.DimensionCount
+
+type BreakdownGetter interface {
+	Breakdown(urlID, userID, dimension string, includeBots bool) ([]repo.DimensionCount, error)
+}
+
+// @Summary Get clicks of user's url by dimension
+// @Description Most popular values first. Clicks without a value are counted as "unknown"
+// @Tags url
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param dimension path string true "click dimension" Enums(referrer, browser, os, device, language, country)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /url/{id}/breakdown/{dimension} [get]
+// @Security Bearer
+func New(log *slog.Logger, breakdownGetter BreakdownGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.breakdown"))
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		breakdown, err := breakdownGetter.Breakdown(urlID, userID.(string), c.Param("dimension"), includeBots)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, breakdown)
+	}
+}
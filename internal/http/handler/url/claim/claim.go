@@ -0,0 +1,61 @@
+package claim
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.ClaimUrl
+type SuccessResponse = *dto.PublicUrl
+
+type UrlClaimer interface {
+	Claim(id, userID string, claimDto *dto.ClaimUrl) (*model.Url, error)
+}
+
+// @Summary Claim an anonymous short url into user's account
+// @Description The url stops expiring and its claim token stops working
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param request body Request true "claim token returned on anonymous creation"
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 403  {object}  api.ErrorResponse
+// @Router /url/{id}/claim [post]
+// @Security Bearer
+func New(log *slog.Logger, urlClaimer UrlClaimer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.claim"))
+
+		var req Request
+		if err := c.ShouldBind(&req); err != nil {
+			log.Info("invalid input", sl.Err(err))
+			c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+			return
+		}
+
+		id := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		url, err := urlClaimer.Claim(id, userID.(string), &req)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToPublicUrl(url))
+	}
+}
@@ -14,20 +14,26 @@ import (
 type Request = dto.CreateUrl
 type SuccessResponse = *dto.PublicUrl
 
+type AnonymousSuccessResponse = *dto.AnonymousUrl
+
 type UrlCreator interface {
 	Create(urlDto *dto.CreateUrl, userID string) (*model.Url, error)
+	CreateAnonymous(urlDto *dto.CreateUrl) (*model.Url, string, error)
 }
 
 // @Summary Create a short url
+// @Description Without authorization (if enabled) creates an expiring url and returns its claim token
 // @Tags url
 // @Accept  json
 // @Produce  json
 // @Param request body Request true "alias is optional"
 // @Success 201  {object}  SuccessResponse
+// @Success 201  {object}  AnonymousSuccessResponse
 // @Failure 400  {object}  api.ErrorResponse
 // @Failure 401  {object}  api.ErrorResponse
 // @Failure 409  {object}  api.ErrorResponse
 // @Failure 422  {object}  api.ErrorResponse
+// @Failure 429  {object}  api.ErrorResponse
 // @Router /url [post]
 // @Security Bearer
 func New(log *slog.Logger, urlCreator UrlCreator) gin.HandlerFunc {
@@ -41,9 +47,17 @@ func New(log *slog.Logger, urlCreator UrlCreator) gin.HandlerFunc {
 			return
 		}
 
+		// user_id is absent only on routes that allow anonymous urls
 		userID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			url, token, err := urlCreator.CreateAnonymous(&req)
+			if err != nil {
+				// no need for logs
+				c.JSON(api.ErrReponseFromServiceError(err))
+				return
+			}
+
+			c.JSON(http.StatusCreated, dto.AnonymousUrl{PublicUrl: *dto.ToPublicUrl(url), ClaimToken: token})
 			return
 		}
 
@@ -0,0 +1,62 @@
+package dedup
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.UrlDedup
+type SuccessResponse = *dto.PublicUrl
+
+type DedupSetter interface {
+	SetDedupWindow(id, userID string, dedup *dto.UrlDedup) (*model.Url, error)
+}
+
+// @Summary Set the de-duplication window of user's short url
+// @Description Clicks of the same visitor within the window after a counted click are duplicates. They're redirected and counted only as raw clicks.
+// @Description The window is in seconds, 0 disables de-duplication and an empty window resets it to the global one.
+// @Description The change is recorded in the url history, the global window is an empty value
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param request body Request true "window in seconds"
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/dedup [put]
+// @Security Bearer
+func New(log *slog.Logger, dedupSetter DedupSetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.url.dedup"))
+
+		var req Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Info("invalid input", sl.Err(err))
+			c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		url, err := dedupSetter.SetDedupWindow(c.Param("id"), userID.(string), &req)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToPublicUrl(url))
+	}
+}
@@ -0,0 +1,67 @@
+package heatmap
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.Heatmap
+
+type HeatmapGetter interface {
+	Heatmap(urlID, userID string, query *dto.StatsQuery) (*dto.Heatmap, error)
+}
+
+// @Summary Get clicks of user's url by weekday and hour
+// @Description 7×24 clicks by weekday from Monday and hour in tz. The last 30 days are returned by default.
+// @Description Clicks older than the retention of the plan are kept as daily rollups, so they aren't counted
+// @Tags url
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param tz query string false "IANA time zone of weekdays, hours and dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/heatmap [get]
+// @Security Bearer
+func New(log *slog.Logger, heatmapGetter HeatmapGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.heatmap"))
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		query := &dto.StatsQuery{
+			From:        c.Query("from"),
+			To:          c.Query("to"),
+			TZ:          c.Query("tz"),
+			IncludeBots: includeBots,
+		}
+
+		heatmap, err := heatmapGetter.Heatmap(urlID, userID.(string), query)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, heatmap)
+	}
+}
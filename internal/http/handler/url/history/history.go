@@ -0,0 +1,54 @@
+package history
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []*dto.PublicUrlHistory
+
+type HistoryGetter interface {
+	History(id, userID string) ([]repo.UrlHistoryEntry, error)
+}
+
+// @Summary Get the change history of user's short url
+// @Description Newest changes first. Destination changes include clicks made while they were live, rolled up days of clicks count in the destination live at the end of the UTC day
+// @Tags url
+// @Produce  json
+// @Param id path string true "short url id"
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/history [get]
+// @Security Bearer
+func New(log *slog.Logger, historyGetter HistoryGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.history"))
+
+		id := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		history, err := historyGetter.History(id, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		publicHistory := make([]*dto.PublicUrlHistory, len(history))
+		for i := range history {
+			publicHistory[i] = dto.ToPublicUrlHistory(&history[i].UrlHistory, history[i].Clicks)
+		}
+
+		c.JSON(http.StatusOK, publicHistory)
+	}
+}
@@ -0,0 +1,78 @@
+package live
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClickSubscriber interface {
+	Subscribe(urlID, userID string) (<-chan dto.LiveClick, func(), error)
+	Heartbeat() time.Duration
+}
+
+// @Summary Stream clicks of user's url or of all user's urls
+// @Description Server-sent events named "click" with the click as json data are pushed as clicks are recorded.
+// @Description Clicks recorded before the stream is opened aren't sent, a slow client misses clicks
+// @Tags stats
+// @Produce  text/event-stream
+// @Param id path string false "short url id, only for /url/{id}/live"
+// @Success 200  {object}  dto.LiveClick
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/live [get]
+// @Router /stats/live [get]
+// @Security Bearer
+func New(log *slog.Logger, subscriber ClickSubscriber) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := log.With(slog.String("op", "handler.url.live"))
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		clicks, unsubscribe, err := subscriber.Subscribe(c.Param("id"), userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+		defer unsubscribe()
+
+		// the stream outlives the write timeout of the server
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to disable write deadline", sl.Err(err))
+		}
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(subscriber.Heartbeat())
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case click, ok := <-clicks:
+				if !ok {
+					return
+				}
+				c.SSEvent("click", click)
+			case <-heartbeat.C:
+				if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
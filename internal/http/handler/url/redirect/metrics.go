@@ -0,0 +1,53 @@
+package redirect
+
+import (
+	"errors"
+	"time"
+	"url-shortener/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// lookupBuckets are finer than the default ones, lookups are expected to take a few milliseconds
+var lookupBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+type instrumentedLinkGetter struct {
+	linkGetter LinkGetter
+	redirects  *prometheus.CounterVec
+	latency    prometheus.Histogram
+}
+
+// Instrument counts redirects by result and observes latencies of link lookups
+func Instrument(linkGetter LinkGetter, reg prometheus.Registerer) LinkGetter {
+	factory := promauto.With(reg)
+	return &instrumentedLinkGetter{
+		linkGetter: linkGetter,
+		redirects: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "redirects_total",
+			Help: "Redirects by result: found, not_found or error.",
+		}, []string{"result"}),
+		latency: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "redirect_lookup_duration_seconds",
+			Help:    "Latency of link lookups of redirects.",
+			Buckets: lookupBuckets,
+		}),
+	}
+}
+
+func (g *instrumentedLinkGetter) RedirectLinkByID(id string) (string, error) {
+	start := time.Now()
+	link, err := g.linkGetter.RedirectLinkByID(id)
+	g.latency.Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil:
+		g.redirects.WithLabelValues("found").Inc()
+	case errors.Is(err, service.ErrUrlNotFound):
+		g.redirects.WithLabelValues("not_found").Inc()
+	default:
+		g.redirects.WithLabelValues("error").Inc()
+	}
+
+	return link, err
+}
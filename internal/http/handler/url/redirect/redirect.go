@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"net/http"
 	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,18 +14,27 @@ type LinkGetter interface {
 	RedirectLinkByID(id string) (string, error)
 }
 type ClickRecorder interface {
-	Record(urlID string) error
+	Record(urlID string, visit *dto.Visit) error
 }
+type ClickTokenIssuer interface {
+	ClickToken(urlID string) (string, string)
+}
+
+// purposeHeaders mark prefetch requests of browsers and link previews
+var purposeHeaders = []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"}
 
 // @Summary Redirect
+// @Description HEAD and prefetch requests are redirected too, their clicks are recorded as bots.
+// @Description GET requests pass the click token to the destination in the click_token query parameter,
+// @Description landing pages report conversions with it, see /c/{alias}
 // @Produce  json
 // @Param alias path string true "alias for long url"
 // @Success 302
 // @Failure 404  {object}  api.ErrorResponse
 // @Router /{alias} [get]
-func New(log *slog.Logger, linkGetter LinkGetter, clickRecorder ClickRecorder) gin.HandlerFunc {
+func New(log *slog.Logger, linkGetter LinkGetter, clickRecorder ClickRecorder, clickTokens ClickTokenIssuer) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log = log.With(slog.String("op", "handler.url.create"))
+		log := log.With(slog.String("op", "handler.url.redirect"))
 
 		alias := c.Param("alias")
 		if alias == "" {
@@ -37,13 +48,36 @@ func New(log *slog.Logger, linkGetter LinkGetter, clickRecorder ClickRecorder) g
 			c.JSON(api.ErrReponseFromServiceError(err))
 			return
 		}
-		err = clickRecorder.Record(alias)
-		if err != nil {
-			// no need for logs
-			c.JSON(api.ErrReponseFromServiceError(err))
-			return
+		visit := &dto.Visit{
+			IP:             c.ClientIP(),
+			UserAgent:      c.Request.UserAgent(),
+			Referrer:       c.Request.Referer(),
+			AcceptLanguage: c.GetHeader("Accept-Language"),
+			Method:         c.Request.Method,
+			Purpose:        purpose(c),
+		}
+		// conversions are attributed to the click by the token, the click counts them if it's counted itself
+		var token string
+		if c.Request.Method == http.MethodGet {
+			token, visit.ClickID = clickTokens.ClickToken(alias)
+		}
+
+		// the visitor is redirected even if the click isn't recorded
+		if err := clickRecorder.Record(alias, visit); err != nil {
+			log.Warn("click isn't recorded", sl.Err(err))
+		} else if token != "" {
+			link = api.WithClickToken(link, token)
 		}
 
 		c.Redirect(http.StatusFound, link)
 	}
 }
+
+func purpose(c *gin.Context) string {
+	for _, header := range purposeHeaders {
+		if value := c.GetHeader(header); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package rollback
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = *dto.PublicUrl
+
+type UrlRollbacker interface {
+	Rollback(id string, historyID int64, userID string) (*model.Url, error)
+}
+
+// @Summary Roll back user's short url to a previous destination
+// @Tags url
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param historyId path int true "history entry id whose destination becomes live"
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id}/history/{historyId}/rollback [post]
+// @Security Bearer
+func New(log *slog.Logger, urlRollbacker UrlRollbacker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.rollback"))
+
+		id := c.Param("id")
+		historyID, err := strconv.ParseInt(c.Param("historyId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("path parameter `historyId` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		url, err := urlRollbacker.Rollback(id, historyID, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToPublicUrl(url))
+	}
+}
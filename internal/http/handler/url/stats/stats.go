@@ -3,8 +3,10 @@ package stats
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 	"url-shortener/internal/database/repo"
 	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,14 +14,23 @@ import (
 type SuccessResponse = []repo.DailyCount
 
 type StatsGetter interface {
-	Stats(urlID string, userID string) ([]repo.DailyCount, error)
+	Stats(urlID string, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error)
 }
 
 // @Summary Get user's url stats
+// @Description Zero-filled series of clicks and unique visitors. The last 30 days by day are returned by default.
+// @Description Clicks older than the retention of the plan are kept as daily rollups, so by hour they aren't available.
+// @Description Uniques are counted by UTC days and aren't available by hour
 // @Tags url
 // @Produce  json
-// @Param id path int true "short url id"
+// @Param id path string true "short url id"
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param granularity query string false "bucket size" Enums(hour, day, week, month) default(day)
+// @Param tz query string false "IANA time zone of buckets and dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
 // @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
 // @Failure 404  {object}  api.ErrorResponse
 // @Router /url/{id} [get]
 // @Security Bearer
@@ -27,6 +38,12 @@ func New(log *slog.Logger, statsGetter StatsGetter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log = log.With(slog.String("op", "handler.url.stats"))
 
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
 		urlID := c.Param("id")
 		userID, ok := c.Get("user_id")
 		if !ok {
@@ -34,7 +51,15 @@ func New(log *slog.Logger, statsGetter StatsGetter) gin.HandlerFunc {
 			return
 		}
 
-		stats, err := statsGetter.Stats(urlID, userID.(string))
+		query := &dto.StatsQuery{
+			From:        c.Query("from"),
+			To:          c.Query("to"),
+			Granularity: c.Query("granularity"),
+			TZ:          c.Query("tz"),
+			IncludeBots: includeBots,
+		}
+
+		stats, err := statsGetter.Stats(urlID, userID.(string), query)
 		if err != nil {
 			// no need for logs
 			c.JSON(api.ErrReponseFromServiceError(err))
@@ -0,0 +1,77 @@
+package top
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = dto.TopValues
+
+type TopValuesGetter interface {
+	TopValues(urlID, userID string, query *dto.TopValuesQuery) (*dto.TopValues, error)
+}
+
+// @Summary Get top referrers and countries of user's url
+// @Description Most popular values in the range first. Clicks without a value are counted as "unknown".
+// @Description The last 30 days are returned by default
+// @Tags url
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param from query string false "start of the range, RFC 3339 time or date in tz"
+// @Param to query string false "end of the range, RFC 3339 time or date in tz (inclusive)"
+// @Param tz query string false "IANA time zone of dates" default(UTC)
+// @Param include_bots query bool false "count clicks of bots, link unfurlers, monitors and prefetching" default(false)
+// @Param top query int false "number of values of each dimension, at most 100" default(10)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /url/{id}/top [get]
+// @Security Bearer
+func New(log *slog.Logger, topValuesGetter TopValuesGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.top"))
+
+		top, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `top` is invalid"))
+			return
+		}
+
+		includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `include_bots` is invalid"))
+			return
+		}
+
+		urlID := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		query := &dto.TopValuesQuery{
+			StatsQuery: dto.StatsQuery{
+				From:        c.Query("from"),
+				To:          c.Query("to"),
+				TZ:          c.Query("tz"),
+				IncludeBots: includeBots,
+			},
+			Top: top,
+		}
+
+		values, err := topValuesGetter.TopValues(urlID, userID.(string), query)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, values)
+	}
+}
@@ -0,0 +1,61 @@
+package update
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.UpdateUrl
+type SuccessResponse = *dto.PublicUrl
+
+type UrlUpdater interface {
+	Update(id, userID string, urlDto *dto.UpdateUrl) (*model.Url, error)
+}
+
+// @Summary Change the destination of user's short url
+// @Description The change is appended to the url's history
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param id path string true "short url id"
+// @Param request body Request true "new destination"
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /url/{id} [patch]
+// @Security Bearer
+func New(log *slog.Logger, urlUpdater UrlUpdater) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.url.update"))
+
+		var req Request
+		if err := c.ShouldBind(&req); err != nil {
+			log.Info("invalid input", sl.Err(err))
+			c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+			return
+		}
+
+		id := c.Param("id")
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		url, err := urlUpdater.Update(id, userID.(string), &req)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToPublicUrl(url))
+	}
+}
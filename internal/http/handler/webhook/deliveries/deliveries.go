@@ -0,0 +1,62 @@
+package deliveries
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []*dto.PublicWebhookDelivery
+
+type DeliveriesGetter interface {
+	Deliveries(webhookID, userID string, limit int) ([]model.WebhookDelivery, error)
+}
+
+// @Summary Get the delivery log of user's webhook
+// @Description Newest deliveries first. Pending deliveries are retried at nextAttemptAt
+// @Tags webhook
+// @Produce  json
+// @Param id path string true "webhook id"
+// @Param limit query int false "number of deliveries, at most 100" default(50)
+// @Success 200  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+// @Security Bearer
+func New(log *slog.Logger, deliveriesGetter DeliveriesGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.webhook.deliveries"))
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("query parameter `limit` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		deliveries, err := deliveriesGetter.Deliveries(c.Param("id"), userID.(string), limit)
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		res := make(SuccessResponse, len(deliveries))
+		for i := range deliveries {
+			res[i] = dto.ToPublicWebhookDelivery(&deliveries[i])
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
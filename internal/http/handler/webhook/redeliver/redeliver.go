@@ -0,0 +1,57 @@
+package redeliver
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = *dto.PublicWebhookDelivery
+
+type Redeliverer interface {
+	Redeliver(id int64, webhookID, userID string) (*model.WebhookDelivery, error)
+}
+
+// @Summary Deliver the event of a delivery again
+// @Description A new delivery of the same payload is queued, the log of the original one is kept
+// @Tags webhook
+// @Produce  json
+// @Param id path string true "webhook id"
+// @Param deliveryId path int true "delivery id"
+// @Success 202  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+// @Security Bearer
+func New(log *slog.Logger, redeliverer Redeliverer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.webhook.redeliver"))
+
+		deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, api.ErrResponse("path parameter `deliveryId` is invalid"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		delivery, err := redeliverer.Redeliver(deliveryID, c.Param("id"), userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusAccepted, dto.ToPublicWebhookDelivery(delivery))
+	}
+}
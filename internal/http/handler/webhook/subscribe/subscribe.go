@@ -0,0 +1,60 @@
+package subscribe
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Request = dto.CreateWebhook
+type SuccessResponse = dto.CreatedWebhook
+
+type WebhookCreator interface {
+	Create(webhookDto *dto.CreateWebhook, userID string) (*model.Webhook, error)
+}
+
+// @Summary Register a webhook
+// @Description Events of user's urls are posted to the endpoint as JSON. The X-Webhook-Signature header is
+// @Description "sha256=" and hex HMAC-SHA256 of X-Webhook-Timestamp, a dot and the body keyed by the secret.
+// @Description The secret is returned only once. Failed deliveries are retried with exponential backoff
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param request body Request true "endpoint and events: url.created, url.updated, url.deleted, click.recorded, click.milestone"
+// @Success 201  {object}  SuccessResponse
+// @Failure 400  {object}  api.ErrorResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /webhooks [post]
+// @Security Bearer
+func New(log *slog.Logger, webhookCreator WebhookCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.webhook.subscribe"))
+
+		var req Request
+		if err := c.ShouldBind(&req); err != nil {
+			log.Info("invalid input", sl.Err(err))
+			c.JSON(http.StatusBadRequest, api.ErrResponse("invalid input"))
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		webhook, err := webhookCreator.Create(&req, userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, dto.CreatedWebhook{PublicWebhook: *dto.ToPublicWebhook(webhook), Secret: webhook.Secret})
+	}
+}
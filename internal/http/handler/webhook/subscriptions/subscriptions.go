@@ -0,0 +1,51 @@
+package subscriptions
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuccessResponse = []*dto.PublicWebhook
+
+type WebhooksGetter interface {
+	ByUserID(userID string) ([]model.Webhook, error)
+}
+
+// @Summary Get user's webhooks
+// @Description Newest webhooks first. Secrets aren't returned
+// @Tags webhook
+// @Produce  json
+// @Success 200  {object}  SuccessResponse
+// @Failure 401  {object}  api.ErrorResponse
+// @Router /webhooks [get]
+// @Security Bearer
+func New(log *slog.Logger, webhooksGetter WebhooksGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.webhook.subscriptions"))
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		webhooks, err := webhooksGetter.ByUserID(userID.(string))
+		if err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		res := make(SuccessResponse, len(webhooks))
+		for i := range webhooks {
+			res[i] = dto.ToPublicWebhook(&webhooks[i])
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
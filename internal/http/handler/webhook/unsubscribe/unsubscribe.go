@@ -0,0 +1,43 @@
+package unsubscribe
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookDeleter interface {
+	Delete(id, userID string) error
+}
+
+// @Summary Delete a webhook
+// @Description Queued deliveries are dropped with the delivery log
+// @Tags webhook
+// @Produce  json
+// @Param id path string true "webhook id"
+// @Success 200
+// @Failure 401  {object}  api.ErrorResponse
+// @Failure 404  {object}  api.ErrorResponse
+// @Router /webhooks/{id} [delete]
+// @Security Bearer
+func New(log *slog.Logger, webhookDeleter WebhookDeleter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log = log.With(slog.String("op", "handler.webhook.unsubscribe"))
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, api.ErrResponse("authorization error"))
+			return
+		}
+
+		if err := webhookDeleter.Delete(c.Param("id"), userID.(string)); err != nil {
+			// no need for logs
+			c.JSON(api.ErrReponseFromServiceError(err))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminChecker interface {
+	IsAdmin(userID string) (bool, error)
+}
+
+// Admin lets only admins through. It has to be used after Auth
+func Admin(adminChecker AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid authorization"})
+			return
+		}
+
+		isAdmin, err := adminChecker.IsAdmin(userID.(string))
+		if err != nil || !isAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "forbidden"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminChecker map[string]bool
+
+func (a adminChecker) IsAdmin(userID string) (bool, error) {
+	if userID == "404" {
+		return false, errors.New("user not found")
+	}
+	return a[userID], nil
+}
+
+func TestAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		status int
+	}{
+		{name: "admin", userID: "1234", status: http.StatusOK},
+		{name: "not admin", userID: "5678", status: http.StatusForbidden},
+		{name: "user not found", userID: "404", status: http.StatusForbidden},
+		{name: "no user", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.userID != "" {
+					c.Set("user_id", tt.userID)
+				}
+			})
+			router.Use(middleware.Admin(adminChecker{"1234": true}))
+			router.GET("/admin", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -36,3 +36,18 @@ func Auth(jwtParser JWTParser) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth lets requests without authorization header through as anonymous.
+// A present but invalid header is still rejected
+func OptionalAuth(jwtParser JWTParser) gin.HandlerFunc {
+	auth := Auth(jwtParser)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		auth(c)
+	}
+}
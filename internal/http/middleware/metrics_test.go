@@ -0,0 +1,36 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	router := gin.New()
+	router.Use(middleware.Metrics(reg))
+	router.GET("/url/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/url/1", "/url/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	res := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	// requests are grouped by the route pattern
+	body := res.Body.String()
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/url/:id",status="204"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/url/:id",status="204"} 2`)
+}
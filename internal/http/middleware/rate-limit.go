@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimiter interface {
+	Allow(key string) bool
+}
+
+// AnonymousRateLimit limits requests without user_id by client ip. Authorized requests aren't limited
+func AnonymousRateLimit(limiter RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("user_id"); ok {
+			c.Next()
+			return
+		}
+
+		if !limiter.Allow(c.ClientIP()) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
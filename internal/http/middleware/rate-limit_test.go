@@ -0,0 +1,49 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/http/middleware"
+	"url-shortener/internal/lib/ratelimit"
+	"url-shortener/internal/service/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymousRateLimit(t *testing.T) {
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	token, err := jwtService.Generate("1234")
+	require.NoError(t, err)
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.Use(middleware.OptionalAuth(jwtService), middleware.AnonymousRateLimit(ratelimit.New(1, time.Hour)))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	requests := 0
+	request := func(authHeader string) int {
+		requests++
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", authHeader)
+		// clients without trusted proxies can't pick their ip
+		req.Header.Set("X-Forwarded-For", "10.0.0."+strconv.Itoa(requests))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(""))
+	assert.Equal(t, http.StatusTooManyRequests, request(""))
+	// authorized requests aren't limited
+	assert.Equal(t, http.StatusOK, request("Bearer "+token))
+	assert.Equal(t, http.StatusOK, request("Bearer "+token))
+	// invalid authorization isn't treated as anonymous
+	assert.Equal(t, http.StatusUnauthorized, request("Bearer invalid"))
+}
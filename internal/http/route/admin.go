@@ -0,0 +1,20 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/admin/cleanup"
+	"url-shortener/internal/http/handler/admin/drift"
+	"url-shortener/internal/http/handler/admin/reconcile"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Admin(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/admin", middleware.Auth(deps.JwtService), middleware.Admin(deps.UserService))
+
+	r.GET("/cleanup", cleanup.New(log, deps.ClickStatService))
+	r.GET("/reconcile", drift.New(log, deps.ClickStatService))
+	r.POST("/reconcile", reconcile.New(log, deps.ClickStatService))
+}
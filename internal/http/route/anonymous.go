@@ -0,0 +1,18 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/anonymous/discard"
+	"url-shortener/internal/http/handler/anonymous/manage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anonymous routes are authorized by the url's claim token
+func Anonymous(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/anonymous")
+
+	r.GET(":id", manage.New(log, deps.UrlService, deps.ClickStatService))
+	r.DELETE(":id", discard.New(log, deps.UrlService))
+}
@@ -14,7 +14,7 @@ import (
 func Auth(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
 	r := router.Group("/auth")
 
-	r.GET("/me", middleware.Auth(deps.JwtService), me.New(log, deps.UserService))
+	r.GET("/me", middleware.Auth(deps.JwtService), me.New(log, deps.UserService, deps.PlanService))
 	r.POST("/register", register.New(log, deps.AuthService))
 	r.POST("/login", login.New(log, deps.AuthService))
 }
@@ -0,0 +1,19 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/conversion/pixel"
+	"url-shortener/internal/http/handler/conversion/track"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Conversion routes are beacons of landing pages. They're authorized by the click token passed to destinations
+// at redirects, so they're on the root like redirects
+func Conversion(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/c")
+
+	r.GET(":alias", pixel.New(log, deps.ClickStatService))
+	r.POST(":alias", track.New(log, deps.ClickStatService))
+}
@@ -0,0 +1,28 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	shared_breakdown "url-shortener/internal/http/handler/public/shared-breakdown"
+	shared_stats "url-shortener/internal/http/handler/public/shared-stats"
+	"url-shortener/internal/http/handler/share/grant"
+	"url-shortener/internal/http/handler/share/list"
+	"url-shortener/internal/http/handler/share/revoke"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Share routes manage shares of url stats. Public routes are authorized by the share token
+func Share(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/url", middleware.Auth(deps.JwtService))
+
+	r.POST(":id/share", grant.New(log, deps.ShareService))
+	r.GET(":id/share", list.New(log, deps.ShareService))
+	r.DELETE(":id/share/:shareId", revoke.New(log, deps.ShareService))
+
+	public := router.Group("/public/stats")
+
+	public.GET(":token", shared_stats.New(log, deps.ShareService))
+	public.GET(":token/breakdown/:dimension", shared_breakdown.New(log, deps.ShareService))
+}
@@ -0,0 +1,22 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/stats/compare"
+	"url-shortener/internal/http/handler/stats/export"
+	"url-shortener/internal/http/handler/stats/overview"
+	"url-shortener/internal/http/handler/url/live"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Stats(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/stats", middleware.Auth(deps.JwtService))
+
+	r.GET("/overview", overview.New(log, deps.ClickStatService))
+	r.GET("/compare", compare.New(log, deps.ClickStatService))
+	r.GET("/export", export.New(log, deps.ClickStatService))
+	r.GET("/live", live.New(log, deps.LiveService))
+}
@@ -0,0 +1,22 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/transfer/accept"
+	"url-shortener/internal/http/handler/transfer/cancel"
+	"url-shortener/internal/http/handler/transfer/offer"
+	"url-shortener/internal/http/handler/transfer/pending"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Transfer(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/transfer", middleware.Auth(deps.JwtService))
+
+	r.POST("", offer.New(log, deps.TransferService))
+	r.GET("", pending.New(log, deps.TransferService))
+	r.POST(":id/accept", accept.New(log, deps.TransferService))
+	r.DELETE(":id", cancel.New(log, deps.TransferService))
+}
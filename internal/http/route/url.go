@@ -3,11 +3,21 @@ package route
 import (
 	"log/slog"
 	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/stats/export"
+	"url-shortener/internal/http/handler/url/breakdown"
 	by_user "url-shortener/internal/http/handler/url/by-user"
+	"url-shortener/internal/http/handler/url/claim"
 	"url-shortener/internal/http/handler/url/create"
+	"url-shortener/internal/http/handler/url/dedup"
+	"url-shortener/internal/http/handler/url/heatmap"
+	"url-shortener/internal/http/handler/url/history"
+	"url-shortener/internal/http/handler/url/live"
 	"url-shortener/internal/http/handler/url/redirect"
 	"url-shortener/internal/http/handler/url/remove"
+	"url-shortener/internal/http/handler/url/rollback"
 	"url-shortener/internal/http/handler/url/stats"
+	"url-shortener/internal/http/handler/url/top"
+	"url-shortener/internal/http/handler/url/update"
 	"url-shortener/internal/http/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -16,9 +26,32 @@ import (
 func Url(root gin.IRouter, router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
 	r := router.Group("/url", middleware.Auth(deps.JwtService))
 
-	root.GET("/:alias", redirect.New(log, deps.UrlService, deps.ClickStatService))
-	r.POST("", create.New(log, deps.UrlService))
+	var clickRecorder redirect.ClickRecorder = deps.ClickStatService
+	if deps.ClickRecorder != nil {
+		clickRecorder = deps.ClickRecorder
+	}
+	var linkGetter redirect.LinkGetter = deps.UrlService
+	if deps.Metrics != nil {
+		linkGetter = redirect.Instrument(linkGetter, deps.Metrics)
+	}
+	root.GET("/:alias", redirect.New(log, linkGetter, clickRecorder, deps.ClickStatService))
+	root.HEAD("/:alias", redirect.New(log, linkGetter, clickRecorder, deps.ClickStatService))
+	if deps.AnonymousLimiter != nil {
+		router.POST("/url", middleware.OptionalAuth(deps.JwtService), middleware.AnonymousRateLimit(deps.AnonymousLimiter), create.New(log, deps.UrlService))
+	} else {
+		r.POST("", create.New(log, deps.UrlService))
+	}
 	r.GET("", by_user.New(log, deps.UrlService))
 	r.DELETE(":id", remove.New(log, deps.UrlService))
+	r.PATCH(":id", update.New(log, deps.UrlService))
+	r.PUT(":id/dedup", dedup.New(log, deps.UrlService))
 	r.GET(":id", stats.New(log, deps.ClickStatService))
+	r.GET(":id/breakdown/:dimension", breakdown.New(log, deps.ClickStatService))
+	r.GET(":id/heatmap", heatmap.New(log, deps.ClickStatService))
+	r.GET(":id/top", top.New(log, deps.ClickStatService))
+	r.GET(":id/export", export.New(log, deps.ClickStatService))
+	r.GET(":id/live", live.New(log, deps.LiveService))
+	r.GET(":id/history", history.New(log, deps.UrlService))
+	r.POST(":id/history/:historyId/rollback", rollback.New(log, deps.UrlService))
+	r.POST(":id/claim", claim.New(log, deps.UrlService))
 }
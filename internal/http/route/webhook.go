@@ -0,0 +1,24 @@
+package route
+
+import (
+	"log/slog"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/webhook/deliveries"
+	"url-shortener/internal/http/handler/webhook/redeliver"
+	"url-shortener/internal/http/handler/webhook/subscribe"
+	"url-shortener/internal/http/handler/webhook/subscriptions"
+	"url-shortener/internal/http/handler/webhook/unsubscribe"
+	"url-shortener/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Webhook(router gin.IRouter, log *slog.Logger, deps *handler.Dependencies) {
+	r := router.Group("/webhooks", middleware.Auth(deps.JwtService))
+
+	r.POST("", subscribe.New(log, deps.WebhookService))
+	r.GET("", subscriptions.New(log, deps.WebhookService))
+	r.DELETE(":id", unsubscribe.New(log, deps.WebhookService))
+	r.GET(":id/deliveries", deliveries.New(log, deps.WebhookService))
+	r.POST(":id/deliveries/:deliveryId/redeliver", redeliver.New(log, deps.WebhookService))
+}
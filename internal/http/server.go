@@ -3,6 +3,7 @@ package http_server
 import (
 	"log/slog"
 	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/middleware"
 	"url-shortener/internal/http/route"
 
 	"github.com/gin-gonic/gin"
@@ -10,14 +11,27 @@ import (
 
 func NewRouter(log *slog.Logger, deps *handler.Dependencies) *gin.Engine {
 	r := gin.Default()
-
-	v1 := r.Group("/api/v1")
+	// X-Forwarded-For is spoofable, so client ips come from it only behind proxies trusted by the caller
+	r.SetTrustedProxies(nil)
 
 	// middleware
+	if deps.Metrics != nil {
+		r.Use(middleware.Metrics(deps.Metrics))
+	}
+
+	// groups copy the middleware of the engine when they're created, so v1 is created after it
+	v1 := r.Group("/api/v1")
 
 	// routes
 	route.Auth(v1, log, deps)
 	route.Url(r, v1, log, deps)
+	route.Conversion(r, log, deps)
+	route.Transfer(v1, log, deps)
+	route.Stats(v1, log, deps)
+	route.Share(v1, log, deps)
+	route.Webhook(v1, log, deps)
+	route.Anonymous(v1, log, deps)
+	route.Admin(v1, log, deps)
 
 	return r
 }
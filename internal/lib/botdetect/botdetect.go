@@ -0,0 +1,85 @@
+package botdetect
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var ErrNoPatterns = errors.New("no bot patterns")
+
+// Detector matches user agents of bots, link unfurlers, monitors and crawlers against a list of patterns
+type Detector struct {
+	re *regexp.Regexp
+}
+
+// Load reads patterns from the file. A .json file is a crawler-user-agents.json list of objects with "pattern" fields,
+// any other file has a pattern per line, empty lines and lines starting with # are skipped.
+// Patterns are case-insensitive regular expressions
+func Load(path string) (*Detector, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var entries []struct {
+			Pattern string `json:"pattern"`
+		}
+		if err := json.NewDecoder(f).Decode(&entries); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			patterns = append(patterns, e.Pattern)
+		}
+	} else {
+		patterns, err = readLines(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return New(patterns)
+}
+
+func New(patterns []string) (*Detector, error) {
+	if len(patterns) == 0 {
+		return nil, ErrNoPatterns
+	}
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid bot pattern %q: %w", p, err)
+		}
+	}
+
+	re, err := regexp.Compile("(?i)(?:" + strings.Join(patterns, "|") + ")")
+	if err != nil {
+		return nil, err
+	}
+	return &Detector{re}, nil
+}
+
+func (d *Detector) IsBot(userAgent string) bool {
+	return d.re.MatchString(userAgent)
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
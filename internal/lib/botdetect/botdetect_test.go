@@ -0,0 +1,53 @@
+package botdetect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	d, err := Load("../../../config/bot-patterns.txt")
+	require.NoError(t, err)
+
+	bots := []string{
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+		"Twitterbot/1.0",
+		"facebookexternalhit/1.1 Facebook Twitter",
+		"Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"curl/8.0.1",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+	}
+	for _, ua := range bots {
+		assert.True(t, d.IsBot(ua), ua)
+	}
+
+	humans := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+	}
+	for _, ua := range humans {
+		assert.False(t, d.IsBot(ua), ua)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler-user-agents.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"pattern": "Googlebot\\/", "instances": []}, {"pattern": "^Pingdom"}]`), 0o600))
+
+	d, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, d.IsBot("Googlebot/2.1"))
+	assert.True(t, d.IsBot("pingdom.com_bot_version_1.4"))
+	assert.False(t, d.IsBot("Mozilla/5.0"))
+
+	_, err = New(nil)
+	assert.ErrorIs(t, err, ErrNoPatterns)
+	_, err = New([]string{"("})
+	assert.Error(t, err)
+}
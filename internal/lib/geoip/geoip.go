@@ -0,0 +1,35 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Locator resolves countries of IPs from a local MaxMind-format database (GeoLite2-Country, GeoIP2-City and so on)
+type Locator struct {
+	reader *geoip2.Reader
+}
+
+func Open(path string) (*Locator, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Locator{reader}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the ip's country, or an empty string if it's unknown
+func (l *Locator) Country(ip net.IP) (string, error) {
+	country, err := l.reader.Country(ip)
+	if err != nil {
+		return "", err
+	}
+
+	return country.Country.IsoCode, nil
+}
+
+func (l *Locator) Close() error {
+	return l.reader.Close()
+}
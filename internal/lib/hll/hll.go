@@ -0,0 +1,79 @@
+package hll
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// Precision is the number of hash bits used to pick a register. 2^12 registers take 4KB and give ~1.6% standard error
+const Precision = 12
+
+const registers = 1 << Precision
+
+const version = 1
+
+var ErrInvalidSketch = errors.New("invalid hyperloglog sketch")
+
+// Sketch is a HyperLogLog cardinality estimator. Sketches of disjoint periods can be merged
+// to count distinct items over the whole range without storing the items
+type Sketch struct {
+	registers []uint8
+}
+
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registers)}
+}
+
+// Add adds an item by its uniformly distributed 64-bit hash
+func (s *Sketch) Add(hash uint64) {
+	idx := hash >> (64 - Precision)
+	// the sentinel bit limits the rank when the rest of the hash is zero
+	rank := uint8(bits.LeadingZeros64(hash<<Precision|1<<(Precision-1))) + 1
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+// Merge makes the sketch count items of both sketches
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Count estimates the number of distinct items added to the sketch
+func (s *Sketch) Count() int64 {
+	sum, zeros := 0.0, 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(registers)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// linear counting is more accurate for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(math.Round(estimate))
+}
+
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 2+registers)
+	data = append(data, version, Precision)
+	return append(data, s.registers...), nil
+}
+
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) != 2+registers || data[0] != version || data[1] != Precision {
+		return ErrInvalidSketch
+	}
+	s.registers = append(make([]uint8, 0, registers), data[2:]...)
+	return nil
+}
@@ -0,0 +1,49 @@
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hash(i int) uint64 {
+	sum := sha256.Sum256([]byte(strconv.Itoa(i)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func TestSketch_Count(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		s := New()
+		for i := range n {
+			// duplicates don't change the count
+			s.Add(hash(i))
+			s.Add(hash(i))
+		}
+		assert.InEpsilon(t, float64(n)+1, float64(s.Count())+1, 0.05, "n = %d", n)
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	a, b := New(), New()
+	for i := range 6000 {
+		a.Add(hash(i))
+	}
+	for i := 4000; i < 10000; i++ {
+		b.Add(hash(i))
+	}
+
+	data, err := b.MarshalBinary()
+	require.NoError(t, err)
+	restored := New()
+	require.NoError(t, restored.UnmarshalBinary(data))
+	assert.Equal(t, b.Count(), restored.Count())
+
+	a.Merge(restored)
+	assert.InEpsilon(t, 10000, a.Count(), 0.05)
+
+	assert.ErrorIs(t, restored.UnmarshalBinary(data[:10]), ErrInvalidSketch)
+}
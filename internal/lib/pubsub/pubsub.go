@@ -0,0 +1,85 @@
+package pubsub
+
+import "sync"
+
+// Hub delivers messages published to a topic to its current subscribers. Publishing never blocks:
+// a subscriber that doesn't keep up misses messages that don't fit into its buffer
+type Hub[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan T]struct{}
+	closed bool
+}
+
+func New[T any]() *Hub[T] {
+	return &Hub[T]{topics: make(map[string]map[chan T]struct{})}
+}
+
+// Subscribe returns the channel of the topic's messages and the function that unsubscribes and closes the channel
+func (h *Hub[T]) Subscribe(topic string, buffer int) (<-chan T, func()) {
+	ch := make(chan T, buffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[chan T]struct{})
+	}
+	h.topics[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.topics[topic][ch]; !ok {
+				// closed by Close
+				return
+			}
+			delete(h.topics[topic], ch)
+			if len(h.topics[topic]) == 0 {
+				delete(h.topics, topic)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Close closes channels of all the subscribers. Channels of later subscriptions are closed right away
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscribers := range h.topics {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	h.topics = make(map[string]map[chan T]struct{})
+	h.closed = true
+}
+
+// Publish returns the number of subscribers that missed the message
+func (h *Hub[T]) Publish(topic string, msg T) (dropped int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.topics[topic] {
+		select {
+		case ch <- msg:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Subscribers returns the number of subscribers of the topic
+func (h *Hub[T]) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	h := New[int]()
+	a, unsubscribeA := h.Subscribe("topic", 1)
+	b, unsubscribeB := h.Subscribe("topic", 2)
+	other, unsubscribeOther := h.Subscribe("other", 1)
+	defer unsubscribeOther()
+
+	assert.Zero(t, h.Publish("topic", 1))
+	// a's buffer is full
+	assert.Equal(t, 1, h.Publish("topic", 2))
+
+	assert.Equal(t, 1, <-a)
+	assert.Equal(t, 1, <-b)
+	assert.Equal(t, 2, <-b)
+	assert.Empty(t, other)
+
+	unsubscribeA()
+	unsubscribeA()
+	_, ok := <-a
+	assert.False(t, ok)
+	assert.Equal(t, 1, h.Subscribers("topic"))
+
+	unsubscribeB()
+	assert.Zero(t, h.Subscribers("topic"))
+	assert.Zero(t, h.Publish("topic", 3))
+
+	h.Close()
+	_, ok = <-other
+	assert.False(t, ok)
+	closed, unsubscribe := h.Subscribe("topic", 1)
+	_, ok = <-closed
+	assert.False(t, ok)
+	unsubscribe()
+}
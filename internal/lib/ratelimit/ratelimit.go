@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory fixed window limiter keyed by an arbitrary string, e.g. client ip
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]*window
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  per,
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether one more request for the key fits into the current window
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+
+	w.count++
+	return true
+}
+
+// sweep drops expired windows so the map doesn't grow with every client ever seen
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.Allow("a"))
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+	// other keys have their own window
+	assert.True(t, l.Allow("b"))
+
+	now = now.Add(time.Minute)
+	assert.True(t, l.Allow("a"))
+
+	// expired windows are swept
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+	assert.Len(t, l.windows, 1)
+}
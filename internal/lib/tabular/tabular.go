@@ -0,0 +1,100 @@
+package tabular
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats of tables
+const (
+	CSV    = "csv"
+	NDJSON = "ndjson"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	if format == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes rows of the same columns as CSV records or NDJSON objects. Rows are buffered, Flush has to be called at the end
+type Writer struct {
+	columns []string
+	csv     *csv.Writer
+	json    *bufio.Writer
+}
+
+// New writes the CSV header right away
+func New(w io.Writer, format string, columns []string) (*Writer, error) {
+	switch format {
+	case CSV:
+		writer := &Writer{columns: columns, csv: csv.NewWriter(w)}
+		return writer, writer.csv.Write(columns)
+	case NDJSON:
+		return &Writer{columns: columns, json: bufio.NewWriter(w)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Write writes a row of values of the columns. Strings, integers, booleans and times are supported, times are formatted as RFC 3339
+func (w *Writer) Write(values ...any) error {
+	if w.csv != nil {
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = format(v)
+		}
+		return w.csv.Write(record)
+	}
+
+	w.json.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			w.json.WriteByte(',')
+		}
+		key, _ := json.Marshal(w.columns[i])
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		w.json.Write(key)
+		w.json.WriteByte(':')
+		w.json.Write(value)
+	}
+	w.json.WriteByte('}')
+	return w.json.WriteByte('\n')
+}
+
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return w.json.Flush()
+}
+
+func format(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,34 @@
+package tabular
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	at := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSV, "bucket,clicks,referrer,bot\n2025-07-01T12:00:00Z,10,\"a,b\",true\n"},
+		{NDJSON, "{\"bucket\":\"2025-07-01T12:00:00Z\",\"clicks\":10,\"referrer\":\"a,b\",\"bot\":true}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out strings.Builder
+			w, err := New(&out, tt.format, []string{"bucket", "clicks", "referrer", "bot"})
+			require.NoError(t, err)
+			require.NoError(t, w.Write(at, int64(10), "a,b", true))
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	_, err := New(&strings.Builder{}, "xml", nil)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,51 @@
+package timebucket
+
+import "time"
+
+// Granularities of buckets. They match date_trunc fields of Postgres, weeks start on Monday
+const (
+	Hour  = "hour"
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// Truncate returns the start of the bucket containing t in t's location
+func Truncate(t time.Time, granularity string) time.Time {
+	y, m, d := t.Date()
+	switch granularity {
+	case Hour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case Week:
+		// Monday is the first day of the week
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Next returns the start of the bucket after the one starting at start
+func Next(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case Hour:
+		return start.Add(time.Hour)
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Count returns the number of buckets between from and to, at most limit+1
+func Count(from, to time.Time, granularity string, limit int) int {
+	n := 0
+	for b := Truncate(from, granularity); b.Before(to) && n <= limit; b = Next(b, granularity) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,39 @@
+package timebucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	// Thursday
+	ts := time.Date(2024, 2, 29, 13, 45, 10, 0, berlin)
+
+	assert.Equal(t, time.Date(2024, 2, 29, 13, 0, 0, 0, berlin), Truncate(ts, Hour))
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, berlin), Truncate(ts, Day))
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, berlin), Truncate(ts, Week))
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, berlin), Truncate(ts, Month))
+	// Sunday belongs to the week started on Monday before
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, berlin), Truncate(time.Date(2024, 3, 3, 23, 0, 0, 0, berlin), Week))
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	// the day of switching to summer time is 23 hours long
+	start := time.Date(2024, 3, 31, 0, 0, 0, 0, berlin)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin), Next(start, Day))
+	assert.Equal(t, 23*time.Hour, Next(start, Day).Sub(start))
+
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Month))
+	assert.Equal(t, 3, Count(start, Next(Next(Next(start, Hour), Hour), Hour), Hour, 10))
+	assert.Equal(t, 6, Count(start, start.AddDate(1, 0, 0), Day, 5))
+}
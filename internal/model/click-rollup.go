@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// ClickRollup counts clicks of a url during a UTC day with the same dimensions.
+// Raw clicks are rolled up before they're cleaned up. Empty dimensions are stored as empty strings to be part of the key
+type ClickRollup struct {
+	UrlID        string    `gorm:"primaryKey;type:varchar(16)"`
+	Day          time.Time `gorm:"primaryKey;type:date"`
+	ReferrerHost string    `gorm:"primaryKey;type:varchar(255);not null;default:''"`
+	Browser      string    `gorm:"primaryKey;type:varchar(64);not null;default:''"`
+	OS           string    `gorm:"primaryKey;type:varchar(64);not null;default:''"`
+	Device       string    `gorm:"primaryKey;type:varchar(16);not null;default:''"`
+	Language     string    `gorm:"primaryKey;type:varchar(16);not null;default:''"`
+	Country      string    `gorm:"primaryKey;type:varchar(2);not null;default:''"`
+	Bot          bool      `gorm:"primaryKey;not null;default:false"`
+	Clicks       int64     `gorm:"type:bigint;not null"`
+	// Duplicates are rolled up duplicate clicks, they aren't included in Clicks
+	Duplicates int64 `gorm:"type:bigint;not null;default:0"`
+}
@@ -2,7 +2,28 @@ package model
 
 import "time"
 
+// ClickStat is a single click. Visitor IPs are only used to resolve the country and are never stored
 type ClickStat struct {
-	UrlID     string    `gorm:"type:varchar(16);not null;index:idx_url_created"`
-	CreatedAt time.Time `gorm:"type:timestamp;not null;index:idx_url_created"`
+	UrlID        string    `gorm:"type:varchar(16);not null;index:idx_url_created"`
+	CreatedAt    time.Time `gorm:"type:timestamp;not null;index:idx_url_created"`
+	ReferrerHost string    `gorm:"type:varchar(255);default:null"`
+	Browser      string    `gorm:"type:varchar(64);default:null"`
+	OS           string    `gorm:"type:varchar(64);default:null"`
+	Device       string    `gorm:"type:varchar(16);default:null"`
+	Language     string    `gorm:"type:varchar(16);default:null"`
+	Country      string    `gorm:"type:char(2);default:null"`
+	// Bot clicks are made by crawlers, link unfurlers, monitors, prefetching or HEAD requests. They're excluded from stats by default
+	Bot bool `gorm:"not null;default:false"`
+	// Duplicate clicks repeat a click of the same visitor within the de-duplication window. They're counted only as raw clicks
+	Duplicate bool `gorm:"not null;default:false"`
+	// ClickID is the id of the click token of a counted click, its conversions are counted. Other clicks have none
+	ClickID string `gorm:"type:varchar(16);default:null"`
 }
+
+// Device classes of a click
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
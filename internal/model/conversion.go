@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Conversion is a signup or another goal of a landing page attributed to the click that brought the visitor.
+// A click converts at most once, so repeated beacons of the same visitor aren't counted again.
+// Only conversions of counted clicks are counted, bots, duplicates and clicks over the quota don't convert
+type Conversion struct {
+	UrlID   string `gorm:"primaryKey;type:varchar(16)"`
+	ClickID string `gorm:"primaryKey;type:varchar(16)"`
+	// ClickedAt is the time of the converted click in UTC. Conversions are counted in buckets of their clicks
+	ClickedAt time.Time `gorm:"type:timestamp;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp;not null"`
+	// Counted is set when the click is saved as counted. The landing page can report the conversion before
+	// the queued click is saved, so the click sets it then
+	Counted bool `gorm:"not null;default:false"`
+}
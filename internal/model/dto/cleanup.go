@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// Statuses of a cleanup run
+const (
+	CleanupRunning   = "running"
+	CleanupSucceeded = "succeeded"
+	CleanupFailed    = "failed"
+)
+
+type CleanupRun struct {
+	Status          string     `json:"status"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	RolledUpClicks  int64      `json:"rolledUpClicks"`
+	DeletedSketches int64      `json:"deletedSketches"`
+	// CreatedPartitions and DroppedPartitions are monthly partitions of raw clicks
+	CreatedPartitions int    `json:"createdPartitions"`
+	DroppedPartitions int    `json:"droppedPartitions"`
+	Error             string `json:"error,omitempty"`
+}
+
+type CleanupStatus struct {
+	Schedule string `json:"schedule"`
+	// NextRunAt is empty until the job is scheduled
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	// LastRun is empty until the job runs once after the start
+	LastRun *CleanupRun `json:"lastRun,omitempty"`
+	// SucceededRuns and FailedRuns are counted since the start
+	SucceededRuns uint64 `json:"succeededRuns"`
+	FailedRuns    uint64 `json:"failedRuns"`
+}
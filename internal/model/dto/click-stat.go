@@ -0,0 +1,143 @@
+package dto
+
+import (
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+)
+
+// Visit is the raw request data of a click. It's parsed into click dimensions and isn't stored as is
+type Visit struct {
+	IP             string
+	UserAgent      string
+	Referrer       string
+	AcceptLanguage string
+	// Method is the http method of the redirect request
+	Method string
+	// Purpose is the value of Purpose-like headers of prefetch requests
+	Purpose string
+	// ClickID is the id of the click token passed to the destination, see ClickStatService.ClickToken
+	ClickID string
+}
+
+// StatsQuery selects the range of the stats series. From and To are RFC 3339 times or dates in TZ, To is inclusive for dates
+type StatsQuery struct {
+	From        string
+	To          string
+	Granularity string `validate:"omitempty,oneof=hour day week month"`
+	TZ          string
+	IncludeBots bool
+}
+
+// OverviewQuery selects the range of the account overview and the number of top urls
+type OverviewQuery struct {
+	StatsQuery
+	Top int `validate:"min=1,max=100"`
+}
+
+// TopValuesQuery selects the range of top values of a url and their number
+type TopValuesQuery struct {
+	StatsQuery
+	Top int `validate:"min=1,max=100"`
+}
+
+// CompareQuery selects urls of the user to compare and the range of their series
+type CompareQuery struct {
+	StatsQuery
+	UrlIDs []string `validate:"required,min=1,max=10,dive,required"`
+}
+
+// StatsComparison has series of several urls with the same buckets. Shares are percents of the total clicks of the urls
+// and conversion rates are percents of clicks of the url that converted, they're empty when there are no clicks
+type StatsComparison struct {
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Granularity string        `json:"granularity"`
+	TotalClicks int64         `json:"totalClicks"`
+	Urls        []ComparedUrl `json:"urls"`
+}
+
+type ComparedUrl struct {
+	Alias       string   `json:"alias"`
+	TotalClicks int64    `json:"totalClicks"`
+	Share       *float64 `json:"share"`
+	Conversions int64    `json:"conversions"`
+	// ConversionRate can exceed 100, conversions aren't filtered out like clicks of bots and duplicates
+	ConversionRate *float64          `json:"conversionRate"`
+	Series         []repo.DailyCount `json:"series"`
+}
+
+// StatsOverview sums clicks of all the user's urls. The previous period has the same length and ends at From.
+// Changes are percents, they're empty when the previous period has nothing to compare with
+type StatsOverview struct {
+	From                time.Time         `json:"from"`
+	To                  time.Time         `json:"to"`
+	TotalClicks         int64             `json:"totalClicks"`
+	PreviousTotalClicks int64             `json:"previousTotalClicks"`
+	ClicksChange        *float64          `json:"clicksChange"`
+	NewUrls             int64             `json:"newUrls"`
+	PreviousNewUrls     int64             `json:"previousNewUrls"`
+	NewUrlsChange       *float64          `json:"newUrlsChange"`
+	Series              []repo.DailyCount `json:"series"`
+	TopUrls             []repo.UrlClicks  `json:"topUrls"`
+}
+
+// Heatmap counts clicks of a url by weekday and hour in the time zone of the query. Rows are weekdays from Monday,
+// columns are hours. Clicks older than the retention of the plan are only kept by day, so they aren't counted
+type Heatmap struct {
+	From   time.Time    `json:"from"`
+	To     time.Time    `json:"to"`
+	TZ     string       `json:"tz"`
+	Total  int64        `json:"total"`
+	Clicks [7][24]int64 `json:"clicks"`
+}
+
+// TopValues are the most popular referrers and countries of a url in the range
+type TopValues struct {
+	From      time.Time             `json:"from"`
+	To        time.Time             `json:"to"`
+	Referrers []repo.DimensionCount `json:"referrers"`
+	Countries []repo.DimensionCount `json:"countries"`
+}
+
+// LiveClick is a click streamed to the url owner as it's recorded
+type LiveClick struct {
+	Alias     string    `json:"alias"`
+	CreatedAt time.Time `json:"createdAt"`
+	Referrer  string    `json:"referrer,omitempty"`
+	Browser   string    `json:"browser,omitempty"`
+	OS        string    `json:"os,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	Bot       bool      `json:"bot,omitempty"`
+}
+
+func ToLiveClick(click *model.ClickStat) LiveClick {
+	return LiveClick{
+		Alias:     click.UrlID,
+		CreatedAt: click.CreatedAt,
+		Referrer:  click.ReferrerHost,
+		Browser:   click.Browser,
+		OS:        click.OS,
+		Device:    click.Device,
+		Language:  click.Language,
+		Country:   click.Country,
+		Bot:       click.Bot,
+	}
+}
+
+// ClickEvent is a recorded click written to click sinks, e.g. to files of data pipelines
+type ClickEvent struct {
+	LiveClick
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+func ToClickEvent(click *model.ClickStat) ClickEvent {
+	return ClickEvent{LiveClick: ToLiveClick(click), Duplicate: click.Duplicate}
+}
+
+// ConversionResult reports whether the conversion is attributed to a click of the url
+type ConversionResult struct {
+	Attributed bool `json:"attributed"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+type PlanUsage struct {
+	Plan          string     `json:"plan"`
+	Links         UsageLimit `json:"links"`
+	Aliases       UsageLimit `json:"aliases"`
+	MonthlyClicks UsageLimit `json:"monthlyClicks"`
+}
+
+// UsageLimit has Limit 0 when there's no limit
+type UsageLimit struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "url-shortener/internal/database/repo"
+
+// HitsDrift reports urls whose total hits differ from their recorded clicks of humans
+type HitsDrift struct {
+	DriftedUrls int64 `json:"driftedUrls"`
+	// Urls are the first drifted urls by alias
+	Urls []repo.HitsDrift `json:"urls"`
+}
+
+// HitsReconciliation reports urls whose total hits were set to their baseline and recorded clicks
+type HitsReconciliation struct {
+	RepairedUrls int `json:"repairedUrls"`
+	// Urls are the first repaired urls by alias with hits before the repair
+	Urls []repo.HitsDrift `json:"urls"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+	"url-shortener/internal/model"
+)
+
+type CreateShare struct {
+	HideBreakdowns bool `json:"hideBreakdowns"`
+}
+
+type PublicShare struct {
+	ID             string    `json:"id"`
+	UrlID          string    `json:"urlId"`
+	HideBreakdowns bool      `json:"hideBreakdowns"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// CreatedShare is returned once on creation. The token isn't stored and can't be shown again
+type CreatedShare struct {
+	PublicShare
+	Token string `json:"token"`
+}
+
+func ToPublicShare(s *model.StatsShare) *PublicShare {
+	return &PublicShare{ID: s.ID, UrlID: s.UrlID, HideBreakdowns: s.HideBreakdowns, CreatedAt: s.CreatedAt}
+}
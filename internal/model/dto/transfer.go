@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+	"url-shortener/internal/model"
+)
+
+type CreateTransfer struct {
+	Email  string   `validate:"required,email"`
+	UrlIDs []string `validate:"required,min=1,max=100,dive,required"`
+}
+
+type PublicTransfer struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Status    string    `json:"status"`
+	UrlIDs    []string  `json:"urlIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func ToPublicTransfer(t *model.UrlTransfer) *PublicTransfer {
+	urlIDs := make([]string, len(t.Items))
+	for i := range urlIDs {
+		urlIDs[i] = t.Items[i].UrlID
+	}
+	return &PublicTransfer{
+		ID:        t.ID,
+		From:      t.FromUser.Email,
+		To:        t.ToUser.Email,
+		Status:    t.Status,
+		UrlIDs:    urlIDs,
+		CreatedAt: t.CreatedAt,
+	}
+}
@@ -1,22 +1,74 @@
 package dto
 
-import "url-shortener/internal/model"
+import (
+	"time"
+	"url-shortener/internal/model"
+)
 
 type CreateUrl struct {
 	Alias string `validate:"omitempty,ascii,max=16"`
 	Link  string `validate:"required,url"`
+	// DedupWindow overrides the global de-duplication window of clicks in seconds, 0 disables de-duplication
+	DedupWindow *int `validate:"omitempty,min=0,max=86400"`
+}
+
+type UpdateUrl struct {
+	Link string `validate:"required,url"`
+}
+
+// UrlDedup sets the de-duplication window of the url's clicks in seconds. Empty window resets it to the global one
+type UrlDedup struct {
+	Window *int `json:"window" validate:"omitempty,min=0,max=86400"`
 }
 
 type PublicUrl struct {
 	Alias     string `json:"alias"`
 	Link      string `json:"link"`
 	TotalHits int64  `json:"totalHits"`
+	// DedupWindow is empty when the global window is used
+	DedupWindow *int       `json:"dedupWindow,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// AnonymousUrl is returned once on anonymous creation. The claim token isn't stored and can't be shown again
+type AnonymousUrl struct {
+	PublicUrl
+	ClaimToken string `json:"claimToken"`
+}
+
+type ClaimUrl struct {
+	Token string `validate:"required"`
 }
 
 func (dto *CreateUrl) Model(userID string) *model.Url {
-	return &model.Url{ID: dto.Alias, Link: dto.Link, UserID: userID}
+	return &model.Url{ID: dto.Alias, Link: dto.Link, UserID: userID, CustomAlias: dto.Alias != "", DedupWindow: dto.DedupWindow}
 }
 
 func ToPublicUrl(url *model.Url) *PublicUrl {
-	return &PublicUrl{Alias: url.ID, Link: url.Link, TotalHits: url.TotalHits}
+	return &PublicUrl{Alias: url.ID, Link: url.Link, TotalHits: url.TotalHits, DedupWindow: url.DedupWindow, ExpiresAt: url.ExpiresAt}
+}
+
+type PublicUrlHistory struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"oldValue"`
+	NewValue  string    `json:"newValue"`
+	UserID    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Clicks    int64     `json:"clicks"`
+}
+
+// ToPublicUrlHistory takes the number of clicks made while the entry's value was live
+func ToPublicUrlHistory(h *model.UrlHistory, clicks int64) *PublicUrlHistory {
+	return &PublicUrlHistory{
+		ID:        h.ID,
+		Action:    h.Action,
+		Field:     h.Field,
+		OldValue:  h.OldValue,
+		NewValue:  h.NewValue,
+		UserID:    h.UserID,
+		CreatedAt: h.CreatedAt,
+		Clicks:    clicks,
+	}
 }
@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+	"url-shortener/internal/model"
+)
+
+type CreateWebhook struct {
+	Endpoint string   `validate:"required,http_url,max=2048"`
+	Events   []string `validate:"required,min=1,dive,oneof=url.created url.updated url.deleted click.recorded click.milestone"`
+}
+
+type PublicWebhook struct {
+	ID        string    `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreatedWebhook is returned once on creation. The secret verifies the X-Webhook-Signature header of deliveries
+type CreatedWebhook struct {
+	PublicWebhook
+	Secret string `json:"secret"`
+}
+
+type PublicWebhookDelivery struct {
+	ID       int64  `json:"id"`
+	Event    string `json:"event"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// NextAttemptAt is set only for pending deliveries
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// WebhookEvent is the body of a delivery
+type WebhookEvent struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// MilestoneReached is the data of click.milestone events
+type MilestoneReached struct {
+	Alias     string `json:"alias"`
+	Link      string `json:"link"`
+	Milestone int64  `json:"milestone"`
+	TotalHits int64  `json:"totalHits"`
+}
+
+func ToPublicWebhook(w *model.Webhook) *PublicWebhook {
+	return &PublicWebhook{ID: w.ID, Endpoint: w.Endpoint, Events: w.EventNames(), CreatedAt: w.CreatedAt}
+}
+
+func ToPublicWebhookDelivery(d *model.WebhookDelivery) *PublicWebhookDelivery {
+	delivery := &PublicWebhookDelivery{
+		ID:             d.ID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == model.DeliveryPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	return delivery
+}
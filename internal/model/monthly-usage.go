@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// MonthlyUsage counts clicks on all user's urls during a month
+type MonthlyUsage struct {
+	UserID string    `gorm:"primaryKey;type:varchar(16)"`
+	Month  time.Time `gorm:"primaryKey;type:date"`
+	Clicks int64     `gorm:"type:bigint;not null;default:0"`
+}
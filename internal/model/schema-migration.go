@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// SchemaMigration marks a migration that AutoMigrate can't do as applied
+type SchemaMigration struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	AppliedAt time.Time `gorm:"type:timestamp;not null;autoCreateTime"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// StatsShare lets anyone with its token see stats of the url without login.
+// Only the hash of the token is stored, revoking deletes the share
+type StatsShare struct {
+	ID        string `gorm:"primaryKey;type:varchar(16)"`
+	UrlID     string `gorm:"type:varchar(16);not null;index"`
+	TokenHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	// HideBreakdowns limits the share to the series of clicks
+	HideBreakdowns bool      `gorm:"not null;default:false"`
+	CreatedAt      time.Time `gorm:"type:timestamp;not null"`
+	Url            Url       `gorm:"constraint:OnDelete:CASCADE;"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	HistoryCreate   = "create"
+	HistoryUpdate   = "update"
+	HistoryRollback = "rollback"
+)
+
+// UrlHistory is an append-only record of a change to one of url's fields
+type UrlHistory struct {
+	ID        int64     `gorm:"primaryKey"`
+	UrlID     string    `gorm:"type:varchar(16);not null;index:idx_url_history"`
+	UserID    string    `gorm:"type:varchar(16);not null"`
+	Action    string    `gorm:"type:varchar(16);not null"`
+	Field     string    `gorm:"type:varchar(32);not null"`
+	OldValue  string    `gorm:"type:text;not null"`
+	NewValue  string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp;not null;index:idx_url_history"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+// UrlTransfer is a request to move ownership of urls from one user to another.
+// Ownership changes only after the recipient accepts it.
+type UrlTransfer struct {
+	ID         string            `gorm:"primaryKey;type:varchar(16)"`
+	FromUserID string            `gorm:"type:varchar(16);not null;index"`
+	ToUserID   string            `gorm:"type:varchar(16);not null;index"`
+	Status     string            `gorm:"type:varchar(16);not null;default:pending"`
+	CreatedAt  time.Time         `gorm:"type:timestamp;not null"`
+	FromUser   User              `gorm:"foreignKey:FromUserID;constraint:OnDelete:CASCADE;"`
+	ToUser     User              `gorm:"foreignKey:ToUserID;constraint:OnDelete:CASCADE;"`
+	Items      []UrlTransferItem `gorm:"foreignKey:TransferID;constraint:OnDelete:CASCADE;"`
+}
+
+type UrlTransferItem struct {
+	TransferID string `gorm:"primaryKey;type:varchar(16)"`
+	UrlID      string `gorm:"primaryKey;type:varchar(16)"`
+	Url        Url    `gorm:"constraint:OnDelete:CASCADE;"`
+}
@@ -1,9 +1,29 @@
 package model
 
+import "time"
+
 type Url struct {
-	ID         string      `gorm:"primaryKey;type:varchar(16)"`
-	Link       string      `gorm:"type:varchar(255);not null"`
-	TotalHits  int64       `gorm:"type:bigint;not null;default:0"`
-	UserID     string      `gorm:"type:varchar(16);not null;index"`
-	ClickStats []ClickStat `gorm:"constraint:OnDelete:CASCADE;"`
+	ID        string `gorm:"primaryKey;type:varchar(16)"`
+	Link      string `gorm:"type:text;not null"`
+	TotalHits int64  `gorm:"type:bigint;not null;default:0"`
+	// HitsBaseline is the part of TotalHits counted before their clicks were kept, e.g. bots and clicks cleaned up without rollups
+	HitsBaseline int64 `gorm:"type:bigint;not null;default:0"`
+	// LastMilestone is the highest click milestone reported to webhooks
+	LastMilestone int64 `gorm:"type:bigint;not null;default:0"`
+	// CustomAlias is set when the id was chosen by the user
+	CustomAlias bool `gorm:"not null;default:false"`
+	// DedupWindow overrides the global de-duplication window of clicks in seconds, 0 disables de-duplication
+	DedupWindow *int `gorm:"type:integer;default:null"`
+	// UserID is empty for anonymous urls
+	UserID string `gorm:"type:varchar(16);default:null;index"`
+	// ClaimTokenHash and ExpiresAt are set only for anonymous urls
+	ClaimTokenHash string     `gorm:"type:varchar(64);default:null"`
+	ExpiresAt      *time.Time `gorm:"type:timestamp"`
+	// CreatedAt is in UTC like click times
+	CreatedAt   time.Time       `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	ClickStats  []ClickStat     `gorm:"constraint:OnDelete:CASCADE;"`
+	History     []UrlHistory    `gorm:"constraint:OnDelete:CASCADE;"`
+	Visitors    []VisitorSketch `gorm:"constraint:OnDelete:CASCADE;"`
+	Rollups     []ClickRollup   `gorm:"constraint:OnDelete:CASCADE;"`
+	Conversions []Conversion    `gorm:"constraint:OnDelete:CASCADE;"`
 }
@@ -4,5 +4,8 @@ type User struct {
 	ID       string `gorm:"primaryKey;type:varchar(16)"`
 	Email    string `gorm:"type:varchar(64);uniqueIndex;not null"`
 	Password string `gorm:"type:varchar(60);not null"`
-	Urls     []Url  `gorm:"constraint:OnDelete:CASCADE;"`
+	// Plan is empty for users on the default plan
+	Plan   string         `gorm:"type:varchar(32);default:null"`
+	Urls   []Url          `gorm:"constraint:OnDelete:CASCADE;"`
+	Usages []MonthlyUsage `gorm:"constraint:OnDelete:CASCADE;"`
 }
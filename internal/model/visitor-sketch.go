@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// VisitorSketch is a HyperLogLog sketch of unique visitors of a url during a day.
+// Visitor identifiers themselves aren't stored
+type VisitorSketch struct {
+	UrlID  string    `gorm:"primaryKey;type:varchar(16)"`
+	Day    time.Time `gorm:"primaryKey;type:date"`
+	Sketch []byte    `gorm:"type:bytea;not null"`
+}
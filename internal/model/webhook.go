@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook events
+const (
+	EventUrlCreated     = "url.created"
+	EventUrlUpdated     = "url.updated"
+	EventUrlDeleted     = "url.deleted"
+	EventClickRecorded  = "click.recorded"
+	EventClickMilestone = "click.milestone"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of the user that receives signed events of the user's urls
+type Webhook struct {
+	ID       string `gorm:"primaryKey;type:varchar(16)"`
+	UserID   string `gorm:"type:varchar(16);not null;index"`
+	Endpoint string `gorm:"type:text;not null"`
+	// Secret keys HMAC-SHA256 signatures of payloads
+	Secret string `gorm:"type:varchar(64);not null"`
+	// Events are comma-separated names of subscribed events
+	Events    string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp;not null"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+func (w *Webhook) EventNames() []string {
+	return strings.Split(w.Events, ",")
+}
+
+// WebhookDelivery is both a queued event and its delivery log. Pending deliveries are retried
+// at NextAttemptAt until they succeed or run out of attempts
+type WebhookDelivery struct {
+	ID        int64  `gorm:"primaryKey"`
+	WebhookID string `gorm:"type:varchar(16);not null;index"`
+	Event     string `gorm:"type:varchar(32);not null"`
+	// Payload is the JSON body, it's signed as is on every attempt
+	Payload       string     `gorm:"type:text;not null"`
+	Status        string     `gorm:"type:varchar(16);not null;default:pending;index:idx_webhook_deliveries_due"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `gorm:"type:timestamp;not null;index:idx_webhook_deliveries_due"`
+	LastAttemptAt *time.Time `gorm:"type:timestamp"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 when there was no response
+	ResponseStatus int       `gorm:"not null;default:0"`
+	Error          string    `gorm:"type:text;default:null"`
+	CreatedAt      time.Time `gorm:"type:timestamp;not null"`
+	Webhook        Webhook   `gorm:"constraint:OnDelete:CASCADE;"`
+}
@@ -0,0 +1,243 @@
+package clickstat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+)
+
+var (
+	ErrQueueFull      = errors.New("click queue is full")
+	ErrRecorderClosed = errors.New("click recorder is closed")
+)
+
+type visitorKey struct {
+	urlID string
+	day   time.Time
+}
+
+type queuedVisit struct {
+	urlID     string
+	visit     *dto.Visit
+	createdAt time.Time
+}
+
+// RecorderMetrics are counters of the batch recorder since it was started
+type RecorderMetrics struct {
+	// Queued is the current number of clicks waiting in the queue
+	Queued   int
+	Capacity int
+	Enqueued uint64
+	// Dropped clicks didn't fit into the queue
+	Dropped  uint64
+	Recorded uint64
+	// Failed clicks were rejected by the quota or couldn't be saved by the primary sink
+	Failed uint64
+	// SinkFailed clicks were recorded, but other sinks than the primary one failed to write them
+	SinkFailed uint64
+	Batches    uint64
+}
+
+// BatchRecorder records clicks off the redirect path. Visits are put into a bounded queue
+// and saved in batches by background workers. Clicks are dropped when the queue is full
+type BatchRecorder struct {
+	service *ClickStatService
+	cfg     config.ClickQueue
+	log     *slog.Logger
+
+	queue  chan queuedVisit
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	recorded atomic.Uint64
+	failed   atomic.Uint64
+	batches  atomic.Uint64
+}
+
+// NewBatchRecorder starts the workers. Close has to be called on shutdown to save queued clicks
+func NewBatchRecorder(service *ClickStatService, cfg config.ClickQueue, log *slog.Logger) *BatchRecorder {
+	r := &BatchRecorder{
+		service: service,
+		cfg:     cfg,
+		log:     log,
+		queue:   make(chan queuedVisit, cfg.Size),
+	}
+
+	workers := max(cfg.Workers, 1)
+	r.wg.Add(workers)
+	for range workers {
+		go r.work()
+	}
+
+	return r
+}
+
+// Record puts the click into the queue without waiting for it to be saved
+func (r *BatchRecorder) Record(urlID string, visit *dto.Visit) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return ErrRecorderClosed
+	}
+
+	select {
+	case r.queue <- queuedVisit{urlID, visit, time.Now()}:
+		r.enqueued.Add(1)
+		return nil
+	default:
+		r.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting clicks and waits until the workers save the queued ones or the context is done
+func (r *BatchRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *BatchRecorder) Metrics() RecorderMetrics {
+	return RecorderMetrics{
+		Queued:     len(r.queue),
+		Capacity:   cap(r.queue),
+		Enqueued:   r.enqueued.Load(),
+		Dropped:    r.dropped.Load(),
+		Recorded:   r.recorded.Load(),
+		Failed:     r.failed.Load(),
+		SinkFailed: r.service.SinkFailures(),
+		Batches:    r.batches.Load(),
+	}
+}
+
+func (r *BatchRecorder) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]queuedVisit, 0, r.cfg.BatchSize)
+	var reportedDrops uint64
+	for {
+		select {
+		case v, ok := <-r.queue:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) >= r.cfg.BatchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+
+			if dropped := r.dropped.Load(); dropped > reportedDrops {
+				r.log.Warn("click queue is full, clicks are dropped",
+					slog.String("op", "service.clickstat.BatchRecorder"),
+					slog.Uint64("dropped", dropped-reportedDrops),
+					slog.Int("capacity", cap(r.queue)),
+				)
+				reportedDrops = dropped
+			}
+		}
+	}
+}
+
+func (r *BatchRecorder) flush(batch []queuedVisit) {
+	if len(batch) == 0 {
+		return
+	}
+	log := r.log.With(slog.String("op", "service.clickstat.BatchRecorder.flush"))
+
+	urlIDs := make([]string, 0, len(batch))
+	for _, v := range batch {
+		urlIDs = append(urlIDs, v.urlID)
+	}
+	windows := r.service.dedupWindows(urlIDs...)
+	over, err := r.service.overQuota(urlIDs...)
+	if err != nil {
+		// clicks can't be checked against the quota, so none of them is recorded
+		log.Error("failed to check click quota", sl.Err(err), slog.Int("clicks", len(batch)))
+		r.failed.Add(uint64(len(batch)))
+		return
+	}
+
+	clicks := make([]*model.ClickStat, 0, len(batch))
+	visitors := make(map[visitorKey]*hll.Sketch)
+	for _, v := range batch {
+		click, visitor, err := r.service.click(v.urlID, v.visit, v.createdAt, r.service.dedupWindow(windows, v.urlID), over[v.urlID])
+		if err != nil {
+			r.failed.Add(1)
+			continue
+		}
+		clicks = append(clicks, click)
+
+		if visitor != 0 {
+			key := visitorKey{v.urlID, day(click.CreatedAt)}
+			if visitors[key] == nil {
+				visitors[key] = hll.New()
+			}
+			visitors[key].Add(visitor)
+		}
+	}
+	if len(clicks) == 0 {
+		return
+	}
+
+	r.batches.Add(1)
+	saved, err := r.service.write(clicks)
+	r.service.trackClicks(saved)
+	r.service.countConversions(saved)
+	if err != nil {
+		log.Error("failed to record clicks", sl.Err(err), slog.Int("clicks", len(clicks)), slog.Int("saved", len(saved)))
+	}
+	r.recorded.Add(uint64(len(saved)))
+	r.failed.Add(uint64(len(clicks) - len(saved)))
+	if len(saved) > 0 {
+		r.addVisitors(visitors)
+		r.service.publish(saved...)
+	}
+}
+
+// addVisitors merges one sketch per url and day of the batch into the stored ones
+func (r *BatchRecorder) addVisitors(visitors map[visitorKey]*hll.Sketch) {
+	if !r.service.stores() {
+		return
+	}
+	for key, sketch := range visitors {
+		if err := r.service.repo.AddVisitors(key.urlID, key.day, sketch); err != nil {
+			r.log.Error("failed to record visitors", slog.String("op", "service.clickstat.BatchRecorder.addVisitors"), sl.Err(err))
+		}
+	}
+}
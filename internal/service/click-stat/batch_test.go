@@ -0,0 +1,155 @@
+package clickstat_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testQueue = config.ClickQueue{Size: 10, BatchSize: 3, FlushInterval: time.Hour, Workers: 1}
+
+func TestBatchRecorder_DrainsOnClose(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("CreateBatch", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 3 })).Return(nil).Once()
+	repo.On("CreateBatch", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 2 })).Return(nil).Once()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default()), testQueue, slog.Default())
+	for range 5 {
+		require.NoError(t, r.Record("1234", nil))
+	}
+
+	require.NoError(t, r.Close(context.Background()))
+	assert.ErrorIs(t, r.Record("1234", nil), clickstat.ErrRecorderClosed)
+
+	metrics := r.Metrics()
+	assert.Equal(t, uint64(5), metrics.Enqueued)
+	assert.Equal(t, uint64(5), metrics.Recorded)
+	assert.Equal(t, uint64(2), metrics.Batches)
+	assert.Zero(t, metrics.Queued)
+}
+
+func TestBatchRecorder_DropsWhenFull(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	// batches of one click are saved by Create
+	repo.On("Create", mock.Anything).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(nil).Once()
+	repo.On("Create", mock.Anything).Return(nil).Once()
+
+	cfg := config.ClickQueue{Size: 1, BatchSize: 1, FlushInterval: time.Hour, Workers: 1}
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default()), cfg, slog.Default())
+
+	// the worker is busy with the first click, the second one fills the queue
+	require.NoError(t, r.Record("1234", nil))
+	<-started
+	require.NoError(t, r.Record("1234", nil))
+	assert.ErrorIs(t, r.Record("1234", nil), clickstat.ErrQueueFull)
+
+	close(release)
+	require.NoError(t, r.Close(context.Background()))
+
+	metrics := r.Metrics()
+	assert.Equal(t, uint64(2), metrics.Recorded)
+	assert.Equal(t, uint64(1), metrics.Dropped)
+}
+
+func TestBatchRecorder_Failures(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	quota := mocks.NewClickQuota(t)
+	quota.On("OverClickQuota", []string{"overquota", "deleted", "1234"}).Return(map[string]bool{"overquota": true}, nil).Once()
+	// only the saved click is counted
+	quota.On("TrackClicks", map[string]int64{"1234": 1}).Return(nil).Once()
+	// the batch fails because of the deleted url, other clicks are saved one by one
+	repo.On("CreateBatch", mock.Anything).Return(&pgconn.PgError{Code: "23503"}).Once()
+	repo.On("Create", mock.MatchedBy(func(c *model.ClickStat) bool { return c.UrlID == "deleted" })).Return(&pgconn.PgError{Code: "23503"}).Once()
+	repo.On("Create", mock.MatchedBy(func(c *model.ClickStat) bool { return c.UrlID == "1234" })).Return(nil).Once()
+
+	s := clickstat.New(repo, slog.Default(), clickstat.WithClickQuota(quota))
+	r := clickstat.NewBatchRecorder(s, testQueue, slog.Default())
+	for _, urlID := range []string{"overquota", "deleted", "1234"} {
+		require.NoError(t, r.Record(urlID, nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+
+	metrics := r.Metrics()
+	assert.Equal(t, uint64(1), metrics.Recorded)
+	assert.Equal(t, uint64(2), metrics.Failed)
+}
+
+func TestBatchRecorder_Quota(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("CreateBatch", mock.Anything).Return(nil).Once()
+	quota := mocks.NewClickQuota(t)
+	// the batch is checked and counted once
+	quota.On("OverClickQuota", []string{"1234", "5678", "1234"}).Return(map[string]bool{}, nil).Once()
+	quota.On("TrackClicks", map[string]int64{"1234": 2, "5678": 1}).Return(nil).Once()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default(), clickstat.WithClickQuota(quota)), testQueue, slog.Default())
+	for _, urlID := range []string{"1234", "5678", "1234"} {
+		require.NoError(t, r.Record(urlID, nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+	assert.Equal(t, uint64(3), r.Metrics().Recorded)
+
+	// none of the clicks is recorded when the quota can't be checked
+	quota.On("OverClickQuota", mock.Anything).Return(nil, service.ErrInternalError).Once()
+	r = clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default(), clickstat.WithClickQuota(quota)), testQueue, slog.Default())
+	require.NoError(t, r.Record("1234", nil))
+	require.NoError(t, r.Close(context.Background()))
+	assert.Equal(t, uint64(1), r.Metrics().Failed)
+}
+
+func TestBatchRecorder_CloseTimeout(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	release := make(chan struct{})
+	defer close(release)
+	repo.On("Create", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(errors.New("unexpected")).Maybe()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default()), testQueue, slog.Default())
+	require.NoError(t, r.Record("1234", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, r.Close(ctx), context.DeadlineExceeded)
+}
+
+func TestBatchRecorder_Visitors(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("CreateBatch", mock.Anything).Return(nil).Once()
+	repo.On("AddVisitors", "1234", mock.Anything, mock.MatchedBy(func(s *hll.Sketch) bool { return s.Count() == 2 })).Return(nil).Once()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default(), clickstat.WithVisitorSecret("secret")), testQueue, slog.Default())
+	// the same visitor twice and another one
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}))
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}))
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.143", UserAgent: "curl/8.0"}))
+	require.NoError(t, r.Close(context.Background()))
+}
+
+func TestBatchRecorder_Publishes(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("CreateBatch", mock.Anything).Return(nil).Once()
+	publisher := mocks.NewClickPublisher(t)
+	publisher.On("Publish", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 2 })).Return().Once()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default(), clickstat.WithClickPublisher(publisher)), testQueue, slog.Default())
+	require.NoError(t, r.Record("1234", nil))
+	require.NoError(t, r.Record("1234", nil))
+	require.NoError(t, r.Close(context.Background()))
+}
@@ -0,0 +1,246 @@
+package clickstat
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/robfig/cron/v3"
+)
+
+var defaultRetention = config.Retention{Schedule: "0 2 * * *", RawClicks: 30 * 24 * time.Hour, BatchSize: 10000, PartitionsAhead: 2}
+
+var ErrCleanupRunning = errors.New("cleanup is already running")
+
+type cleanupState struct {
+	mu      sync.Mutex
+	lastRun *dto.CleanupRun
+	// succeeded and failed count finished runs
+	succeeded uint64
+	failed    uint64
+	cron      *cron.Cron
+	entry     cron.EntryID
+}
+
+// CleanupStaleRecords schedules Cleanup by the configured schedule
+func (s *ClickStatService) CleanupStaleRecords() (*cron.Cron, error) {
+	c := cron.New()
+
+	entry, err := c.AddFunc(s.retention.Schedule, func() {
+		// the result is logged and kept for the status
+		s.Cleanup()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cleanup.mu.Lock()
+	s.cleanup.cron, s.cleanup.entry = c, entry
+	s.cleanup.mu.Unlock()
+
+	c.Start()
+
+	return c, nil
+}
+
+// Cleanup rolls up raw clicks older than the retention of their url owner's plan and deletes stale visitor sketches.
+// Monthly partitions of clicks older than the longest retention are rolled up and dropped as a whole,
+// clicks of plans with shorter retentions are rolled up row by row in batches. Partitions of the next months are created beforehand
+func (s *ClickStatService) Cleanup() (*dto.CleanupRun, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Cleanup"))
+
+	run := &dto.CleanupRun{Status: dto.CleanupRunning, StartedAt: time.Now()}
+	s.cleanup.mu.Lock()
+	if s.cleanup.lastRun != nil && s.cleanup.lastRun.Status == dto.CleanupRunning {
+		s.cleanup.mu.Unlock()
+		log.Warn("cleanup is already running")
+		return nil, ErrCleanupRunning
+	}
+	s.cleanup.lastRun = run
+	s.cleanup.mu.Unlock()
+
+	err := s.cleanupClicks(run.StartedAt)
+
+	s.updateRun(func(r *dto.CleanupRun) {
+		now := time.Now()
+		r.FinishedAt = &now
+		r.Status = dto.CleanupSucceeded
+		if err != nil {
+			r.Status = dto.CleanupFailed
+			r.Error = err.Error()
+			s.cleanup.failed++
+		} else {
+			s.cleanup.succeeded++
+		}
+		finished := *r
+		run = &finished
+	})
+	if err != nil {
+		log.Error("cleanup failed", sl.Err(err), slog.Int64("rolled_up_clicks", run.RolledUpClicks), slog.Int64("deleted_sketches", run.DeletedSketches))
+		return run, err
+	}
+
+	log.Info("cleanup finished",
+		slog.Int64("rolled_up_clicks", run.RolledUpClicks),
+		slog.Int64("deleted_sketches", run.DeletedSketches),
+		slog.Int("created_partitions", run.CreatedPartitions),
+		slog.Int("dropped_partitions", run.DroppedPartitions),
+		slog.Duration("duration", run.FinishedAt.Sub(run.StartedAt)),
+	)
+	return run, nil
+}
+
+// EnsureClickPartitions creates missing monthly partitions of clicks from the current month to the configured number of months ahead
+// and returns the number of created ones. Clicks can't be saved without a partition of their month
+func (s *ClickStatService) EnsureClickPartitions() (int, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.EnsureClickPartitions"))
+
+	created, err := s.ensureClickPartitions(time.Now())
+	if err != nil {
+		log.Error("failed to create click partitions", sl.Err(err), slog.Int("created", created))
+		return created, service.ErrInternalError
+	}
+
+	return created, nil
+}
+
+func (s *ClickStatService) ensureClickPartitions(now time.Time) (int, error) {
+	return s.repo.EnsureClickPartitions(now, now.UTC().AddDate(0, s.retention.PartitionsAhead, 0))
+}
+
+// cleanupClicks runs the steps of Cleanup and adds their results to the current run
+func (s *ClickStatService) cleanupClicks(now time.Time) error {
+	created, err := s.ensureClickPartitions(now)
+	s.updateRun(func(r *dto.CleanupRun) { r.CreatedPartitions = created })
+	if err != nil {
+		return err
+	}
+
+	scopes := s.retentionScopes(now)
+	// clicks before the oldest cutoff are kept raw by no plan
+	oldest := scopes[0].Cutoff
+	for _, scope := range scopes[1:] {
+		if scope.Cutoff.Before(oldest) {
+			oldest = scope.Cutoff
+		}
+	}
+
+	for _, scope := range scopes {
+		if scope.Cutoff.After(oldest) {
+			n, err := s.inBatches(func() (int64, error) { return s.repo.RollupStaleClicks(scope, s.retention.BatchSize) })
+			s.updateRun(func(r *dto.CleanupRun) { r.RolledUpClicks += n })
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := s.inBatches(func() (int64, error) { return s.repo.DeleteStaleSketches(scope, s.retention.BatchSize) })
+		s.updateRun(func(r *dto.CleanupRun) { r.DeletedSketches += n })
+		if err != nil {
+			return err
+		}
+	}
+
+	partitions, err := s.repo.ClickPartitions()
+	if err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		// partitions are sorted from the oldest one
+		if partition.To.After(oldest) {
+			break
+		}
+		n, err := s.repo.DropClickPartition(partition)
+		if err != nil {
+			return err
+		}
+		s.updateRun(func(r *dto.CleanupRun) {
+			r.RolledUpClicks += n
+			r.DroppedPartitions++
+		})
+	}
+
+	return nil
+}
+
+// CleanupStatus returns the schedule and the last run of the cleanup since the start
+func (s *ClickStatService) CleanupStatus() *dto.CleanupStatus {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+
+	status := &dto.CleanupStatus{
+		Schedule:      s.retention.Schedule,
+		SucceededRuns: s.cleanup.succeeded,
+		FailedRuns:    s.cleanup.failed,
+	}
+	if s.cleanup.cron != nil {
+		next := s.cleanup.cron.Entry(s.cleanup.entry).Next
+		status.NextRunAt = &next
+	}
+	if s.cleanup.lastRun != nil {
+		lastRun := *s.cleanup.lastRun
+		status.LastRun = &lastRun
+	}
+
+	return status
+}
+
+func (s *ClickStatService) updateRun(update func(r *dto.CleanupRun)) {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+	update(s.cleanup.lastRun)
+}
+
+// inBatches repeats the batch until it affects fewer rows than the batch size
+func (s *ClickStatService) inBatches(batch func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := batch()
+		total += n
+		if err != nil || n < int64(s.retention.BatchSize) {
+			return total, err
+		}
+	}
+}
+
+// retentionScopes groups urls by the retention of their owners' plans. Users with unknown plans are on the default one
+func (s *ClickStatService) retentionScopes(now time.Time) []repo.RetentionScope {
+	fallback := s.retention.RawClicks
+	if plan, ok := s.plans.Tiers[s.plans.Default]; ok && plan.RawClickRetention > 0 {
+		fallback = plan.RawClickRetention
+	}
+
+	cutoff := func(retention time.Duration) time.Time {
+		return now.UTC().Add(-retention).Truncate(24 * time.Hour)
+	}
+
+	var scopes []repo.RetentionScope
+	var custom []string
+	for _, name := range slices.Sorted(maps.Keys(s.plans.Tiers)) {
+		plan := s.plans.Tiers[name]
+		if name == s.plans.Default || plan.RawClickRetention <= 0 || plan.RawClickRetention == fallback {
+			continue
+		}
+		custom = append(custom, name)
+		scopes = append(scopes, repo.RetentionScope{
+			Cutoff:      cutoff(plan.RawClickRetention),
+			Plans:       []string{name},
+			DefaultPlan: s.plans.Default,
+		})
+	}
+
+	return append(scopes, repo.RetentionScope{
+		Cutoff:      cutoff(fallback),
+		Plans:       custom,
+		Exclude:     true,
+		DefaultPlan: s.plans.Default,
+	})
+}
@@ -0,0 +1,121 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testPlans = config.Plans{
+	Default: "free",
+	Tiers: map[string]config.Plan{
+		"free": {},
+		"pro":  {},
+		"team": {RawClickRetention: 90 * 24 * time.Hour},
+	},
+}
+
+func scopeOf(plans []string, exclude bool, retention time.Duration) any {
+	return mock.MatchedBy(func(s repo.RetentionScope) bool {
+		cutoff := time.Now().UTC().Add(-retention).Truncate(24 * time.Hour)
+		return assert.ObjectsAreEqual(plans, s.Plans) && s.Exclude == exclude && s.DefaultPlan == "free" && s.Cutoff.Equal(cutoff)
+	})
+}
+
+func TestClickStatService_Cleanup(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	team := scopeOf([]string{"team"}, false, 90*24*time.Hour)
+	rest := scopeOf([]string{"team"}, true, 30*24*time.Hour)
+	clickRepo.On("EnsureClickPartitions", mock.Anything, mock.Anything).Return(1, nil).Once()
+	// clicks of the longest retention are only dropped with their partitions
+	clickRepo.On("DeleteStaleSketches", team, 2).Return(int64(0), nil).Once()
+	// full batches are repeated
+	clickRepo.On("RollupStaleClicks", rest, 2).Return(int64(2), nil).Once()
+	clickRepo.On("RollupStaleClicks", rest, 2).Return(int64(1), nil).Once()
+	clickRepo.On("DeleteStaleSketches", rest, 2).Return(int64(1), nil).Once()
+
+	teamCutoff := time.Now().UTC().AddDate(0, 0, -90)
+	stale := repo.ClickPartition{Name: "stale", To: teamCutoff.AddDate(0, 0, -1)}
+	clickRepo.On("ClickPartitions").Return([]repo.ClickPartition{stale, {Name: "kept", To: teamCutoff.AddDate(0, 0, 1)}}, nil).Once()
+	clickRepo.On("DropClickPartition", stale).Return(int64(5), nil).Once()
+
+	s := clickstat.New(clickRepo, slog.Default(), clickstat.WithRetention(config.Retention{BatchSize: 2}, testPlans))
+	assert.Nil(t, s.CleanupStatus().LastRun)
+
+	run, err := s.Cleanup()
+	require.NoError(t, err)
+	assert.Equal(t, dto.CleanupSucceeded, run.Status)
+	assert.Equal(t, int64(8), run.RolledUpClicks)
+	assert.Equal(t, int64(1), run.DeletedSketches)
+	assert.Equal(t, 1, run.CreatedPartitions)
+	assert.Equal(t, 1, run.DroppedPartitions)
+	assert.NotNil(t, run.FinishedAt)
+
+	status := s.CleanupStatus()
+	assert.Equal(t, "0 2 * * *", status.Schedule)
+	assert.Nil(t, status.NextRunAt)
+	assert.Equal(t, run, status.LastRun)
+	assert.Equal(t, uint64(1), status.SucceededRuns)
+	assert.Zero(t, status.FailedRuns)
+}
+
+func TestClickStatService_CleanupFailure(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("EnsureClickPartitions", mock.Anything, mock.Anything).Return(0, nil).Once()
+	clickRepo.On("DeleteStaleSketches", mock.Anything, 10000).Return(int64(0), nil).Once()
+	old := []repo.ClickPartition{{Name: "first"}, {Name: "second"}}
+	clickRepo.On("ClickPartitions").Return(old, nil).Once()
+	clickRepo.On("DropClickPartition", old[0]).Return(int64(10000), nil).Once()
+	clickRepo.On("DropClickPartition", old[1]).Return(int64(0), errors.New("unexpected")).Once()
+
+	s := clickstat.New(clickRepo, slog.Default())
+
+	run, err := s.Cleanup()
+	assert.Error(t, err)
+	assert.Equal(t, dto.CleanupFailed, run.Status)
+	assert.Equal(t, "unexpected", run.Error)
+	assert.Equal(t, int64(10000), run.RolledUpClicks)
+	assert.Equal(t, 1, run.DroppedPartitions)
+	assert.Equal(t, dto.CleanupFailed, s.CleanupStatus().LastRun.Status)
+	assert.Equal(t, uint64(1), s.CleanupStatus().FailedRuns)
+}
+
+func TestClickStatService_EnsureClickPartitions(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	ahead := mock.MatchedBy(func(to time.Time) bool {
+		return to.After(time.Now().AddDate(0, 3, -1)) && to.Before(time.Now().AddDate(0, 3, 1))
+	})
+	clickRepo.On("EnsureClickPartitions", mock.Anything, ahead).Return(2, nil).Once()
+	clickRepo.On("EnsureClickPartitions", mock.Anything, ahead).Return(0, errors.New("unexpected")).Once()
+
+	s := clickstat.New(clickRepo, slog.Default(), clickstat.WithRetention(config.Retention{PartitionsAhead: 3}, config.Plans{}))
+	created, err := s.EnsureClickPartitions()
+	require.NoError(t, err)
+	assert.Equal(t, 2, created)
+
+	_, err = s.EnsureClickPartitions()
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
+
+func TestClickStatService_CleanupStaleRecords(t *testing.T) {
+	s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default())
+	c, err := s.CleanupStaleRecords()
+	require.NoError(t, err)
+	defer c.Stop()
+	assert.NotNil(t, s.CleanupStatus().NextRunAt)
+
+	s = clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithRetention(config.Retention{Schedule: "invalid"}, config.Plans{}))
+	_, err = s.CleanupStaleRecords()
+	assert.Error(t, err)
+}
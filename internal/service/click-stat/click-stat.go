@@ -1,75 +1,404 @@
 package clickstat
 
 import (
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/config"
 	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/hll"
 	"url-shortener/internal/lib/logger/sl"
-	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/lib/timebucket"
 	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
 	"url-shortener/internal/service"
 
-	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
 )
 
 //go:generate mockery --name=ClickStatRepo
 type ClickStatRepo interface {
 	Create(ClickStat *model.ClickStat) error
-	ByUrlID(urlID string, userID string) ([]repo.DailyCount, error)
-	CleanupStaleRecords() error
+	CreateBatch(clicks []*model.ClickStat) error
+	ByUrlID(urlID string, userID string, rng repo.StatsRange) ([]repo.DailyCount, error)
+	ByUrlIDUnchecked(urlID string, rng repo.StatsRange) ([]repo.DailyCount, error)
+	ByUserID(userID string, rng repo.StatsRange) ([]repo.DailyCount, error)
+	TopUrls(userID string, rng repo.StatsRange, limit int) ([]repo.UrlClicks, error)
+	CreatedUrls(userID string, from, to time.Time) (int64, error)
+	EachClick(urlID, userID string, rng repo.StatsRange, each func(click *model.ClickStat) error) error
+	Breakdown(urlID, userID, dimension string, includeBots bool) ([]repo.DimensionCount, error)
+	TopValues(urlID, userID, dimension string, rng repo.StatsRange, limit int) ([]repo.DimensionCount, error)
+	Heatmap(urlID, userID string, rng repo.StatsRange) ([]repo.HeatmapCell, error)
+	AddVisitors(urlID string, day time.Time, sketch *hll.Sketch) error
+	VisitorSketches(urlID string, from, to time.Time) ([]model.VisitorSketch, error)
+	RollupStaleClicks(scope repo.RetentionScope, limit int) (int64, error)
+	DeleteStaleSketches(scope repo.RetentionScope, limit int) (int64, error)
+	HitsDrift(limit int) ([]repo.HitsDrift, int64, error)
+	RepairHits(afterID string, limit int) ([]repo.HitsDrift, string, error)
+	DedupWindows(urlIDs []string) (map[string]time.Duration, error)
+	ClickPartitions() ([]repo.ClickPartition, error)
+	EnsureClickPartitions(from, to time.Time) (int, error)
+	DropClickPartition(partition repo.ClickPartition) (int64, error)
+	CreateConversion(conversion *model.Conversion) (bool, error)
+	CountConversions(clickIDs []string) error
+}
+
+//go:generate mockery --name=ClickQuota
+type ClickQuota interface {
+	OverClickQuota(urlIDs []string) (map[string]bool, error)
+	TrackClicks(counts map[string]int64) error
+}
+
+//go:generate mockery --name=BotDetector
+type BotDetector interface {
+	IsBot(userAgent string) bool
+}
+
+//go:generate mockery --name=ClickPublisher
+type ClickPublisher interface {
+	Publish(clicks []*model.ClickStat)
+}
+
+//go:generate mockery --name=CountryLocator
+type CountryLocator interface {
+	Country(ip net.IP) (string, error)
 }
 
 type ClickStatService struct {
-	repo ClickStatRepo
-	log  *slog.Logger
+	repo       ClickStatRepo
+	log        *slog.Logger
+	quota      ClickQuota
+	locator    CountryLocator
+	publishers []ClickPublisher
+	sinks      []ClickSink
+	// sinkFailures counts clicks lost by sinks other than the primary one, see write
+	sinkFailures atomic.Uint64
+	bots         BotDetector
+	// visitorSecret keys hashes of visitors, see visitorHash
+	visitorSecret []byte
+	retention     config.Retention
+	plans         config.Plans
+	cleanup       cleanupState
+	// dedup is nil when clicks aren't de-duplicated, see WithDedup
+	dedup        *dedupCache
+	dedupDefault time.Duration
+	// conversionWindow is the lifetime of click tokens, see ClickToken
+	conversionWindow time.Duration
+}
+
+type Option func(s *ClickStatService)
+
+// WithClickQuota makes Record skip clicks over the monthly limit of the url owner's plan. Saved clicks are counted
+// once per url and batch, so a batch can go over the limit by its size
+func WithClickQuota(quota ClickQuota) Option {
+	return func(s *ClickStatService) {
+		s.quota = quota
+	}
+}
+
+// WithCountryLocator makes Record resolve countries of visitors by their ips
+func WithCountryLocator(locator CountryLocator) Option {
+	return func(s *ClickStatService) {
+		s.locator = locator
+	}
+}
+
+// WithBotDetector makes Record classify clicks of user agents matched by the detector as bots
+func WithBotDetector(bots BotDetector) Option {
+	return func(s *ClickStatService) {
+		s.bots = bots
+	}
+}
+
+// WithClickPublisher makes recorded clicks published, e.g. to live streams. Clicks are published to every added publisher
+func WithClickPublisher(publisher ClickPublisher) Option {
+	return func(s *ClickStatService) {
+		s.publishers = append(s.publishers, publisher)
+	}
+}
+
+// WithDedup makes repeated clicks of the same visitor within the window duplicates. Urls can override the window,
+// so de-duplication is enabled even if the global window is 0
+func WithDedup(window time.Duration) Option {
+	return func(s *ClickStatService) {
+		s.dedup = newDedupCache()
+		s.dedupDefault = max(window, 0)
+	}
+}
+
+// WithVisitorSecret sets the key of visitor hashes and click tokens. Without it a random key is used,
+// so the same visitor is counted again and clicks can't be converted after a restart
+func WithVisitorSecret(secret string) Option {
+	return func(s *ClickStatService) {
+		if secret != "" {
+			s.visitorSecret = []byte(secret)
+		}
+	}
+}
+
+// WithRetention sets the cleanup schedule and retention of raw clicks. Plans can override the retention for urls of their users
+func WithRetention(retention config.Retention, plans config.Plans) Option {
+	return func(s *ClickStatService) {
+		if retention.Schedule == "" {
+			retention.Schedule = defaultRetention.Schedule
+		}
+		if retention.RawClicks <= 0 {
+			retention.RawClicks = defaultRetention.RawClicks
+		}
+		if retention.BatchSize <= 0 {
+			retention.BatchSize = defaultRetention.BatchSize
+		}
+		if retention.PartitionsAhead <= 0 {
+			retention.PartitionsAhead = defaultRetention.PartitionsAhead
+		}
+		s.retention = retention
+		s.plans = plans
+	}
 }
 
-func New(repo ClickStatRepo, log *slog.Logger) *ClickStatService {
-	return &ClickStatService{repo, log}
+func New(repo ClickStatRepo, log *slog.Logger, opts ...Option) *ClickStatService {
+	s := &ClickStatService{repo: repo, log: log, retention: defaultRetention, conversionWindow: defaultConversionWindow}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.sinks == nil {
+		s.sinks = []ClickSink{NewPostgresSink(repo)}
+	}
+	if s.visitorSecret == nil {
+		s.visitorSecret = make([]byte, 32)
+		rand.Read(s.visitorSecret)
+	}
+	return s
 }
 
-func (s *ClickStatService) Record(urlID string) error {
+// Record writes the click with dimensions parsed from the visit to the sinks. The visitor ip is never stored
+func (s *ClickStatService) Record(urlID string, visit *dto.Visit) error {
 	log := s.log.With(slog.String("op", "service.clickstat.Record"))
 
-	if err := s.repo.Create(&model.ClickStat{UrlID: urlID}); err != nil {
+	over, err := s.overQuota(urlID)
+	if err != nil {
+		// no need for logs
+		return err
+	}
+	window := s.dedupWindow(s.dedupWindows(urlID), urlID)
+	click, visitor, err := s.click(urlID, visit, time.Now(), window, over[urlID])
+	if err != nil {
+		// no need for logs
+		return err
+	}
+
+	saved, err := s.write([]*model.ClickStat{click})
+	s.trackClicks(saved)
+	s.countConversions(saved)
+	if err != nil {
 		log.Error("failed to record click", sl.Err(err))
-		if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23503" { // 23503 = foreign_key_violation
+		if errors.Is(err, service.ErrRelatedResourceNotFound) {
 			return service.ErrRelatedResourceNotFound
 		}
 		return service.ErrInternalError
 	}
+	if visitor != 0 && s.stores() {
+		sketch := hll.New()
+		sketch.Add(visitor)
+		if err := s.repo.AddVisitors(urlID, day(click.CreatedAt), sketch); err != nil {
+			// the click is recorded anyway
+			log.Error("failed to record visitor", sl.Err(err))
+		}
+	}
+	s.publish(click)
 
 	// log.Info("click successfully recorded")
 	return nil
 }
 
-func (s *ClickStatService) Stats(urlID, userID string) ([]repo.DailyCount, error) {
+// overQuota returns the urls over the click quota of their owners' plans
+func (s *ClickStatService) overQuota(urlIDs ...string) (map[string]bool, error) {
+	if s.quota == nil {
+		return nil, nil
+	}
+	return s.quota.OverClickQuota(urlIDs)
+}
+
+// trackClicks counts saved clicks, except bots and duplicates, for the click quota with one update per batch
+func (s *ClickStatService) trackClicks(saved []*model.ClickStat) {
+	if s.quota == nil {
+		return
+	}
+	counts := make(map[string]int64)
+	for _, click := range saved {
+		if !click.Bot && !click.Duplicate {
+			counts[click.UrlID]++
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	if err := s.quota.TrackClicks(counts); err != nil {
+		// the clicks are recorded anyway
+		s.log.Error("failed to count clicks", slog.String("op", "service.clickstat.trackClicks"), sl.Err(err))
+	}
+}
+
+// publish passes recorded clicks to publishers. Duplicates aren't published, they only add to raw counts
+func (s *ClickStatService) publish(clicks ...*model.ClickStat) {
+	counted := make([]*model.ClickStat, 0, len(clicks))
+	for _, click := range clicks {
+		if !click.Duplicate {
+			counted = append(counted, click)
+		}
+	}
+	if len(counted) == 0 {
+		return
+	}
+	for _, publisher := range s.publishers {
+		publisher.Publish(counted)
+	}
+}
+
+// click parses the visit into a click ready to be saved and the visitor hash, 0 if it's unknown, a bot or a duplicate.
+// Clicks of humans are de-duplicated within the window and the counted ones are rejected when the url is over the click quota
+func (s *ClickStatService) click(urlID string, visit *dto.Visit, at time.Time, window time.Duration, overQuota bool) (*model.ClickStat, uint64, error) {
+	click := clickFromVisit(urlID, visit)
+	if visit != nil && s.bots != nil && s.bots.IsBot(visit.UserAgent) {
+		click.Bot, click.Device = true, model.DeviceBot
+	}
+
+	if !click.Bot && window > 0 {
+		if fp := fingerprint(s.visitorSecret, visit); fp != 0 {
+			click.Duplicate = s.dedup.duplicate(dedupKey{urlID, fp}, at, window)
+		}
+	}
+
+	if overQuota && !click.Bot && !click.Duplicate {
+		return nil, 0, service.ErrClickQuotaExceeded
+	}
+
+	// click times are stored in UTC, see repo.ByUrlIDUnchecked
+	click.CreatedAt = at.UTC()
+	if ip := visitIP(visit); s.locator != nil && ip != nil {
+		country, err := s.locator.Country(ip)
+		if err != nil {
+			// the click is still recorded without a country
+			s.log.Warn("failed to resolve country", slog.String("op", "service.clickstat.click"), sl.Err(err))
+		}
+		click.Country = country
+	}
+
+	if click.Bot || click.Duplicate {
+		// bots aren't unique visitors, visitors of duplicates are already counted
+		return click, 0, nil
+	}
+	if visit != nil {
+		click.ClickID = visit.ClickID
+	}
+	return click, visitorHash(s.visitorSecret, day(click.CreatedAt), visit), nil
+}
+
+// Stats returns the zero-filled series of the user's url in the range of the query
+func (s *ClickStatService) Stats(urlID, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error) {
 	log := s.log.With(slog.String("op", "service.clickstat.Stats"))
 
-	stats, err := s.repo.ByUrlID(urlID, userID)
+	rng, err := statsRange(query, time.Now())
+	if err != nil {
+		log.Info("invalid stats query", sl.Err(err))
+		return nil, err
+	}
+
+	stats, err := s.repo.ByUrlID(urlID, userID, rng)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return nil, service.ErrUrlStatsNotFound
+		}
 		log.Error("failed to get stats", sl.Err(err))
 		return nil, service.ErrInternalError
 	}
-	if len(stats) == 0 {
-		log.Info("statistics not found")
-		return nil, service.ErrUrlStatsNotFound
+	if err := s.addUniques(urlID, stats, rng); err != nil {
+		log.Error("failed to get unique visitors", sl.Err(err))
+		return nil, service.ErrInternalError
 	}
 
 	log.Info("statistics successfully received")
 	return stats, nil
 }
-func (s *ClickStatService) CleanupStaleRecords() (*cron.Cron, error) {
-	c := cron.New()
 
-	// Run daily at 02:00 AM
-	_, err := c.AddFunc("0 2 * * *", func() {
-		s.repo.CleanupStaleRecords()
-	})
+// StatsByUrlID returns the default series and doesn't check the url owner. Use it only after access to the url is checked otherwise
+func (s *ClickStatService) StatsByUrlID(urlID string) ([]repo.DailyCount, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.StatsByUrlID"))
+
+	rng, err := statsRange(&dto.StatsQuery{}, time.Now())
 	if err != nil {
-		return nil, err
+		log.Error("invalid default stats query", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	stats, err := s.repo.ByUrlIDUnchecked(urlID, rng)
+	if err != nil {
+		log.Error("failed to get stats", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	if err := s.addUniques(urlID, stats, rng); err != nil {
+		log.Error("failed to get unique visitors", sl.Err(err))
+		return nil, service.ErrInternalError
 	}
 
-	c.Start()
+	log.Info("statistics successfully received")
+	return stats, nil
+}
+
+// addUniques sets unique visitors of the buckets by merging daily sketches. Sketches are kept by UTC days,
+// so with another time zone days are approximated. Uniques aren't available by hour
+func (s *ClickStatService) addUniques(urlID string, stats []repo.DailyCount, rng repo.StatsRange) error {
+	if len(stats) == 0 || rng.Granularity == timebucket.Hour {
+		return nil
+	}
+
+	sketches, err := s.repo.VisitorSketches(urlID, day(rng.From.In(rng.Location)), day(rng.To.In(rng.Location)))
+	if err != nil {
+		return err
+	}
+
+	buckets := make(map[time.Time]*hll.Sketch)
+	for _, stored := range sketches {
+		sketch := hll.New()
+		if err := sketch.UnmarshalBinary(stored.Sketch); err != nil {
+			return err
+		}
+
+		d := stored.Day
+		bucket := timebucket.Truncate(time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, rng.Location), rng.Granularity)
+		if buckets[bucket] == nil {
+			buckets[bucket] = hll.New()
+		}
+		buckets[bucket].Merge(sketch)
+	}
+	for i := range stats {
+		if sketch := buckets[stats[i].Day]; sketch != nil {
+			stats[i].Uniques = sketch.Count()
+		}
+	}
+
+	return nil
+}
+
+// Breakdown returns click counts of the user's url grouped by the dimension
+func (s *ClickStatService) Breakdown(urlID, userID, dimension string, includeBots bool) ([]repo.DimensionCount, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Breakdown"))
+
+	breakdown, err := s.repo.Breakdown(urlID, userID, dimension, includeBots)
+	if err != nil {
+		if errors.Is(err, repo.ErrUnknownDimension) {
+			log.Info("unknown dimension", slog.String("dimension", dimension))
+			return nil, fmt.Errorf("%w%s", service.ErrValidation, "unknown dimension "+dimension)
+		}
+		log.Error("failed to get breakdown", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
 
-	return c, nil
+	log.Info("breakdown successfully received")
+	return breakdown, nil
 }
@@ -3,9 +3,15 @@ package clickstat_test
 import (
 	"errors"
 	"log/slog"
+	"net"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
 	"url-shortener/internal/service"
 	clickstat "url-shortener/internal/service/click-stat"
 	"url-shortener/internal/service/click-stat/mocks"
@@ -13,6 +19,8 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestClickStatService_Record(t *testing.T) {
@@ -54,42 +62,293 @@ func TestClickStatService_Record(t *testing.T) {
 			}
 			s := clickstat.New(repo, slog.Default())
 
-			err := s.Record(tt.urlID)
+			err := s.Record(tt.urlID, nil)
 			assert.Equal(t, tt.wantErr, err)
 		})
 	}
 }
 
+func TestClickStatService_RecordWithQuota(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	quota := mocks.NewClickQuota(t)
+
+	s := clickstat.New(repo, slog.Default(), clickstat.WithClickQuota(quota))
+
+	quota.On("OverClickQuota", []string{"1234"}).Return(map[string]bool{"1234": true}, nil).Once()
+	assert.Equal(t, service.ErrClickQuotaExceeded, s.Record("1234", nil))
+
+	// the click is counted after it's saved
+	quota.On("OverClickQuota", []string{"1234"}).Return(map[string]bool{}, nil).Once()
+	repo.On("Create", mock.Anything).Return(nil).Once()
+	quota.On("TrackClicks", map[string]int64{"1234": 1}).Return(nil).Once()
+	assert.NoError(t, s.Record("1234", nil))
+
+	// clicks that aren't saved aren't counted
+	quota.On("OverClickQuota", []string{"1234"}).Return(map[string]bool{}, nil).Once()
+	repo.On("Create", mock.Anything).Return(errors.New("unexpected")).Once()
+	assert.Equal(t, service.ErrInternalError, s.Record("1234", nil))
+
+	quota.On("OverClickQuota", []string{"1234"}).Return(nil, service.ErrInternalError).Once()
+	assert.Equal(t, service.ErrInternalError, s.Record("1234", nil))
+}
+
+func TestClickStatService_RecordPublishes(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	publisher := mocks.NewClickPublisher(t)
+
+	s := clickstat.New(repo, slog.Default(), clickstat.WithClickPublisher(publisher))
+
+	// failed clicks aren't published
+	repo.On("Create", mock.Anything).Return(errors.New("unexpected")).Once()
+	assert.Error(t, s.Record("1234", nil))
+
+	repo.On("Create", mock.Anything).Return(nil).Once()
+	publisher.On("Publish", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 1 && c[0].UrlID == "1234" })).Return().Once()
+	assert.NoError(t, s.Record("1234", nil))
+}
+
+func TestClickStatService_RecordVisit(t *testing.T) {
+	tests := []struct {
+		name        string
+		visit       *dto.Visit
+		country     string
+		countryErr  error
+		detectedBot bool
+		wantClick   *model.ClickStat
+		wantLocated bool
+		wantVisitor bool
+	}{
+		{
+			name: "desktop",
+			visit: &dto.Visit{
+				IP:             "81.2.69.142",
+				UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+				Referrer:       "https://www.Google.com/search?q=test",
+				AcceptLanguage: "de;q=0.5, en-US, en;q=0.9",
+				ClickID:        "click",
+			},
+			country: "GB",
+			wantClick: &model.ClickStat{
+				UrlID:        "1234",
+				ClickID:      "click",
+				ReferrerHost: "google.com",
+				Browser:      "Chrome",
+				OS:           "Windows",
+				Device:       model.DeviceDesktop,
+				Language:     "en",
+				Country:      "GB",
+			},
+			wantLocated: true,
+			wantVisitor: true,
+		},
+		{
+			name: "mobile",
+			visit: &dto.Visit{
+				IP:        "81.2.69.142",
+				UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			},
+			countryErr: errors.New("unexpected"),
+			wantClick: &model.ClickStat{
+				UrlID:   "1234",
+				Browser: "Safari",
+				OS:      "iPhone OS",
+				Device:  model.DeviceMobile,
+			},
+			wantLocated: true,
+			wantVisitor: true,
+		},
+		{
+			name: "bot without ip",
+			visit: &dto.Visit{
+				UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+				Referrer:  "not a url\x7f",
+			},
+			wantClick: &model.ClickStat{
+				UrlID:   "1234",
+				Browser: "Googlebot",
+				Device:  model.DeviceBot,
+				Bot:     true,
+			},
+		},
+		{
+			name: "bot of the detector",
+			visit: &dto.Visit{
+				IP:        "81.2.69.142",
+				UserAgent: "Slackbot-LinkExpanding 1.0",
+			},
+			detectedBot: true,
+			wantClick: &model.ClickStat{
+				UrlID:   "1234",
+				Browser: "Slackbot-LinkExpanding",
+				Device:  model.DeviceBot,
+				Bot:     true,
+			},
+			wantLocated: true,
+		},
+		{
+			name: "multi-byte headers at the limit",
+			visit: &dto.Visit{
+				UserAgent: strings.Repeat("é", 70) + "\xff/1.0",
+				Referrer:  "https://" + strings.Repeat("ü", 300) + ".com",
+			},
+			wantClick: &model.ClickStat{
+				UrlID:        "1234",
+				ReferrerHost: strings.Repeat("ü", 255),
+				Browser:      strings.Repeat("é", 64),
+				Device:       model.DeviceDesktop,
+			},
+			wantVisitor: true,
+		},
+		{
+			name: "head request",
+			visit: &dto.Visit{
+				UserAgent: "curl/8.0",
+				Method:    http.MethodHead,
+			},
+			wantClick: &model.ClickStat{
+				UrlID:   "1234",
+				Browser: "curl",
+				Device:  model.DeviceDesktop,
+				Bot:     true,
+			},
+		},
+		{
+			name: "prefetch",
+			visit: &dto.Visit{
+				UserAgent: "curl/8.0",
+				Purpose:   "prefetch;prerender",
+				// clicks that aren't counted don't count conversions
+				ClickID: "prefetched",
+			},
+			wantClick: &model.ClickStat{
+				UrlID:   "1234",
+				Browser: "curl",
+				Device:  model.DeviceDesktop,
+				Bot:     true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewClickStatRepo(t)
+			locator := mocks.NewCountryLocator(t)
+			if tt.wantLocated {
+				locator.On("Country", net.ParseIP(tt.visit.IP)).Return(tt.country, tt.countryErr).Once()
+			}
+			repo.On("Create", mock.MatchedBy(func(c *model.ClickStat) bool {
+				want := *tt.wantClick
+				want.CreatedAt = c.CreatedAt
+				return assert.ObjectsAreEqual(&want, c)
+			})).Return(nil).Once()
+			if tt.wantVisitor {
+				repo.On("AddVisitors", "1234", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+			if tt.wantClick.ClickID != "" {
+				repo.On("CountConversions", []string{tt.wantClick.ClickID}).Return(nil).Once()
+			}
+			bots := mocks.NewBotDetector(t)
+			bots.On("IsBot", tt.visit.UserAgent).Return(tt.detectedBot).Once()
+			// bots aren't tracked by the quota
+			quota := mocks.NewClickQuota(t)
+			quota.On("OverClickQuota", []string{"1234"}).Return(map[string]bool{}, nil).Once()
+			if !tt.wantClick.Bot {
+				quota.On("TrackClicks", map[string]int64{"1234": 1}).Return(nil).Once()
+			}
+
+			s := clickstat.New(repo, slog.Default(),
+				clickstat.WithCountryLocator(locator),
+				clickstat.WithBotDetector(bots),
+				clickstat.WithClickQuota(quota),
+			)
+
+			assert.NoError(t, s.Record("1234", tt.visit))
+		})
+	}
+}
+
+func TestClickStatService_Breakdown(t *testing.T) {
+	breakdown := []repo.DimensionCount{{Value: "google.com", Count: 3}, {Value: "unknown", Count: 1}}
+
+	r := mocks.NewClickStatRepo(t)
+	s := clickstat.New(r, slog.Default())
+
+	r.On("Breakdown", "1234", "5678", "referrer", false).Return(breakdown, nil).Once()
+	got, err := s.Breakdown("1234", "5678", "referrer", false)
+	assert.NoError(t, err)
+	assert.Equal(t, breakdown, got)
+
+	r.On("Breakdown", "1234", "5678", "ip", false).Return(nil, repo.ErrUnknownDimension).Once()
+	_, err = s.Breakdown("1234", "5678", "ip", false)
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	r.On("Breakdown", "1234", "5678", "os", false).Return(nil, errors.New("unexpected")).Once()
+	_, err = s.Breakdown("1234", "5678", "os", false)
+	assert.Equal(t, service.ErrInternalError, err)
+}
+
 func TestClickStatService_Stats(t *testing.T) {
-	today := time.Now().Truncate(24 * time.Hour)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
 	stats := []repo.DailyCount{
-		{Day: today.AddDate(0, 0, 0), Count: 23},
-		{Day: today.AddDate(0, 0, -1), Count: 58},
-		{Day: today.AddDate(0, 0, -2), Count: 11},
 		{Day: today.AddDate(0, 0, -3), Count: 9},
+		{Day: today.AddDate(0, 0, -2), Count: 11},
+		{Day: today.AddDate(0, 0, -1), Count: 0},
+		{Day: today.AddDate(0, 0, 0), Count: 23},
 	}
 
 	tests := []struct {
 		name      string
-		urlID     string
-		userID    string
+		query     *dto.StatsQuery
 		mockSetup func(r *mocks.ClickStatRepo)
 		want      []repo.DailyCount
 		wantErr   error
 	}{
 		{
 			name:  "success",
-			urlID: "1234",
+			query: &dto.StatsQuery{},
 			mockSetup: func(r *mocks.ClickStatRepo) {
-				r.On("ByUrlID", mock.Anything, mock.Anything).Return(stats, nil).Once()
+				r.On("ByUrlID", "1234", "5678", mock.Anything).Return(stats, nil).Once()
+				r.On("VisitorSketches", "1234", mock.Anything, mock.Anything).Return(nil, nil).Once()
 			},
 			want: stats,
 		},
+		{
+			name:  "url of another user",
+			query: &dto.StatsQuery{},
+			mockSetup: func(r *mocks.ClickStatRepo) {
+				r.On("ByUrlID", "1234", "5678", mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrUrlStatsNotFound,
+		},
+		{
+			name:    "unknown granularity",
+			query:   &dto.StatsQuery{Granularity: "year"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "unknown time zone",
+			query:   &dto.StatsQuery{TZ: "Mars/Olympus"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "invalid date",
+			query:   &dto.StatsQuery{From: "yesterday"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "from after to",
+			query:   &dto.StatsQuery{From: "2024-02-01", To: "2024-01-01"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "too many buckets",
+			query:   &dto.StatsQuery{From: "2020-01-01", To: "2024-01-01", Granularity: "hour"},
+			wantErr: service.ErrValidation,
+		},
 		{
 			name:  "unexpected",
-			urlID: "1234",
+			query: &dto.StatsQuery{},
 			mockSetup: func(r *mocks.ClickStatRepo) {
-				r.On("ByUrlID", mock.Anything, mock.Anything).Return(nil, errors.New("unexpected")).Once()
+				r.On("ByUrlID", "1234", "5678", mock.Anything).Return(nil, errors.New("unexpected")).Once()
 			},
 			wantErr: service.ErrInternalError,
 		},
@@ -102,9 +361,81 @@ func TestClickStatService_Stats(t *testing.T) {
 			}
 			s := clickstat.New(repo, slog.Default())
 
-			got, err := s.Stats(tt.urlID, tt.userID)
-			assert.Equal(t, tt.wantErr, err)
+			got, err := s.Stats("1234", "5678", tt.query)
+			assert.ErrorIs(t, err, tt.wantErr)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestClickStatService_StatsRange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query *dto.StatsQuery
+		want  repo.StatsRange
+	}{
+		{
+			name:  "dates in time zone",
+			query: &dto.StatsQuery{From: "2024-03-01", To: "2024-03-31", Granularity: "week", TZ: "Europe/Berlin"},
+			want: repo.StatsRange{
+				From:        time.Date(2024, 3, 1, 0, 0, 0, 0, berlin),
+				To:          time.Date(2024, 4, 1, 0, 0, 0, 0, berlin),
+				Granularity: "week",
+				Location:    berlin,
+			},
+		},
+		{
+			name:  "rfc 3339 times",
+			query: &dto.StatsQuery{From: "2024-03-01T10:00:00Z", To: "2024-03-01T16:00:00+02:00", Granularity: "hour"},
+			want: repo.StatsRange{
+				From:        time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+				To:          time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC),
+				Granularity: "hour",
+				Location:    time.UTC,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewClickStatRepo(t)
+			r.On("ByUrlID", "1234", "5678", mock.Anything).Return([]repo.DailyCount{}, nil).Once()
+
+			s := clickstat.New(r, slog.Default())
+
+			_, err := s.Stats("1234", "5678", tt.query)
+			require.NoError(t, err)
+
+			got := r.Calls[0].Arguments.Get(2).(repo.StatsRange)
+			assert.True(t, tt.want.From.Equal(got.From), "from %s", got.From)
+			assert.True(t, tt.want.To.Equal(got.To), "to %s", got.To)
+			assert.Equal(t, tt.want.Granularity, got.Granularity)
+			assert.Equal(t, tt.want.Location.String(), got.Location.String())
+		})
+	}
+}
+
+func TestClickStatService_StatsUniques(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+	stats := []repo.DailyCount{{Day: yesterday, Count: 5}, {Day: today, Count: 3}}
+
+	sketch := hll.New()
+	sketch.Add(0x8000000000000000)
+	sketch.Add(0x4000000000000000)
+	data, err := sketch.MarshalBinary()
+	require.NoError(t, err)
+
+	r := mocks.NewClickStatRepo(t)
+	r.On("ByUrlID", "1234", "5678", mock.Anything).Return(stats, nil).Once()
+	r.On("VisitorSketches", "1234", yesterday, today.AddDate(0, 0, 1)).Return([]model.VisitorSketch{{UrlID: "1234", Day: today, Sketch: data}}, nil).Once()
+
+	s := clickstat.New(r, slog.Default())
+
+	got, err := s.Stats("1234", "5678", &dto.StatsQuery{From: yesterday.Format(time.DateOnly), To: today.Format(time.DateOnly)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), got[0].Uniques)
+	assert.Equal(t, int64(2), got[1].Uniques)
+}
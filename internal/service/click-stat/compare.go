@@ -0,0 +1,82 @@
+package clickstat
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Compare returns series of the user's urls in the range of the query like Stats with their totals, shares of clicks and conversion rates.
+// Every url has to belong to the user, repeated ids are compared once
+func (s *ClickStatService) Compare(userID string, query *dto.CompareQuery) (*dto.StatsComparison, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Compare"))
+
+	if err := service.Validate.Struct(query); err != nil {
+		log.Info("invalid compare query", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+	rng, err := statsRange(&query.StatsQuery, time.Now())
+	if err != nil {
+		log.Info("invalid compare query", sl.Err(err))
+		return nil, err
+	}
+
+	comparison := &dto.StatsComparison{From: rng.From, To: rng.To, Granularity: rng.Granularity, Urls: []dto.ComparedUrl{}}
+	compared := make(map[string]bool, len(query.UrlIDs))
+	for _, urlID := range query.UrlIDs {
+		if compared[urlID] {
+			continue
+		}
+		compared[urlID] = true
+
+		series, err := s.repo.ByUrlID(urlID, userID, rng)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Info("url not found", slog.String("url_id", urlID))
+				return nil, service.ErrUrlStatsNotFound
+			}
+			log.Error("failed to get stats", sl.Err(err))
+			return nil, service.ErrInternalError
+		}
+		if err := s.addUniques(urlID, series, rng); err != nil {
+			log.Error("failed to get unique visitors", sl.Err(err))
+			return nil, service.ErrInternalError
+		}
+
+		clicks := total(series)
+		var conversions int64
+		for _, b := range series {
+			conversions += b.Conversions
+		}
+		comparison.TotalClicks += clicks
+		comparison.Urls = append(comparison.Urls, dto.ComparedUrl{
+			Alias:          urlID,
+			TotalClicks:    clicks,
+			Conversions:    conversions,
+			ConversionRate: share(conversions, clicks),
+			Series:         series,
+		})
+	}
+	for i := range comparison.Urls {
+		comparison.Urls[i].Share = share(comparison.Urls[i].TotalClicks, comparison.TotalClicks)
+	}
+
+	log.Info("comparison successfully received")
+	return comparison, nil
+}
+
+// share returns the part of the total in percents rounded to tenths, nil when the total is 0
+func share(part, total int64) *float64 {
+	if total == 0 {
+		return nil
+	}
+	percent := math.Round(float64(part)/float64(total)*1000) / 10
+	return &percent
+}
@@ -0,0 +1,52 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClickStatService_Compare(t *testing.T) {
+	day := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("ByUrlID", "a", "user", mock.Anything).Return([]repo.DailyCount{{Day: day, Count: 2}}, nil).Once()
+	clickRepo.On("ByUrlID", "b", "user", mock.Anything).Return([]repo.DailyCount{{Day: day, Count: 1}}, nil).Once()
+	clickRepo.On("VisitorSketches", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Twice()
+
+	s := clickstat.New(clickRepo, slog.Default())
+	comparison, err := s.Compare("user", &dto.CompareQuery{UrlIDs: []string{"a", "b", "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), comparison.TotalClicks)
+	require.Len(t, comparison.Urls, 2)
+	require.NotNil(t, comparison.Urls[0].Share)
+	assert.Equal(t, 66.7, *comparison.Urls[0].Share)
+	assert.Equal(t, 33.3, *comparison.Urls[1].Share)
+}
+
+func TestClickStatService_CompareErrors(t *testing.T) {
+	s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default())
+	_, err := s.Compare("user", &dto.CompareQuery{})
+	assert.ErrorIs(t, err, service.ErrValidation)
+	_, err = s.Compare("user", &dto.CompareQuery{UrlIDs: make([]string, 11)})
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("ByUrlID", "other", "user", mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+	clickRepo.On("ByUrlID", "broken", "user", mock.Anything).Return(nil, errors.New("unexpected")).Once()
+	s = clickstat.New(clickRepo, slog.Default())
+	_, err = s.Compare("user", &dto.CompareQuery{UrlIDs: []string{"other"}})
+	assert.ErrorIs(t, err, service.ErrUrlStatsNotFound)
+	_, err = s.Compare("user", &dto.CompareQuery{UrlIDs: []string{"broken"}})
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
@@ -0,0 +1,131 @@
+package clickstat
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+)
+
+// defaultConversionWindow is used without WithConversionWindow
+const defaultConversionWindow = 30 * 24 * time.Hour
+
+// WithConversionWindow sets how long after a click its conversions are attributed to it
+func WithConversionWindow(window time.Duration) Option {
+	return func(s *ClickStatService) {
+		if window > 0 {
+			s.conversionWindow = window
+		}
+	}
+}
+
+// ClickToken returns a token of a click of the url made now and the id of the click. The redirect passes the token
+// to the destination, so the landing page keeps it first-party and reports the conversion with it.
+// The id is recorded with the click when it's counted, conversions of other clicks aren't counted
+func (s *ClickStatService) ClickToken(urlID string) (string, string) {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	clickID := base64.RawURLEncoding.EncodeToString(id)
+	click := clickID + "." + strconv.FormatInt(time.Now().Unix(), 10)
+	return click + "." + s.clickSignature(urlID, click), clickID
+}
+
+// Convert records the conversion of the click of the token. The conversion isn't attributed when the token is missing,
+// signed for another url or older than the window. A click converts only once, so repeated conversions are attributed but not counted.
+// Conversions of clicks that aren't counted, e.g. bots or duplicates, are attributed but not counted either
+func (s *ClickStatService) Convert(urlID, token string) (bool, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Convert"))
+
+	conversion, ok := s.parseClickToken(urlID, token, time.Now())
+	if !ok {
+		log.Info("conversion isn't attributed to a click")
+		return false, nil
+	}
+
+	created, err := s.repo.CreateConversion(conversion)
+	if err != nil {
+		if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23503" { // 23503 = foreign_key_violation
+			log.Info("url not found")
+			return false, service.ErrUrlNotFound
+		}
+		log.Error("failed to record conversion", sl.Err(err))
+		return false, service.ErrInternalError
+	}
+	if !created {
+		log.Info("click is already converted")
+		return true, nil
+	}
+
+	log.Info("conversion successfully recorded")
+	return true, nil
+}
+
+// countConversions counts conversions reported before their saved clicks were saved
+func (s *ClickStatService) countConversions(saved []*model.ClickStat) {
+	if !s.stores() {
+		return
+	}
+	var clickIDs []string
+	for _, click := range saved {
+		if click.ClickID != "" {
+			clickIDs = append(clickIDs, click.ClickID)
+		}
+	}
+	if len(clickIDs) == 0 {
+		return
+	}
+	if err := s.repo.CountConversions(clickIDs); err != nil {
+		// the clicks are recorded anyway
+		s.log.Error("failed to count conversions", slog.String("op", "service.clickstat.countConversions"), sl.Err(err))
+	}
+}
+
+// parseClickToken returns the conversion of the click of the valid token
+func (s *ClickStatService) parseClickToken(urlID, token string, now time.Time) (*model.Conversion, bool) {
+	click, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.clickSignature(urlID, click))) {
+		return nil, false
+	}
+	id, unix, ok := strings.Cut(click, ".")
+	if !ok {
+		return nil, false
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	clickedAt := time.Unix(seconds, 0).UTC()
+	if clickedAt.After(now) || now.Sub(clickedAt) > s.conversionWindow {
+		return nil, false
+	}
+	return &model.Conversion{UrlID: urlID, ClickID: id, ClickedAt: clickedAt, CreatedAt: now.UTC()}, true
+}
+
+// clickSignature signs the click for the url, so tokens can't be forged or moved to other urls
+func (s *ClickStatService) clickSignature(urlID, click string) string {
+	mac := hmac.New(sha256.New, s.visitorSecret)
+	mac.Write([]byte("click"))
+	mac.Write([]byte{0})
+	mac.Write([]byte(urlID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(click))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
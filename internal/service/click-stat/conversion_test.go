@@ -0,0 +1,86 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStatService_Convert(t *testing.T) {
+	t.Run("attributed", func(t *testing.T) {
+		clickRepo := mocks.NewClickStatRepo(t)
+		s := clickstat.New(clickRepo, slog.Default(), clickstat.WithVisitorSecret("secret"))
+		token, clickID := s.ClickToken("1234")
+
+		// the token starts with the click id
+		assert.True(t, strings.HasPrefix(token, clickID+"."))
+		clickRepo.On("CreateConversion", mock.MatchedBy(func(c *model.Conversion) bool {
+			return c.UrlID == "1234" && c.ClickID == clickID && time.Since(c.ClickedAt) < time.Minute
+		})).Return(true, nil).Once()
+		// the click is converted already
+		clickRepo.On("CreateConversion", mock.Anything).Return(false, nil).Once()
+
+		attributed, err := s.Convert("1234", token)
+		require.NoError(t, err)
+		assert.True(t, attributed)
+		attributed, err = s.Convert("1234", token)
+		require.NoError(t, err)
+		assert.True(t, attributed)
+
+		// another click gets another id
+		other, _ := s.ClickToken("1234")
+		clickRepo.On("CreateConversion", mock.MatchedBy(func(c *model.Conversion) bool { return c.ClickID != clickID })).Return(true, nil).Once()
+		attributed, err = s.Convert("1234", other)
+		require.NoError(t, err)
+		assert.True(t, attributed)
+	})
+
+	t.Run("not attributed", func(t *testing.T) {
+		// the repo isn't called
+		s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithVisitorSecret("secret"))
+		token, _ := s.ClickToken("1234")
+		forged, _ := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithVisitorSecret("other")).ClickToken("1234")
+		expired, _ := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithVisitorSecret("secret"), clickstat.WithConversionWindow(time.Nanosecond)).ClickToken("1234")
+
+		for name, tc := range map[string]struct{ urlID, token string }{
+			"no token":  {"1234", ""},
+			"other url": {"5678", token},
+			"tampered":  {"1234", "x" + token[1:]},
+			"forged":    {"1234", forged},
+			"malformed": {"1234", "not-a-token"},
+		} {
+			attributed, err := s.Convert(tc.urlID, tc.token)
+			require.NoError(t, err, name)
+			assert.False(t, attributed, name)
+		}
+
+		short := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithVisitorSecret("secret"), clickstat.WithConversionWindow(time.Nanosecond))
+		attributed, err := short.Convert("1234", expired)
+		require.NoError(t, err)
+		assert.False(t, attributed, "expired")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		clickRepo := mocks.NewClickStatRepo(t)
+		clickRepo.On("CreateConversion", mock.Anything).Return(false, &pgconn.PgError{Code: "23503"}).Once() // 23503 = foreign_key_violation
+		clickRepo.On("CreateConversion", mock.Anything).Return(false, errors.New("unexpected")).Once()
+		s := clickstat.New(clickRepo, slog.Default())
+		token, _ := s.ClickToken("deleted")
+
+		_, err := s.Convert("deleted", token)
+		assert.ErrorIs(t, err, service.ErrUrlNotFound)
+		_, err = s.Convert("deleted", token)
+		assert.ErrorIs(t, err, service.ErrInternalError)
+	})
+}
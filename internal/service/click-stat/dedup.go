@@ -0,0 +1,102 @@
+package clickstat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+)
+
+// dedupSweepInterval limits how often expired fingerprints are dropped
+const dedupSweepInterval = time.Minute
+
+type dedupKey struct {
+	urlID       string
+	fingerprint uint64
+}
+
+// dedupCache remembers visitors of urls until their de-duplication windows end.
+// Only fingerprints are kept and only in memory, so every instance de-duplicates its own clicks
+type dedupCache struct {
+	mu        sync.Mutex
+	expires   map[dedupKey]time.Time
+	lastSweep time.Time
+}
+
+func newDedupCache() *dedupCache {
+	return &dedupCache{expires: make(map[dedupKey]time.Time)}
+}
+
+// duplicate reports whether a counted click of the visitor was made less than the window before. Otherwise the click
+// starts a new window, so steady refreshes are counted once per window
+func (c *dedupCache) duplicate(key dedupKey, at time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(at)
+
+	if expires, ok := c.expires[key]; ok && at.Before(expires) {
+		return true
+	}
+	c.expires[key] = at.Add(window)
+	return false
+}
+
+// sweep drops ended windows so the map doesn't grow with every visitor ever seen
+func (c *dedupCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < dedupSweepInterval {
+		return
+	}
+	for key, expires := range c.expires {
+		if !now.Before(expires) {
+			delete(c.expires, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+// fingerprint identifies the visitor by ip and user agent like visitorHash but without the day,
+// so windows aren't cut at midnight. It's never stored
+func fingerprint(secret []byte, visit *dto.Visit) uint64 {
+	if visit == nil || (visit.IP == "" && visit.UserAgent == "") {
+		return 0
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("dedup"))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visit.IP))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visit.UserAgent))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// dedupWindows returns windows of the urls that override the global one. Without de-duplication nothing is loaded
+func (s *ClickStatService) dedupWindows(urlIDs ...string) map[string]time.Duration {
+	if s.dedup == nil {
+		return nil
+	}
+
+	windows, err := s.repo.DedupWindows(urlIDs)
+	if err != nil {
+		// clicks are de-duplicated by the global window
+		s.log.Error("failed to get dedup windows", slog.String("op", "service.clickstat.dedupWindows"), sl.Err(err))
+		return nil
+	}
+	return windows
+}
+
+// dedupWindow returns the window of the url, 0 means its clicks aren't de-duplicated
+func (s *ClickStatService) dedupWindow(windows map[string]time.Duration, urlID string) time.Duration {
+	if s.dedup == nil {
+		return 0
+	}
+	if window, ok := windows[urlID]; ok {
+		return window
+	}
+	return s.dedupDefault
+}
@@ -0,0 +1,63 @@
+package clickstat_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStatService_Dedup(t *testing.T) {
+	visit := &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}
+	duplicate := func(urlID string, duplicate bool) any {
+		return mock.MatchedBy(func(c *model.ClickStat) bool { return c.UrlID == urlID && c.Duplicate == duplicate })
+	}
+
+	repo := mocks.NewClickStatRepo(t)
+	// de-duplication is disabled for "off"
+	repo.On("DedupWindows", mock.Anything).Return(map[string]time.Duration{"off": 0}, nil)
+	repo.On("Create", duplicate("1234", false)).Return(nil).Once()
+	repo.On("Create", duplicate("1234", true)).Return(nil).Once()
+	repo.On("Create", duplicate("off", false)).Return(nil).Twice()
+	repo.On("AddVisitors", mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(3)
+	quota := mocks.NewClickQuota(t)
+	quota.On("OverClickQuota", mock.Anything).Return(map[string]bool{}, nil).Times(4)
+	quota.On("TrackClicks", map[string]int64{"1234": 1}).Return(nil).Once()
+	quota.On("TrackClicks", map[string]int64{"off": 1}).Return(nil).Twice()
+	publisher := mocks.NewClickPublisher(t)
+	publisher.On("Publish", mock.Anything).Return().Times(3)
+
+	s := clickstat.New(repo, slog.Default(), clickstat.WithDedup(time.Minute), clickstat.WithClickQuota(quota), clickstat.WithClickPublisher(publisher))
+
+	// the duplicate is saved, but it isn't tracked, published or counted as a visitor
+	require.NoError(t, s.Record("1234", visit))
+	require.NoError(t, s.Record("1234", visit))
+	require.NoError(t, s.Record("off", visit))
+	require.NoError(t, s.Record("off", visit))
+}
+
+func TestBatchRecorder_Dedup(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("DedupWindows", []string{"1234", "1234", "1234"}).Return(map[string]time.Duration{}, nil).Once()
+	repo.On("CreateBatch", mock.MatchedBy(func(c []*model.ClickStat) bool {
+		return len(c) == 3 && !c[0].Duplicate && c[1].Duplicate && !c[2].Duplicate
+	})).Return(nil).Once()
+	repo.On("AddVisitors", "1234", mock.Anything, mock.Anything).Return(nil).Once()
+
+	r := clickstat.NewBatchRecorder(clickstat.New(repo, slog.Default(), clickstat.WithDedup(time.Minute)), testQueue, slog.Default())
+	// another user agent is another visitor
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}))
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}))
+	require.NoError(t, r.Record("1234", &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.1"}))
+	require.NoError(t, r.Close(context.Background()))
+
+	assert.Equal(t, uint64(3), r.Metrics().Recorded)
+}
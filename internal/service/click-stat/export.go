@@ -0,0 +1,76 @@
+package clickstat
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// ExportSeries returns the series of the user's url like Stats or, when the url id is empty, of all the user's urls without uniques
+func (s *ClickStatService) ExportSeries(urlID, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error) {
+	if urlID != "" {
+		return s.Stats(urlID, userID, query)
+	}
+	log := s.log.With(slog.String("op", "service.clickstat.ExportSeries"))
+
+	rng, err := statsRange(query, time.Now())
+	if err != nil {
+		log.Info("invalid stats query", sl.Err(err))
+		return nil, err
+	}
+
+	stats, err := s.repo.ByUserID(userID, rng)
+	if err != nil {
+		log.Error("failed to get stats", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("statistics successfully received")
+	return stats, nil
+}
+
+// ExportClicks streams raw clicks of the user's url or, when the url id is empty, of all the user's urls in the range of the query.
+// Click times are in the query's time zone. Clicks that are already rolled up aren't exported.
+// Errors of the callback are returned as is
+func (s *ClickStatService) ExportClicks(urlID, userID string, query *dto.StatsQuery, each func(click *model.ClickStat) error) error {
+	log := s.log.With(slog.String("op", "service.clickstat.ExportClicks"))
+
+	rng, err := statsRange(query, time.Now())
+	if err != nil {
+		log.Info("invalid stats query", sl.Err(err))
+		return err
+	}
+
+	var eachErr error
+	var exported int64
+	err = s.repo.EachClick(urlID, userID, rng, func(click *model.ClickStat) error {
+		click.CreatedAt = click.CreatedAt.In(rng.Location)
+		if eachErr = each(click); eachErr != nil {
+			return eachErr
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		if eachErr != nil {
+			// no need for logs
+			return eachErr
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return service.ErrUrlStatsNotFound
+		}
+		log.Error("failed to export clicks", sl.Err(err), slog.Int64("exported", exported))
+		return service.ErrInternalError
+	}
+
+	log.Info("clicks successfully exported", slog.Int64("exported", exported))
+	return nil
+}
@@ -0,0 +1,74 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClickStatService_ExportClicks(t *testing.T) {
+	at := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	eachClick := func(clicks ...*model.ClickStat) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			each := args.Get(3).(func(click *model.ClickStat) error)
+			for _, click := range clicks {
+				if each(click) != nil {
+					return
+				}
+			}
+		}
+	}
+
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("EachClick", "1234", "user", mock.Anything, mock.Anything).
+		Run(eachClick(&model.ClickStat{UrlID: "1234", CreatedAt: at}, &model.ClickStat{UrlID: "1234", CreatedAt: at})).
+		Return(nil).Once()
+	s := clickstat.New(clickRepo, slog.Default())
+
+	// click times are in the query's time zone
+	var exported []*model.ClickStat
+	err := s.ExportClicks("1234", "user", &dto.StatsQuery{TZ: "Asia/Tokyo"}, func(click *model.ClickStat) error {
+		exported = append(exported, click)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Asia/Tokyo", exported[0].CreatedAt.Location().String())
+	assert.True(t, at.Equal(exported[0].CreatedAt))
+
+	// errors of the callback are returned as is
+	writeErr := errors.New("broken pipe")
+	clickRepo.On("EachClick", "1234", "user", mock.Anything, mock.Anything).
+		Run(eachClick(&model.ClickStat{UrlID: "1234", CreatedAt: at})).
+		Return(writeErr).Once()
+	err = s.ExportClicks("1234", "user", &dto.StatsQuery{}, func(click *model.ClickStat) error { return writeErr })
+	assert.ErrorIs(t, err, writeErr)
+
+	clickRepo.On("EachClick", "notfound", "user", mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+	err = s.ExportClicks("notfound", "user", &dto.StatsQuery{}, func(click *model.ClickStat) error { return nil })
+	assert.ErrorIs(t, err, service.ErrUrlStatsNotFound)
+
+	err = s.ExportClicks("1234", "user", &dto.StatsQuery{Granularity: "year"}, func(click *model.ClickStat) error { return nil })
+	assert.ErrorIs(t, err, service.ErrValidation)
+}
+
+func TestClickStatService_ExportSeries(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("ByUserID", "user", mock.Anything).Return([]repo.DailyCount{{Count: 3}}, nil).Once()
+
+	series, err := clickstat.New(clickRepo, slog.Default()).ExportSeries("", "user", &dto.StatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []repo.DailyCount{{Count: 3}}, series)
+}
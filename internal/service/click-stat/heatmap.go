@@ -0,0 +1,74 @@
+package clickstat
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Heatmap returns clicks of the user's url in the range of the query by weekday and hour in the query's time zone
+func (s *ClickStatService) Heatmap(urlID, userID string, query *dto.StatsQuery) (*dto.Heatmap, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Heatmap"))
+
+	rng, err := statsRange(query, time.Now())
+	if err != nil {
+		log.Info("invalid heatmap query", sl.Err(err))
+		return nil, err
+	}
+
+	cells, err := s.repo.Heatmap(urlID, userID, rng)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return nil, service.ErrUrlStatsNotFound
+		}
+		log.Error("failed to get heatmap", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	heatmap := &dto.Heatmap{From: rng.From, To: rng.To, TZ: rng.Location.String()}
+	for _, cell := range cells {
+		heatmap.Clicks[cell.Weekday][cell.Hour] += cell.Count
+		heatmap.Total += cell.Count
+	}
+
+	log.Info("heatmap successfully received")
+	return heatmap, nil
+}
+
+// TopValues returns the most popular referrers and countries of the user's url in the range of the query.
+// Other users' urls have no values like in Breakdown
+func (s *ClickStatService) TopValues(urlID, userID string, query *dto.TopValuesQuery) (*dto.TopValues, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.TopValues"))
+
+	if err := service.Validate.Struct(query); err != nil {
+		log.Info("invalid top values query", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+	rng, err := statsRange(&query.StatsQuery, time.Now())
+	if err != nil {
+		log.Info("invalid top values query", sl.Err(err))
+		return nil, err
+	}
+
+	top := &dto.TopValues{From: rng.From, To: rng.To}
+	top.Referrers, err = s.repo.TopValues(urlID, userID, "referrer", rng, query.Top)
+	if err != nil {
+		log.Error("failed to get top referrers", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	top.Countries, err = s.repo.TopValues(urlID, userID, "country", rng, query.Top)
+	if err != nil {
+		log.Error("failed to get top countries", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("top values successfully received")
+	return top, nil
+}
@@ -0,0 +1,55 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClickStatService_Heatmap(t *testing.T) {
+	inTokyo := mock.MatchedBy(func(r repo.StatsRange) bool { return r.Location.String() == "Asia/Tokyo" })
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("Heatmap", "1234", "user", inTokyo).Return([]repo.HeatmapCell{{Weekday: 0, Hour: 9, Count: 3}, {Weekday: 6, Hour: 23, Count: 2}}, nil).Once()
+	clickRepo.On("Heatmap", "other", "user", mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	s := clickstat.New(clickRepo, slog.Default())
+	heatmap, err := s.Heatmap("1234", "user", &dto.StatsQuery{TZ: "Asia/Tokyo"})
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", heatmap.TZ)
+	assert.Equal(t, int64(5), heatmap.Total)
+	assert.Equal(t, int64(3), heatmap.Clicks[0][9])
+	assert.Equal(t, int64(2), heatmap.Clicks[6][23])
+
+	_, err = s.Heatmap("other", "user", &dto.StatsQuery{})
+	assert.ErrorIs(t, err, service.ErrUrlStatsNotFound)
+	_, err = s.Heatmap("1234", "user", &dto.StatsQuery{TZ: "Mars/Olympus"})
+	assert.ErrorIs(t, err, service.ErrValidation)
+}
+
+func TestClickStatService_TopValues(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("TopValues", "1234", "user", "referrer", mock.Anything, 5).Return([]repo.DimensionCount{{Value: "google.com", Count: 2}}, nil).Once()
+	clickRepo.On("TopValues", "1234", "user", "country", mock.Anything, 5).Return([]repo.DimensionCount{{Value: "JP", Count: 2}}, nil).Once()
+	clickRepo.On("TopValues", "broken", "user", "referrer", mock.Anything, 5).Return(nil, errors.New("unexpected")).Once()
+
+	s := clickstat.New(clickRepo, slog.Default())
+	top, err := s.TopValues("1234", "user", &dto.TopValuesQuery{Top: 5})
+	require.NoError(t, err)
+	assert.Equal(t, []repo.DimensionCount{{Value: "google.com", Count: 2}}, top.Referrers)
+	assert.Equal(t, []repo.DimensionCount{{Value: "JP", Count: 2}}, top.Countries)
+
+	_, err = s.TopValues("1234", "user", &dto.TopValuesQuery{Top: 101})
+	assert.ErrorIs(t, err, service.ErrValidation)
+	_, err = s.TopValues("broken", "user", &dto.TopValuesQuery{Top: 5})
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
@@ -3,11 +3,15 @@
 package mocks
 
 import (
-	model "url-shortener/internal/model"
+	hll "url-shortener/internal/lib/hll"
 
 	mock "github.com/stretchr/testify/mock"
 
+	model "url-shortener/internal/model"
+
 	repo "url-shortener/internal/database/repo"
+
+	time "time"
 )
 
 // ClickStatRepo is an autogenerated mock type for the ClickStatRepo type
@@ -15,9 +19,57 @@ type ClickStatRepo struct {
 	mock.Mock
 }
 
-// ByUrlID provides a mock function with given fields: urlID, userID
-func (_m *ClickStatRepo) ByUrlID(urlID string, userID string) ([]repo.DailyCount, error) {
-	ret := _m.Called(urlID, userID)
+// AddVisitors provides a mock function with given fields: urlID, day, sketch
+func (_m *ClickStatRepo) AddVisitors(urlID string, day time.Time, sketch *hll.Sketch) error {
+	ret := _m.Called(urlID, day, sketch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddVisitors")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, *hll.Sketch) error); ok {
+		r0 = rf(urlID, day, sketch)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Breakdown provides a mock function with given fields: urlID, userID, dimension, includeBots
+func (_m *ClickStatRepo) Breakdown(urlID string, userID string, dimension string, includeBots bool) ([]repo.DimensionCount, error) {
+	ret := _m.Called(urlID, userID, dimension, includeBots)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Breakdown")
+	}
+
+	var r0 []repo.DimensionCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string, bool) ([]repo.DimensionCount, error)); ok {
+		return rf(urlID, userID, dimension, includeBots)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string, bool) []repo.DimensionCount); ok {
+		r0 = rf(urlID, userID, dimension, includeBots)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.DimensionCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string, bool) error); ok {
+		r1 = rf(urlID, userID, dimension, includeBots)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ByUrlID provides a mock function with given fields: urlID, userID, rng
+func (_m *ClickStatRepo) ByUrlID(urlID string, userID string, rng repo.StatsRange) ([]repo.DailyCount, error) {
+	ret := _m.Called(urlID, userID, rng)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ByUrlID")
@@ -25,19 +77,19 @@ func (_m *ClickStatRepo) ByUrlID(urlID string, userID string) ([]repo.DailyCount
 
 	var r0 []repo.DailyCount
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) ([]repo.DailyCount, error)); ok {
-		return rf(urlID, userID)
+	if rf, ok := ret.Get(0).(func(string, string, repo.StatsRange) ([]repo.DailyCount, error)); ok {
+		return rf(urlID, userID, rng)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) []repo.DailyCount); ok {
-		r0 = rf(urlID, userID)
+	if rf, ok := ret.Get(0).(func(string, string, repo.StatsRange) []repo.DailyCount); ok {
+		r0 = rf(urlID, userID, rng)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]repo.DailyCount)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlID, userID)
+	if rf, ok := ret.Get(1).(func(string, string, repo.StatsRange) error); ok {
+		r1 = rf(urlID, userID, rng)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -45,17 +97,107 @@ func (_m *ClickStatRepo) ByUrlID(urlID string, userID string) ([]repo.DailyCount
 	return r0, r1
 }
 
-// CleanupStaleRecords provides a mock function with no fields
-func (_m *ClickStatRepo) CleanupStaleRecords() error {
+// ByUrlIDUnchecked provides a mock function with given fields: urlID, rng
+func (_m *ClickStatRepo) ByUrlIDUnchecked(urlID string, rng repo.StatsRange) ([]repo.DailyCount, error) {
+	ret := _m.Called(urlID, rng)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ByUrlIDUnchecked")
+	}
+
+	var r0 []repo.DailyCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange) ([]repo.DailyCount, error)); ok {
+		return rf(urlID, rng)
+	}
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange) []repo.DailyCount); ok {
+		r0 = rf(urlID, rng)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.DailyCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, repo.StatsRange) error); ok {
+		r1 = rf(urlID, rng)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ByUserID provides a mock function with given fields: userID, rng
+func (_m *ClickStatRepo) ByUserID(userID string, rng repo.StatsRange) ([]repo.DailyCount, error) {
+	ret := _m.Called(userID, rng)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ByUserID")
+	}
+
+	var r0 []repo.DailyCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange) ([]repo.DailyCount, error)); ok {
+		return rf(userID, rng)
+	}
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange) []repo.DailyCount); ok {
+		r0 = rf(userID, rng)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.DailyCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, repo.StatsRange) error); ok {
+		r1 = rf(userID, rng)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClickPartitions provides a mock function with no fields
+func (_m *ClickStatRepo) ClickPartitions() ([]repo.ClickPartition, error) {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for CleanupStaleRecords")
+		panic("no return value specified for ClickPartitions")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func() error); ok {
+	var r0 []repo.ClickPartition
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]repo.ClickPartition, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []repo.ClickPartition); ok {
 		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.ClickPartition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountConversions provides a mock function with given fields: clickIDs
+func (_m *ClickStatRepo) CountConversions(clickIDs []string) error {
+	ret := _m.Called(clickIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountConversions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(clickIDs)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -81,6 +223,434 @@ func (_m *ClickStatRepo) Create(ClickStat *model.ClickStat) error {
 	return r0
 }
 
+// CreateBatch provides a mock function with given fields: clicks
+func (_m *ClickStatRepo) CreateBatch(clicks []*model.ClickStat) error {
+	ret := _m.Called(clicks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]*model.ClickStat) error); ok {
+		r0 = rf(clicks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateConversion provides a mock function with given fields: conversion
+func (_m *ClickStatRepo) CreateConversion(conversion *model.Conversion) (bool, error) {
+	ret := _m.Called(conversion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateConversion")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*model.Conversion) (bool, error)); ok {
+		return rf(conversion)
+	}
+	if rf, ok := ret.Get(0).(func(*model.Conversion) bool); ok {
+		r0 = rf(conversion)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(*model.Conversion) error); ok {
+		r1 = rf(conversion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatedUrls provides a mock function with given fields: userID, from, to
+func (_m *ClickStatRepo) CreatedUrls(userID string, from time.Time, to time.Time) (int64, error) {
+	ret := _m.Called(userID, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatedUrls")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) (int64, error)); ok {
+		return rf(userID, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) int64); ok {
+		r0 = rf(userID, from, to)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
+		r1 = rf(userID, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DedupWindows provides a mock function with given fields: urlIDs
+func (_m *ClickStatRepo) DedupWindows(urlIDs []string) (map[string]time.Duration, error) {
+	ret := _m.Called(urlIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DedupWindows")
+	}
+
+	var r0 map[string]time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]string) (map[string]time.Duration, error)); ok {
+		return rf(urlIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]string) map[string]time.Duration); ok {
+		r0 = rf(urlIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]time.Duration)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(urlIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteStaleSketches provides a mock function with given fields: scope, limit
+func (_m *ClickStatRepo) DeleteStaleSketches(scope repo.RetentionScope, limit int) (int64, error) {
+	ret := _m.Called(scope, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteStaleSketches")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(repo.RetentionScope, int) (int64, error)); ok {
+		return rf(scope, limit)
+	}
+	if rf, ok := ret.Get(0).(func(repo.RetentionScope, int) int64); ok {
+		r0 = rf(scope, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(repo.RetentionScope, int) error); ok {
+		r1 = rf(scope, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DropClickPartition provides a mock function with given fields: partition
+func (_m *ClickStatRepo) DropClickPartition(partition repo.ClickPartition) (int64, error) {
+	ret := _m.Called(partition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DropClickPartition")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(repo.ClickPartition) (int64, error)); ok {
+		return rf(partition)
+	}
+	if rf, ok := ret.Get(0).(func(repo.ClickPartition) int64); ok {
+		r0 = rf(partition)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(repo.ClickPartition) error); ok {
+		r1 = rf(partition)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EachClick provides a mock function with given fields: urlID, userID, rng, each
+func (_m *ClickStatRepo) EachClick(urlID string, userID string, rng repo.StatsRange, each func(*model.ClickStat) error) error {
+	ret := _m.Called(urlID, userID, rng, each)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachClick")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, repo.StatsRange, func(*model.ClickStat) error) error); ok {
+		r0 = rf(urlID, userID, rng, each)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnsureClickPartitions provides a mock function with given fields: from, to
+func (_m *ClickStatRepo) EnsureClickPartitions(from time.Time, to time.Time) (int, error) {
+	ret := _m.Called(from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnsureClickPartitions")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) (int, error)); ok {
+		return rf(from, to)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) int); ok {
+		r0 = rf(from, to)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time) error); ok {
+		r1 = rf(from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Heatmap provides a mock function with given fields: urlID, userID, rng
+func (_m *ClickStatRepo) Heatmap(urlID string, userID string, rng repo.StatsRange) ([]repo.HeatmapCell, error) {
+	ret := _m.Called(urlID, userID, rng)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Heatmap")
+	}
+
+	var r0 []repo.HeatmapCell
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, repo.StatsRange) ([]repo.HeatmapCell, error)); ok {
+		return rf(urlID, userID, rng)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, repo.StatsRange) []repo.HeatmapCell); ok {
+		r0 = rf(urlID, userID, rng)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.HeatmapCell)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, repo.StatsRange) error); ok {
+		r1 = rf(urlID, userID, rng)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HitsDrift provides a mock function with given fields: limit
+func (_m *ClickStatRepo) HitsDrift(limit int) ([]repo.HitsDrift, int64, error) {
+	ret := _m.Called(limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HitsDrift")
+	}
+
+	var r0 []repo.HitsDrift
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(int) ([]repo.HitsDrift, int64, error)); ok {
+		return rf(limit)
+	}
+	if rf, ok := ret.Get(0).(func(int) []repo.HitsDrift); ok {
+		r0 = rf(limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.HitsDrift)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) int64); ok {
+		r1 = rf(limit)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(int) error); ok {
+		r2 = rf(limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RepairHits provides a mock function with given fields: afterID, limit
+func (_m *ClickStatRepo) RepairHits(afterID string, limit int) ([]repo.HitsDrift, string, error) {
+	ret := _m.Called(afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RepairHits")
+	}
+
+	var r0 []repo.HitsDrift
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]repo.HitsDrift, string, error)); ok {
+		return rf(afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) []repo.HitsDrift); ok {
+		r0 = rf(afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.HitsDrift)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) string); ok {
+		r1 = rf(afterID, limit)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(string, int) error); ok {
+		r2 = rf(afterID, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RollupStaleClicks provides a mock function with given fields: scope, limit
+func (_m *ClickStatRepo) RollupStaleClicks(scope repo.RetentionScope, limit int) (int64, error) {
+	ret := _m.Called(scope, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RollupStaleClicks")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(repo.RetentionScope, int) (int64, error)); ok {
+		return rf(scope, limit)
+	}
+	if rf, ok := ret.Get(0).(func(repo.RetentionScope, int) int64); ok {
+		r0 = rf(scope, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(repo.RetentionScope, int) error); ok {
+		r1 = rf(scope, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TopUrls provides a mock function with given fields: userID, rng, limit
+func (_m *ClickStatRepo) TopUrls(userID string, rng repo.StatsRange, limit int) ([]repo.UrlClicks, error) {
+	ret := _m.Called(userID, rng, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TopUrls")
+	}
+
+	var r0 []repo.UrlClicks
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange, int) ([]repo.UrlClicks, error)); ok {
+		return rf(userID, rng, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, repo.StatsRange, int) []repo.UrlClicks); ok {
+		r0 = rf(userID, rng, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.UrlClicks)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, repo.StatsRange, int) error); ok {
+		r1 = rf(userID, rng, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TopValues provides a mock function with given fields: urlID, userID, dimension, rng, limit
+func (_m *ClickStatRepo) TopValues(urlID string, userID string, dimension string, rng repo.StatsRange, limit int) ([]repo.DimensionCount, error) {
+	ret := _m.Called(urlID, userID, dimension, rng, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TopValues")
+	}
+
+	var r0 []repo.DimensionCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string, repo.StatsRange, int) ([]repo.DimensionCount, error)); ok {
+		return rf(urlID, userID, dimension, rng, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string, repo.StatsRange, int) []repo.DimensionCount); ok {
+		r0 = rf(urlID, userID, dimension, rng, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.DimensionCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string, repo.StatsRange, int) error); ok {
+		r1 = rf(urlID, userID, dimension, rng, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VisitorSketches provides a mock function with given fields: urlID, from, to
+func (_m *ClickStatRepo) VisitorSketches(urlID string, from time.Time, to time.Time) ([]model.VisitorSketch, error) {
+	ret := _m.Called(urlID, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VisitorSketches")
+	}
+
+	var r0 []model.VisitorSketch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) ([]model.VisitorSketch, error)); ok {
+		return rf(urlID, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) []model.VisitorSketch); ok {
+		r0 = rf(urlID, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.VisitorSketch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
+		r1 = rf(urlID, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewClickStatRepo creates a new instance of ClickStatRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClickStatRepo(t interface {
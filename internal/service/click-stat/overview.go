@@ -0,0 +1,82 @@
+package clickstat
+
+import (
+	"log/slog"
+	"math"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Overview sums stats of all the user's urls in the range of the query and compares them with the previous period
+func (s *ClickStatService) Overview(userID string, query *dto.OverviewQuery) (*dto.StatsOverview, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.Overview"))
+
+	if err := service.Validate.Struct(query); err != nil {
+		log.Info("invalid overview query", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+	rng, err := statsRange(&query.StatsQuery, time.Now())
+	if err != nil {
+		log.Info("invalid overview query", sl.Err(err))
+		return nil, err
+	}
+	previous := rng
+	previous.From, previous.To = rng.From.Add(-rng.To.Sub(rng.From)), rng.From
+
+	overview := &dto.StatsOverview{From: rng.From, To: rng.To}
+	overview.Series, err = s.repo.ByUserID(userID, rng)
+	if err != nil {
+		log.Error("failed to get series", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	previousSeries, err := s.repo.ByUserID(userID, previous)
+	if err != nil {
+		log.Error("failed to get series of the previous period", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	overview.TotalClicks, overview.PreviousTotalClicks = total(overview.Series), total(previousSeries)
+	overview.ClicksChange = change(overview.TotalClicks, overview.PreviousTotalClicks)
+
+	overview.TopUrls, err = s.repo.TopUrls(userID, rng, query.Top)
+	if err != nil {
+		log.Error("failed to get top urls", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	overview.NewUrls, err = s.repo.CreatedUrls(userID, rng.From, rng.To)
+	if err != nil {
+		log.Error("failed to count new urls", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	overview.PreviousNewUrls, err = s.repo.CreatedUrls(userID, previous.From, previous.To)
+	if err != nil {
+		log.Error("failed to count new urls of the previous period", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	overview.NewUrlsChange = change(overview.NewUrls, overview.PreviousNewUrls)
+
+	log.Info("overview successfully received")
+	return overview, nil
+}
+
+func total(series []repo.DailyCount) int64 {
+	var sum int64
+	for _, b := range series {
+		sum += b.Count
+	}
+	return sum
+}
+
+// change returns the change from the previous value in percents rounded to tenths, nil when there was nothing before
+func change(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	percent := math.Round(float64(current-previous)/float64(previous)*1000) / 10
+	return &percent
+}
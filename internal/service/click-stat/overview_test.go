@@ -0,0 +1,58 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStatService_Overview(t *testing.T) {
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	previousFrom := time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC)
+	current := mock.MatchedBy(func(r repo.StatsRange) bool { return r.From.Equal(from) && r.To.Equal(to) })
+	previous := mock.MatchedBy(func(r repo.StatsRange) bool { return r.From.Equal(previousFrom) && r.To.Equal(from) })
+	query := &dto.OverviewQuery{StatsQuery: dto.StatsQuery{From: "2025-07-01", To: "2025-07-10"}, Top: 5}
+
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("ByUserID", "user", current).Return([]repo.DailyCount{{Day: from, Count: 10}, {Day: from.AddDate(0, 0, 1), Count: 5}}, nil).Once()
+	clickRepo.On("ByUserID", "user", previous).Return([]repo.DailyCount{{Day: previousFrom, Count: 20}}, nil).Once()
+	clickRepo.On("TopUrls", "user", current, 5).Return([]repo.UrlClicks{{ID: "1234", Link: "https://google.com", Clicks: 15}}, nil).Once()
+	clickRepo.On("CreatedUrls", "user", from, to).Return(int64(2), nil).Once()
+	clickRepo.On("CreatedUrls", "user", previousFrom, from).Return(int64(0), nil).Once()
+
+	overview, err := clickstat.New(clickRepo, slog.Default()).Overview("user", query)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), overview.TotalClicks)
+	assert.Equal(t, int64(20), overview.PreviousTotalClicks)
+	require.NotNil(t, overview.ClicksChange)
+	assert.Equal(t, -25.0, *overview.ClicksChange)
+	assert.Equal(t, int64(2), overview.NewUrls)
+	// nothing to compare with
+	assert.Nil(t, overview.NewUrlsChange)
+	assert.Len(t, overview.Series, 2)
+	assert.Equal(t, "1234", overview.TopUrls[0].ID)
+}
+
+func TestClickStatService_OverviewErrors(t *testing.T) {
+	s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default())
+	_, err := s.Overview("user", &dto.OverviewQuery{Top: 101})
+	assert.ErrorIs(t, err, service.ErrValidation)
+	_, err = s.Overview("user", &dto.OverviewQuery{StatsQuery: dto.StatsQuery{TZ: "Mars/Olympus"}, Top: 10})
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("ByUserID", "user", mock.Anything).Return(nil, errors.New("unexpected")).Once()
+	_, err = clickstat.New(clickRepo, slog.Default()).Overview("user", &dto.OverviewQuery{Top: 10})
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
@@ -0,0 +1,77 @@
+package clickstat
+
+import (
+	"fmt"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/timebucket"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// defaultStatsPeriod is used when the query has no from
+	defaultStatsPeriod = 30 * 24 * time.Hour
+	// maxBuckets limits the length of the series
+	maxBuckets = 5000
+)
+
+// statsRange validates the query and resolves it into a range relative to now.
+// Date bounds are days in the query's time zone, the day of to is included
+func statsRange(query *dto.StatsQuery, now time.Time) (repo.StatsRange, error) {
+	if err := service.Validate.Struct(query); err != nil {
+		return repo.StatsRange{}, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+
+	rng := repo.StatsRange{Granularity: query.Granularity, Location: time.UTC, IncludeBots: query.IncludeBots}
+	if rng.Granularity == "" {
+		rng.Granularity = timebucket.Day
+	}
+	if query.TZ != "" {
+		loc, err := time.LoadLocation(query.TZ)
+		if err != nil || query.TZ == "Local" {
+			return repo.StatsRange{}, fmt.Errorf("%w%s", service.ErrValidation, "unknown time zone "+query.TZ)
+		}
+		rng.Location = loc
+	}
+
+	rng.To = timebucket.Next(timebucket.Truncate(now.In(rng.Location), rng.Granularity), rng.Granularity)
+	if query.To != "" {
+		to, dateOnly, err := parseBound(query.To, rng.Location)
+		if err != nil {
+			return repo.StatsRange{}, fmt.Errorf("%w%s", service.ErrValidation, "field to must be an RFC 3339 time or a date")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		rng.To = to
+	}
+
+	rng.From = timebucket.Truncate(rng.To.Add(-defaultStatsPeriod).In(rng.Location), rng.Granularity)
+	if query.From != "" {
+		from, _, err := parseBound(query.From, rng.Location)
+		if err != nil {
+			return repo.StatsRange{}, fmt.Errorf("%w%s", service.ErrValidation, "field from must be an RFC 3339 time or a date")
+		}
+		rng.From = from
+	}
+
+	if !rng.From.Before(rng.To) {
+		return repo.StatsRange{}, fmt.Errorf("%w%s", service.ErrValidation, "field from must be before to")
+	}
+	if timebucket.Count(rng.From.In(rng.Location), rng.To, rng.Granularity, maxBuckets) > maxBuckets {
+		return repo.StatsRange{}, fmt.Errorf("%w%s", service.ErrValidation, fmt.Sprintf("range must have at most %d buckets, use a coarser granularity", maxBuckets))
+	}
+
+	return rng, nil
+}
+
+func parseBound(value string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
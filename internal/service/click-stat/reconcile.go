@@ -0,0 +1,77 @@
+package clickstat
+
+import (
+	"log/slog"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"github.com/robfig/cron/v3"
+)
+
+// driftLimit is the number of drifted urls listed by HitsDrift and ReconcileHits
+const driftLimit = 100
+
+// repairBatch is the number of urls checked by one repair, so locks are held briefly
+const repairBatch = 1000
+
+// ReconcileHitsOnSchedule schedules ReconcileHits by the cron expression
+func (s *ClickStatService) ReconcileHitsOnSchedule(schedule string) (*cron.Cron, error) {
+	c := cron.New()
+
+	_, err := c.AddFunc(schedule, func() {
+		// the result is logged
+		s.ReconcileHits()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Start()
+
+	return c, nil
+}
+
+// HitsDrift finds urls whose total hits differ from their recorded clicks of humans without repairing them
+func (s *ClickStatService) HitsDrift() (*dto.HitsDrift, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.HitsDrift"))
+
+	urls, total, err := s.repo.HitsDrift(driftLimit)
+	if err != nil {
+		log.Error("failed to find drifted urls", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("drifted urls found", slog.Int64("drifted_urls", total))
+	return &dto.HitsDrift{DriftedUrls: total, Urls: urls}, nil
+}
+
+// ReconcileHits sets total hits of drifted urls to their baseline and recorded clicks of humans, so they match the stats.
+// Urls are repaired in batches
+func (s *ClickStatService) ReconcileHits() (*dto.HitsReconciliation, error) {
+	log := s.log.With(slog.String("op", "service.clickstat.ReconcileHits"))
+
+	var repaired []repo.HitsDrift
+	for afterID := ""; ; {
+		batch, next, err := s.repo.RepairHits(afterID, repairBatch)
+		if err != nil {
+			log.Error("failed to repair total hits", sl.Err(err), slog.Int("repaired_urls", len(repaired)))
+			return nil, service.ErrInternalError
+		}
+		repaired = append(repaired, batch...)
+
+		if next == "" {
+			break
+		}
+		afterID = next
+	}
+
+	if len(repaired) > 0 {
+		// drift means clicks are lost somewhere, so it's worth a look
+		log.Warn("total hits repaired", slog.Int("repaired_urls", len(repaired)))
+	} else {
+		log.Info("total hits match recorded clicks")
+	}
+	return &dto.HitsReconciliation{RepairedUrls: len(repaired), Urls: repaired[:min(len(repaired), driftLimit)]}, nil
+}
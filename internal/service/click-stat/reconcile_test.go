@@ -0,0 +1,51 @@
+package clickstat_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStatService_HitsDrift(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	clickRepo.On("HitsDrift", 100).Return([]repo.HitsDrift{{ID: "1234", TotalHits: 5, Clicks: 3}}, int64(1), nil).Once()
+	clickRepo.On("HitsDrift", 100).Return(nil, int64(0), errors.New("unexpected")).Once()
+
+	s := clickstat.New(clickRepo, slog.Default())
+
+	drift, err := s.HitsDrift()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), drift.DriftedUrls)
+	assert.Equal(t, []repo.HitsDrift{{ID: "1234", TotalHits: 5, Clicks: 3}}, drift.Urls)
+
+	_, err = s.HitsDrift()
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
+
+func TestClickStatService_ReconcileHits(t *testing.T) {
+	clickRepo := mocks.NewClickStatRepo(t)
+	// urls are repaired in batches until the last one
+	clickRepo.On("RepairHits", "", 1000).Return([]repo.HitsDrift{{ID: "1234", TotalHits: 5, Clicks: 3}}, "5678", nil).Once()
+	clickRepo.On("RepairHits", "5678", 1000).Return([]repo.HitsDrift{{ID: "9abc", TotalHits: 9, Baseline: 4, Clicks: 3}}, "", nil).Once()
+	clickRepo.On("RepairHits", "", 1000).Return(nil, "", errors.New("unexpected")).Once()
+
+	s := clickstat.New(clickRepo, slog.Default())
+
+	reconciliation, err := s.ReconcileHits()
+	require.NoError(t, err)
+	assert.Equal(t, 2, reconciliation.RepairedUrls)
+	assert.Equal(t, []repo.HitsDrift{{ID: "1234", TotalHits: 5, Clicks: 3}, {ID: "9abc", TotalHits: 9, Baseline: 4, Clicks: 3}}, reconciliation.Urls)
+
+	_, err = s.ReconcileHits()
+	assert.ErrorIs(t, err, service.ErrInternalError)
+
+	_, err = s.ReconcileHitsOnSchedule("invalid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package clickstat
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+)
+
+// ClickSink receives recorded clicks in batches. A sink rejecting a click of a url deleted after the redirect
+// returns service.ErrRelatedResourceNotFound, so the rest of the batch is written click by click
+//
+//go:generate mockery --name=ClickSink
+type ClickSink interface {
+	Write(clicks []*model.ClickStat) error
+}
+
+// PostgresSink saves clicks to click_stats. It's the only sink stats are read from
+type PostgresSink struct {
+	repo ClickStatRepo
+}
+
+func NewPostgresSink(repo ClickStatRepo) *PostgresSink {
+	return &PostgresSink{repo}
+}
+
+func (p *PostgresSink) Write(clicks []*model.ClickStat) error {
+	var err error
+	if len(clicks) == 1 {
+		err = p.repo.Create(clicks[0])
+	} else {
+		err = p.repo.CreateBatch(clicks)
+	}
+
+	if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23503" { // 23503 = foreign_key_violation
+		return fmt.Errorf("%w: %w", service.ErrRelatedResourceNotFound, err)
+	}
+	return err
+}
+
+// WithClickSinks replaces the default Postgres sink. Clicks are written to every sink, but they're recorded
+// when the primary sink saves them: the PostgresSink if there is one or the first sink otherwise.
+// Unique visitors are only counted when one of the sinks is a PostgresSink
+func WithClickSinks(sinks ...ClickSink) Option {
+	return func(s *ClickStatService) {
+		if len(sinks) > 0 {
+			s.sinks = sinks
+		}
+	}
+}
+
+// stores reports whether clicks are saved to Postgres, which keeps visitor sketches of the stats
+func (s *ClickStatService) stores() bool {
+	for _, sink := range s.sinks {
+		if _, ok := sink.(*PostgresSink); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// primarySink returns the index of the sink whose saved clicks are recorded, see WithClickSinks
+func (s *ClickStatService) primarySink() int {
+	for i, sink := range s.sinks {
+		if _, ok := sink.(*PostgresSink); ok {
+			return i
+		}
+	}
+	return 0
+}
+
+// SinkFailures returns the number of recorded clicks other sinks than the primary one failed to write
+func (s *ClickStatService) SinkFailures() uint64 {
+	return s.sinkFailures.Load()
+}
+
+// write passes the clicks to every sink and returns the ones saved by the primary sink with its error.
+// Stats, quotas and events follow the primary sink, so failures of the other sinks are only logged and counted.
+// A failed sink doesn't stop the others
+func (s *ClickStatService) write(clicks []*model.ClickStat) ([]*model.ClickStat, error) {
+	primary := s.primarySink()
+
+	var saved []*model.ClickStat
+	var primaryErr error
+	for i, sink := range s.sinks {
+		failed, err := writeTo(sink, clicks)
+		if i == primary {
+			saved = make([]*model.ClickStat, 0, len(clicks))
+			for _, click := range clicks {
+				if !failed[click] {
+					saved = append(saved, click)
+				}
+			}
+			primaryErr = err
+			continue
+		}
+
+		if len(failed) > 0 {
+			s.sinkFailures.Add(uint64(len(failed)))
+			s.log.Error("failed to write clicks to secondary sink", slog.String("op", "service.clickstat.write"),
+				sl.Err(err), slog.String("sink", fmt.Sprintf("%T", sink)), slog.Int("failed", len(failed)))
+		}
+	}
+	return saved, primaryErr
+}
+
+// writeTo writes the clicks to the sink and returns the clicks it failed to write
+func writeTo(sink ClickSink, clicks []*model.ClickStat) (map[*model.ClickStat]bool, error) {
+	failed := make(map[*model.ClickStat]bool)
+
+	err := sink.Write(clicks)
+	switch {
+	case errors.Is(err, service.ErrRelatedResourceNotFound) && len(clicks) > 1:
+		// a click of a deleted url fails the whole batch, so the clicks are written one by one
+		err = nil
+		for _, click := range clicks {
+			clickErr := sink.Write([]*model.ClickStat{click})
+			if clickErr == nil {
+				continue
+			}
+			failed[click] = true
+			if !errors.Is(clickErr, service.ErrRelatedResourceNotFound) {
+				err = clickErr
+			}
+		}
+	case err != nil:
+		for _, click := range clicks {
+			failed[click] = true
+		}
+	}
+	return failed, err
+}
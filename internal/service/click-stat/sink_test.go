@@ -0,0 +1,123 @@
+package clickstat_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/click-stat/mocks"
+	"url-shortener/internal/service/clicksink"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStatService_RecordToSinks(t *testing.T) {
+	visit := &dto.Visit{IP: "81.2.69.142", UserAgent: "curl/8.0"}
+
+	t.Run("postgres and memory", func(t *testing.T) {
+		repo := mocks.NewClickStatRepo(t)
+		repo.On("Create", mock.Anything).Return(nil).Once()
+		repo.On("AddVisitors", "1234", mock.Anything, mock.Anything).Return(nil).Once()
+		memory := clicksink.NewMemory(0)
+
+		s := clickstat.New(repo, slog.Default(), clickstat.WithClickSinks(clickstat.NewPostgresSink(repo), memory))
+		require.NoError(t, s.Record("1234", visit))
+		require.Len(t, memory.Clicks(), 1)
+		assert.Equal(t, "1234", memory.Clicks()[0].UrlID)
+	})
+
+	t.Run("memory only", func(t *testing.T) {
+		// neither clicks nor visitors are saved to Postgres
+		memory := clicksink.NewMemory(0)
+		s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithClickSinks(memory))
+		require.NoError(t, s.Record("1234", visit))
+		assert.Len(t, memory.Clicks(), 1)
+	})
+
+	t.Run("failed sink", func(t *testing.T) {
+		failing := mocks.NewClickSink(t)
+		failing.On("Write", mock.Anything).Return(errors.New("unexpected")).Once()
+		memory := clicksink.NewMemory(0)
+		publisher := mocks.NewClickPublisher(t)
+
+		// other sinks get the click, but it isn't published
+		s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithClickSinks(failing, memory), clickstat.WithClickPublisher(publisher))
+		assert.ErrorIs(t, s.Record("1234", visit), service.ErrInternalError)
+		assert.Len(t, memory.Clicks(), 1)
+	})
+
+	t.Run("failed secondary sink", func(t *testing.T) {
+		repo := mocks.NewClickStatRepo(t)
+		repo.On("Create", mock.Anything).Return(nil).Once()
+		repo.On("AddVisitors", "1234", mock.Anything, mock.Anything).Return(nil).Once()
+		failing := mocks.NewClickSink(t)
+		failing.On("Write", mock.Anything).Return(errors.New("unexpected")).Once()
+		publisher := mocks.NewClickPublisher(t)
+		publisher.On("Publish", mock.Anything).Return().Once()
+
+		// the click saved to Postgres is recorded, the failure of the other sink is only counted
+		s := clickstat.New(repo, slog.Default(), clickstat.WithClickSinks(failing, clickstat.NewPostgresSink(repo)), clickstat.WithClickPublisher(publisher))
+		require.NoError(t, s.Record("1234", visit))
+		assert.Equal(t, uint64(1), s.SinkFailures())
+	})
+}
+
+func TestBatchRecorder_SecondarySinkFailure(t *testing.T) {
+	repo := mocks.NewClickStatRepo(t)
+	repo.On("CreateBatch", mock.Anything).Return(nil).Once()
+	failing := mocks.NewClickSink(t)
+	failing.On("Write", mock.Anything).Return(errors.New("unexpected")).Once()
+	publisher := mocks.NewClickPublisher(t)
+	publisher.On("Publish", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 2 })).Return().Once()
+	quota := mocks.NewClickQuota(t)
+	quota.On("OverClickQuota", []string{"1234", "1234"}).Return(map[string]bool{}, nil).Once()
+	quota.On("TrackClicks", map[string]int64{"1234": 2}).Return(nil).Once()
+
+	s := clickstat.New(repo, slog.Default(),
+		clickstat.WithClickSinks(clickstat.NewPostgresSink(repo), failing),
+		clickstat.WithClickPublisher(publisher),
+		clickstat.WithClickQuota(quota),
+	)
+	r := clickstat.NewBatchRecorder(s, testQueue, slog.Default())
+	for range 2 {
+		require.NoError(t, r.Record("1234", nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+
+	metrics := r.Metrics()
+	assert.Equal(t, uint64(2), metrics.Recorded)
+	assert.Zero(t, metrics.Failed)
+	assert.Equal(t, uint64(2), metrics.SinkFailed)
+}
+
+func TestBatchRecorder_Sinks(t *testing.T) {
+	rejecting := mocks.NewClickSink(t)
+	// the batch is rejected because of the deleted url, the rest of it is written click by click
+	rejecting.On("Write", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 3 })).Return(service.ErrRelatedResourceNotFound).Once()
+	rejecting.On("Write", mock.MatchedBy(func(c []*model.ClickStat) bool { return c[0].UrlID == "deleted" })).Return(service.ErrRelatedResourceNotFound).Once()
+	rejecting.On("Write", mock.MatchedBy(func(c []*model.ClickStat) bool { return c[0].UrlID == "1234" })).Return(nil).Twice()
+	memory := clicksink.NewMemory(2)
+	publisher := mocks.NewClickPublisher(t)
+	publisher.On("Publish", mock.MatchedBy(func(c []*model.ClickStat) bool { return len(c) == 2 })).Return().Once()
+
+	s := clickstat.New(mocks.NewClickStatRepo(t), slog.Default(), clickstat.WithClickSinks(rejecting, memory), clickstat.WithClickPublisher(publisher))
+	r := clickstat.NewBatchRecorder(s, testQueue, slog.Default())
+	for _, urlID := range []string{"1234", "deleted", "1234"} {
+		require.NoError(t, r.Record(urlID, nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+
+	metrics := r.Metrics()
+	assert.Equal(t, uint64(2), metrics.Recorded)
+	assert.Equal(t, uint64(1), metrics.Failed)
+	// the memory sink keeps the latest clicks within its limit
+	clicks := memory.Clicks()
+	require.Len(t, clicks, 2)
+	assert.Equal(t, "deleted", clicks[0].UrlID)
+}
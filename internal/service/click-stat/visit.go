@@ -0,0 +1,137 @@
+package clickstat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/mssola/useragent"
+)
+
+// clickFromVisit parses the visit into click dimensions. The ip isn't copied, it's only used by the country locator.
+// HEAD and prefetch requests and user agents of known bots are classified as bots
+func clickFromVisit(urlID string, visit *dto.Visit) *model.ClickStat {
+	click := &model.ClickStat{UrlID: urlID}
+	if visit == nil {
+		return click
+	}
+
+	click.ReferrerHost = referrerHost(visit.Referrer)
+	click.Language = primaryLanguage(visit.AcceptLanguage)
+
+	if visit.UserAgent != "" {
+		ua := useragent.New(visit.UserAgent)
+		browser, _ := ua.Browser()
+		click.Browser = truncate(browser, 64)
+		click.OS = truncate(ua.OSInfo().Name, 64)
+		click.Device = deviceClass(ua, visit.UserAgent)
+	}
+	click.Bot = click.Device == model.DeviceBot || visit.Method == http.MethodHead || isPrefetch(visit.Purpose)
+
+	return click
+}
+
+// isPrefetch checks values of Purpose, Sec-Purpose and X-Moz headers, e.g. "prefetch" or "prefetch;prerender"
+func isPrefetch(purpose string) bool {
+	purpose = strings.ToLower(purpose)
+	return strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "preview")
+}
+
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return truncate(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), 255)
+}
+
+// primaryLanguage returns the primary subtag of the most preferred language, e.g. "en" for "en-US,en;q=0.9"
+func primaryLanguage(acceptLanguage string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), "-")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return truncate(strings.ToLower(best), 16)
+}
+
+func deviceClass(ua *useragent.UserAgent, raw string) string {
+	switch {
+	case ua.Bot():
+		return model.DeviceBot
+	case strings.Contains(raw, "iPad") || strings.Contains(raw, "Tablet") ||
+		(strings.Contains(raw, "Android") && !strings.Contains(raw, "Mobile")):
+		return model.DeviceTablet
+	case ua.Mobile():
+		return model.DeviceMobile
+	default:
+		return model.DeviceDesktop
+	}
+}
+
+// visitIP parses the visitor ip, nil means it's unknown
+func visitIP(visit *dto.Visit) net.IP {
+	if visit == nil {
+		return nil
+	}
+	return net.ParseIP(visit.IP)
+}
+
+// truncate cuts the string to n characters like varchar columns do. Invalid UTF-8 is dropped, Postgres rejects it
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// visitorHash identifies the visitor during the day by ip and user agent. The hash is keyed by the secret and the day,
+// so it can't be reversed to the ip and the same visitor can't be linked across days
+func visitorHash(secret []byte, day time.Time, visit *dto.Visit) uint64 {
+	if visit == nil || (visit.IP == "" && visit.UserAgent == "") {
+		return 0
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(day.Format(time.DateOnly)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visit.IP))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visit.UserAgent))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// day returns the calendar day of the time as stored in date columns
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
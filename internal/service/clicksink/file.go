@@ -0,0 +1,148 @@
+package clicksink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+)
+
+const partSuffix = ".part"
+
+var defaultFile = config.FileSink{Dir: "data/clicks", MaxSize: 64 << 20, MaxAge: time.Hour}
+
+// File writes clicks as NDJSON lines to files rotated by size and age. Completed files are renamed from the .part suffix,
+// so a data pipeline picking up *.ndjson files never reads a file being written
+type File struct {
+	cfg config.FileSink
+	log *slog.Logger
+
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	size   int64
+	timer  *time.Timer
+	closed bool
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewFile creates the directory and completes files left by a previous run
+func NewFile(cfg config.FileSink, log *slog.Logger) (*File, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = defaultFile.Dir
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultFile.MaxSize
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultFile.MaxAge
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create click sink directory: %w", err)
+	}
+	parts, err := filepath.Glob(filepath.Join(cfg.Dir, "*"+partSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if err := os.Rename(part, strings.TrimSuffix(part, partSuffix)); err != nil {
+			return nil, fmt.Errorf("failed to complete click file: %w", err)
+		}
+	}
+
+	return &File{cfg: cfg, log: log, now: time.Now}, nil
+}
+
+// Write appends the clicks to the current file and rotates it when it reaches the max size
+func (f *File) Write(clicks []*model.ClickStat) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, click := range clicks {
+		line, err := json.Marshal(dto.ToClickEvent(click))
+		if err != nil {
+			return err
+		}
+		n, err := f.buf.Write(append(line, '\n'))
+		f.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	// the batch is on disk when the write returns, so a crash doesn't lose recorded clicks
+	if err := f.buf.Flush(); err != nil {
+		return err
+	}
+
+	if f.size >= f.cfg.MaxSize {
+		return f.complete()
+	}
+	return nil
+}
+
+// Close completes the current file. Clicks can't be written after it
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	return f.complete()
+}
+
+// open starts a new file named by its UTC start time. The file is completed by the timer when it reaches the max age
+func (f *File) open() error {
+	name := filepath.Join(f.cfg.Dir, "clicks-"+f.now().UTC().Format("20060102T150405.000000000")+".ndjson"+partSuffix)
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	f.file, f.buf, f.size = file, bufio.NewWriter(file), 0
+	f.timer = time.AfterFunc(f.cfg.MaxAge, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// the file could be completed by size and another one opened meanwhile
+		if f.file != file {
+			return
+		}
+		if err := f.complete(); err != nil {
+			f.log.Error("failed to complete click file", slog.String("op", "service.clicksink.File"), sl.Err(err))
+		}
+	})
+	return nil
+}
+
+// complete closes the current file and removes its .part suffix
+func (f *File) complete() error {
+	if f.file == nil {
+		return nil
+	}
+	file := f.file
+	f.file, f.buf = nil, nil
+	f.timer.Stop()
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), strings.TrimSuffix(file.Name(), partSuffix))
+}
@@ -0,0 +1,79 @@
+package clicksink
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Write(t *testing.T) {
+	dir := t.TempDir()
+	// a file left by a crash is completed on start
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "clicks-old.ndjson.part"), []byte("{}\n"), 0o644))
+
+	f, err := NewFile(config.FileSink{Dir: dir, MaxSize: 200, MaxAge: time.Hour}, slog.Default())
+	require.NoError(t, err)
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	click := &model.ClickStat{UrlID: "1234", CreatedAt: now, Browser: "Firefox", Duplicate: true}
+	require.NoError(t, f.Write([]*model.ClickStat{click}))
+	parts, err := filepath.Glob(filepath.Join(dir, "*.part"))
+	require.NoError(t, err)
+	assert.Len(t, parts, 1)
+
+	// the file is completed when it reaches the max size, the next clicks go to a new one
+	require.NoError(t, f.Write([]*model.ClickStat{click, click}))
+	now = now.Add(time.Second)
+	require.NoError(t, f.Write([]*model.ClickStat{click}))
+	require.NoError(t, f.Close())
+	assert.ErrorIs(t, f.Write([]*model.ClickStat{click}), os.ErrClosed)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	parts, err = filepath.Glob(filepath.Join(dir, "*.part"))
+	require.NoError(t, err)
+	assert.Empty(t, parts)
+
+	// names sort by the start time, the completed old file is the last one
+	var events []dto.ClickEvent
+	for _, name := range files[:2] {
+		file, err := os.Open(name)
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event dto.ClickEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			events = append(events, event)
+		}
+		file.Close()
+	}
+	require.Len(t, events, 4)
+	assert.Equal(t, dto.ToClickEvent(click), events[0])
+}
+
+func TestFile_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(config.FileSink{Dir: dir, MaxSize: 1 << 20, MaxAge: 10 * time.Millisecond}, slog.Default())
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, f.Write([]*model.ClickStat{{UrlID: "1234", CreatedAt: time.Now().UTC()}}))
+
+	// idle files are completed too
+	assert.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+		return len(files) == 1
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,47 @@
+package clicksink
+
+import (
+	"sync"
+	"url-shortener/internal/model"
+)
+
+// Memory keeps written clicks in memory, e.g. for tests or debugging. Only the latest clicks are kept when it's limited
+type Memory struct {
+	mu     sync.Mutex
+	limit  int
+	clicks []model.ClickStat
+}
+
+// NewMemory returns the sink keeping at most limit clicks, 0 keeps all of them
+func NewMemory(limit int) *Memory {
+	return &Memory{limit: max(limit, 0)}
+}
+
+func (m *Memory) Write(clicks []*model.ClickStat) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, click := range clicks {
+		m.clicks = append(m.clicks, *click)
+	}
+	if m.limit > 0 && len(m.clicks) > m.limit {
+		m.clicks = append(m.clicks[:0], m.clicks[len(m.clicks)-m.limit:]...)
+	}
+	return nil
+}
+
+// Clicks returns copies of the kept clicks from the oldest one
+func (m *Memory) Clicks() []model.ClickStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]model.ClickStat(nil), m.clicks...)
+}
+
+// Reset forgets the kept clicks
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clicks = nil
+}
@@ -0,0 +1,25 @@
+package clicksink
+
+import (
+	"testing"
+	"url-shortener/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(2)
+	click := &model.ClickStat{UrlID: "third"}
+	require.NoError(t, m.Write([]*model.ClickStat{{UrlID: "first"}, {UrlID: "second"}, click}))
+
+	// only the latest clicks are kept, they're copies of the written ones
+	click.UrlID = "changed"
+	clicks := m.Clicks()
+	require.Len(t, clicks, 2)
+	assert.Equal(t, "second", clicks[0].UrlID)
+	assert.Equal(t, "third", clicks[1].UrlID)
+
+	m.Reset()
+	assert.Empty(t, m.Clicks())
+}
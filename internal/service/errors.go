@@ -16,9 +16,25 @@ var (
 	ErrUserNotFound = NewError(http.StatusNotFound, "user not found")
 	ErrEmailTaken   = NewError(http.StatusConflict, "email's already taken")
 	// url
-	ErrUrlNotFound      = NewError(http.StatusNotFound, "url not found")
-	ErrAliasTaken       = NewError(http.StatusConflict, "this alias is already taken")
-	ErrUrlStatsNotFound = NewError(http.StatusNotFound, "url statistics not found")
+	ErrUrlNotFound        = NewError(http.StatusNotFound, "url not found")
+	ErrAliasTaken         = NewError(http.StatusConflict, "this alias is already taken")
+	ErrUrlStatsNotFound   = NewError(http.StatusNotFound, "url statistics not found")
+	ErrUrlHistoryNotFound = NewError(http.StatusNotFound, "url history not found")
+	ErrInvalidClaimToken  = NewError(http.StatusForbidden, "invalid claim token")
+	// plan
+	ErrLinkQuotaExceeded  = NewError(http.StatusForbidden, "url limit of your plan is reached")
+	ErrAliasQuotaExceeded = NewError(http.StatusForbidden, "custom alias limit of your plan is reached")
+	ErrClickQuotaExceeded = NewError(http.StatusTooManyRequests, "monthly click limit of your plan is reached")
+	// transfer
+	ErrTransferNotFound     = NewError(http.StatusNotFound, "transfer not found")
+	ErrTransferToSelf       = NewError(http.StatusBadRequest, "can't transfer urls to yourself")
+	ErrTransferUrlsNotOwned = NewError(http.StatusConflict, "some urls are not owned by the sender")
+	// share
+	ErrShareNotFound       = NewError(http.StatusNotFound, "share not found")
+	ErrBreakdownsNotShared = NewError(http.StatusForbidden, "breakdowns aren't shared")
+	// webhook
+	ErrWebhookNotFound         = NewError(http.StatusNotFound, "webhook not found")
+	ErrWebhookDeliveryNotFound = NewError(http.StatusNotFound, "webhook delivery not found")
 	// common
 	ErrInternalError           = NewError(http.StatusInternalServerError, "internal server error")
 	ErrValidation              = NewError(http.StatusBadRequest, "")
@@ -0,0 +1,239 @@
+package live
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pubsub"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+)
+
+const (
+	// maxNotifyPayload keeps notification payloads under the 8000 bytes limit of Postgres
+	maxNotifyPayload = 7900
+	maxListenBackoff = 30 * time.Second
+)
+
+//go:generate mockery --name=LiveRepo
+type LiveRepo interface {
+	Owners(urlIDs []string) (map[string]string, error)
+	IsOwner(urlID, userID string) (bool, error)
+	Notify(channel, payload string) error
+	Listen(ctx context.Context, channel string, handle func(payload string)) error
+}
+
+// ownedClick is a click with the owner of its url, who gets it in the account-wide stream
+type ownedClick struct {
+	UserID string        `json:"userId"`
+	Click  dto.LiveClick `json:"click"`
+}
+
+type notification struct {
+	// Instance skips clicks the instance has already delivered itself
+	Instance string       `json:"instance"`
+	Clicks   []ownedClick `json:"clicks"`
+}
+
+// LiveService streams recorded clicks to owners of their urls. Clicks are delivered to the instance's subscribers
+// right away and to subscribers of other instances through Postgres notifications
+type LiveService struct {
+	repo     LiveRepo
+	log      *slog.Logger
+	cfg      config.Live
+	hub      *pubsub.Hub[dto.LiveClick]
+	instance string
+}
+
+var defaultLive = config.Live{Channel: "live_clicks", Buffer: 100, Heartbeat: 15 * time.Second}
+
+func New(repo LiveRepo, cfg config.Live, log *slog.Logger) *LiveService {
+	if cfg.Channel == "" {
+		cfg.Channel = defaultLive.Channel
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = defaultLive.Buffer
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = defaultLive.Heartbeat
+	}
+
+	instance := make([]byte, 8)
+	rand.Read(instance)
+	return &LiveService{repo: repo, log: log, cfg: cfg, hub: pubsub.New[dto.LiveClick](), instance: hex.EncodeToString(instance)}
+}
+
+// Publish streams the recorded clicks. Clicks of anonymous urls have no owner to stream to
+func (s *LiveService) Publish(clicks []*model.ClickStat) {
+	log := s.log.With(slog.String("op", "service.live.Publish"))
+
+	urlIDs := make([]string, 0, len(clicks))
+	for _, click := range clicks {
+		urlIDs = append(urlIDs, click.UrlID)
+	}
+	owners, err := s.repo.Owners(urlIDs)
+	if err != nil {
+		log.Error("failed to get owners of urls", sl.Err(err))
+		return
+	}
+
+	owned := make([]ownedClick, 0, len(clicks))
+	for _, click := range clicks {
+		if userID, ok := owners[click.UrlID]; ok {
+			owned = append(owned, ownedClick{UserID: userID, Click: dto.ToLiveClick(click)})
+		}
+	}
+	s.deliver(owned)
+
+	payloads, err := s.notifications(owned)
+	if err != nil {
+		log.Error("failed to encode notification", sl.Err(err))
+		return
+	}
+	for _, payload := range payloads {
+		if err := s.repo.Notify(s.cfg.Channel, payload); err != nil {
+			// other instances miss the clicks of the payload
+			log.Error("failed to notify", sl.Err(err))
+		}
+	}
+}
+
+// notifications splits the clicks into payloads under maxNotifyPayload by their encoded size. JSON escaping makes
+// dimensions up to 6 times longer, so a click too large for a payload is sent without its referrer, browser and os
+func (s *LiveService) notifications(clicks []ownedClick) ([]string, error) {
+	empty, err := json.Marshal(notification{Instance: s.instance, Clicks: []ownedClick{}})
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads []string
+	var chunk []ownedClick
+	size := len(empty)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		payload, err := json.Marshal(notification{Instance: s.instance, Clicks: chunk})
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, string(payload))
+		chunk, size = nil, len(empty)
+		return nil
+	}
+
+	for _, click := range clicks {
+		encoded, err := json.Marshal(click)
+		if err != nil {
+			return nil, err
+		}
+		if len(empty)+len(encoded) > maxNotifyPayload {
+			click.Click.Referrer, click.Click.Browser, click.Click.OS = "", "", ""
+			if encoded, err = json.Marshal(click); err != nil {
+				return nil, err
+			}
+		}
+
+		// clicks are separated by commas
+		if size+len(encoded)+1 > maxNotifyPayload {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		chunk = append(chunk, click)
+		size += len(encoded) + 1
+	}
+	return payloads, flush()
+}
+
+// Subscribe returns the stream of clicks of the user's url or, when the url id is empty, of all the user's urls
+// and the function that closes the stream
+func (s *LiveService) Subscribe(urlID, userID string) (<-chan dto.LiveClick, func(), error) {
+	log := s.log.With(slog.String("op", "service.live.Subscribe"))
+
+	if urlID == "" {
+		clicks, unsubscribe := s.hub.Subscribe(userTopic(userID), s.cfg.Buffer)
+		return clicks, unsubscribe, nil
+	}
+
+	owner, err := s.repo.IsOwner(urlID, userID)
+	if err != nil {
+		log.Error("failed to check url owner", sl.Err(err))
+		return nil, nil, service.ErrInternalError
+	}
+	if !owner {
+		log.Info("url not found")
+		return nil, nil, service.ErrUrlNotFound
+	}
+
+	clicks, unsubscribe := s.hub.Subscribe(urlTopic(urlID), s.cfg.Buffer)
+	return clicks, unsubscribe, nil
+}
+
+// Heartbeat is the interval of keep-alive messages of idle streams
+func (s *LiveService) Heartbeat() time.Duration {
+	return s.cfg.Heartbeat
+}
+
+// Close ends all the streams, e.g. on shutdown
+func (s *LiveService) Close() {
+	s.hub.Close()
+}
+
+// Listen delivers clicks recorded by other instances until the context is done. The connection is reopened after failures
+func (s *LiveService) Listen(ctx context.Context) {
+	log := s.log.With(slog.String("op", "service.live.Listen"))
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := s.repo.Listen(ctx, s.cfg.Channel, s.receive)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxListenBackoff {
+			backoff = time.Second
+		}
+		log.Error("failed to listen for clicks of other instances", sl.Err(err), slog.Duration("retry_in", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxListenBackoff)
+	}
+}
+
+func (s *LiveService) receive(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		s.log.Warn("invalid notification", slog.String("op", "service.live.receive"), sl.Err(err))
+		return
+	}
+	if n.Instance == s.instance {
+		return
+	}
+	s.deliver(n.Clicks)
+}
+
+func (s *LiveService) deliver(clicks []ownedClick) {
+	for _, c := range clicks {
+		s.hub.Publish(urlTopic(c.Click.Alias), c.Click)
+		s.hub.Publish(userTopic(c.UserID), c.Click)
+	}
+}
+
+func urlTopic(urlID string) string {
+	return "url:" + urlID
+}
+
+func userTopic(userID string) string {
+	return "user:" + userID
+}
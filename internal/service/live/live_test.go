@@ -0,0 +1,133 @@
+package live_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/service/live"
+	"url-shortener/internal/service/live/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveService_Publish(t *testing.T) {
+	repo := mocks.NewLiveRepo(t)
+	repo.On("IsOwner", "1234", "user").Return(true, nil).Once()
+	repo.On("Owners", []string{"1234", "anonymous"}).Return(map[string]string{"1234": "user"}, nil).Once()
+	var payload string
+	repo.On("Notify", "live_clicks", mock.Anything).Run(func(args mock.Arguments) { payload = args.String(1) }).Return(nil).Once()
+
+	s := live.New(repo, config.Live{}, slog.Default())
+	urlClicks, unsubscribeUrl, err := s.Subscribe("1234", "user")
+	require.NoError(t, err)
+	defer unsubscribeUrl()
+	userClicks, unsubscribeUser, err := s.Subscribe("", "user")
+	require.NoError(t, err)
+	defer unsubscribeUser()
+
+	at := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	s.Publish([]*model.ClickStat{{UrlID: "1234", CreatedAt: at, Browser: "Firefox"}, {UrlID: "anonymous", CreatedAt: at}})
+
+	want := dto.LiveClick{Alias: "1234", CreatedAt: at, Browser: "Firefox"}
+	assert.Equal(t, want, <-urlClicks)
+	assert.Equal(t, want, <-userClicks)
+	// clicks of anonymous urls aren't streamed
+	assert.Empty(t, urlClicks)
+	assert.Empty(t, userClicks)
+
+	// other instances get only owned clicks
+	var n struct {
+		Clicks []json.RawMessage `json:"clicks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(payload), &n))
+	assert.Len(t, n.Clicks, 1)
+}
+
+func TestLiveService_PublishLargeClicks(t *testing.T) {
+	repo := mocks.NewLiveRepo(t)
+	var payloads []string
+	repo.On("Notify", "live_clicks", mock.Anything).Run(func(args mock.Arguments) { payloads = append(payloads, args.String(1)) }).Return(nil)
+
+	// escaping makes every character of the referrer 6 bytes long
+	at := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	clicks := []*model.ClickStat{{UrlID: "huge", CreatedAt: at, ReferrerHost: strings.Repeat("<", 2000), Browser: "Firefox"}}
+	urlIDs := []string{"huge"}
+	for range 30 {
+		clicks = append(clicks, &model.ClickStat{UrlID: "1234", CreatedAt: at, ReferrerHost: strings.Repeat("<", 255), Browser: "Firefox"})
+		urlIDs = append(urlIDs, "1234")
+	}
+	repo.On("Owners", urlIDs).Return(map[string]string{"1234": "user", "huge": "user"}, nil).Once()
+
+	s := live.New(repo, config.Live{}, slog.Default())
+	s.Publish(clicks)
+
+	var received []dto.LiveClick
+	for _, payload := range payloads {
+		assert.Less(t, len(payload), 8000)
+
+		var n struct {
+			Clicks []struct {
+				Click dto.LiveClick `json:"click"`
+			} `json:"clicks"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(payload), &n))
+		for _, c := range n.Clicks {
+			received = append(received, c.Click)
+		}
+	}
+	require.Len(t, received, 31)
+	assert.Greater(t, len(payloads), 1)
+	// the click too large for a payload loses its long dimensions
+	assert.Equal(t, dto.LiveClick{Alias: "huge", CreatedAt: at}, received[0])
+	assert.Equal(t, strings.Repeat("<", 255), received[1].Referrer)
+}
+
+func TestLiveService_Listen(t *testing.T) {
+	at := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	payload := `{"instance":"peer","clicks":[{"userId":"user","click":{"alias":"1234","createdAt":"2025-07-01T12:00:00Z"}}]}`
+
+	repo := mocks.NewLiveRepo(t)
+	s := live.New(repo, config.Live{}, slog.Default())
+	userClicks, unsubscribe, err := s.Subscribe("", "user")
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("Listen", mock.Anything, "live_clicks", mock.Anything).Run(func(args mock.Arguments) {
+		handle := args.Get(2).(func(payload string))
+		handle(payload)
+		handle("invalid")
+		cancel()
+	}).Return(context.Canceled).Once()
+
+	// returns when the context is done
+	s.Listen(ctx)
+
+	assert.Equal(t, dto.LiveClick{Alias: "1234", CreatedAt: at}, <-userClicks)
+	// streams are closed with the service
+	s.Close()
+	_, ok := <-userClicks
+	assert.False(t, ok)
+}
+
+func TestLiveService_Subscribe(t *testing.T) {
+	repo := mocks.NewLiveRepo(t)
+	repo.On("IsOwner", "5678", "user").Return(false, nil).Once()
+	repo.On("IsOwner", "1234", "user").Return(false, errors.New("unexpected")).Once()
+
+	s := live.New(repo, config.Live{}, slog.Default())
+	_, _, err := s.Subscribe("5678", "user")
+	assert.ErrorIs(t, err, service.ErrUrlNotFound)
+	_, _, err = s.Subscribe("1234", "user")
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
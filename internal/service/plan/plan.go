@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"errors"
+	"log/slog"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name=UsageRepo
+type UsageRepo interface {
+	ByUserID(userID string) (*repo.Usage, error)
+	ClickUsages(urlIDs []string) ([]repo.ClickUsage, error)
+	AddClicks(counts map[string]int64) error
+}
+
+type PlanService struct {
+	repo  UsageRepo
+	plans config.Plans
+	log   *slog.Logger
+}
+
+func New(repo UsageRepo, plans config.Plans, log *slog.Logger) *PlanService {
+	return &PlanService{repo, plans, log}
+}
+
+// Plan returns the plan's name and limits. Unknown and empty names fall back to the default plan
+func (s *PlanService) Plan(name string) (string, config.Plan) {
+	if plan, ok := s.plans.Tiers[name]; ok {
+		return name, plan
+	}
+	return s.plans.Default, s.plans.Tiers[s.plans.Default]
+}
+
+func (s *PlanService) Usage(userID string) (*dto.PlanUsage, error) {
+	log := s.log.With(slog.String("op", "service.plan.Usage"))
+
+	usage, err := s.repo.ByUserID(userID)
+	if err != nil {
+		log.Error("failed to get usage", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrUserNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+
+	name, plan := s.Plan(usage.Plan)
+
+	log.Info("got usage successfully")
+	return &dto.PlanUsage{
+		Plan:          name,
+		Links:         dto.UsageLimit{Used: usage.Links, Limit: plan.MaxLinks},
+		Aliases:       dto.UsageLimit{Used: usage.Aliases, Limit: plan.MaxAliases},
+		MonthlyClicks: dto.UsageLimit{Used: usage.MonthlyClicks, Limit: plan.MaxMonthlyClicks},
+	}, nil
+}
+
+// LinkLimits returns the url limits of the user's plan. Urls created for or moved to the user are checked against them
+func (s *PlanService) LinkLimits(userID string) (repo.LinkLimits, error) {
+	log := s.log.With(slog.String("op", "service.plan.LinkLimits"))
+
+	usage, err := s.repo.ByUserID(userID)
+	if err != nil {
+		log.Error("failed to get usage", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return repo.LinkLimits{}, service.ErrRelatedResourceNotFound
+		}
+		return repo.LinkLimits{}, service.ErrInternalError
+	}
+
+	_, plan := s.Plan(usage.Plan)
+	return repo.LinkLimits{MaxLinks: plan.MaxLinks, MaxAliases: plan.MaxAliases}, nil
+}
+
+// OverClickQuota returns the urls whose owners used up the monthly clicks of their plans. Anonymous urls aren't limited
+func (s *PlanService) OverClickQuota(urlIDs []string) (map[string]bool, error) {
+	log := s.log.With(slog.String("op", "service.plan.OverClickQuota"))
+
+	usages, err := s.repo.ClickUsages(urlIDs)
+	if err != nil {
+		log.Error("failed to get click usage", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	over := make(map[string]bool)
+	for _, usage := range usages {
+		_, plan := s.Plan(usage.Plan)
+		if plan.MaxMonthlyClicks > 0 && usage.MonthlyClicks >= plan.MaxMonthlyClicks {
+			log.Info("click quota exceeded", slog.String("user_id", usage.UserID))
+			over[usage.UrlID] = true
+		}
+	}
+
+	return over, nil
+}
+
+// TrackClicks counts saved clicks of the urls for their owners, the counts are keyed by url ids
+func (s *PlanService) TrackClicks(counts map[string]int64) error {
+	log := s.log.With(slog.String("op", "service.plan.TrackClicks"))
+
+	if err := s.repo.AddClicks(counts); err != nil {
+		log.Error("failed to count clicks", sl.Err(err))
+		return service.ErrInternalError
+	}
+
+	return nil
+}
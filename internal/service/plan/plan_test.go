@@ -0,0 +1,98 @@
+package plan_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/service"
+	"url-shortener/internal/service/plan"
+	"url-shortener/internal/service/plan/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var plans = config.Plans{
+	Default: "free",
+	Tiers: map[string]config.Plan{
+		"free": {MaxLinks: 2, MaxAliases: 1, MaxMonthlyClicks: 10},
+		"team": {MaxLinks: 100},
+	},
+}
+
+func TestPlanService_Plan(t *testing.T) {
+	s := plan.New(mocks.NewUsageRepo(t), plans, slog.Default())
+
+	name, p := s.Plan("team")
+	assert.Equal(t, "team", name)
+	assert.Equal(t, int64(100), p.MaxLinks)
+
+	name, p = s.Plan("")
+	assert.Equal(t, "free", name)
+	assert.Equal(t, int64(2), p.MaxLinks)
+
+	name, _ = s.Plan("unknown")
+	assert.Equal(t, "free", name)
+}
+
+func TestPlanService_OverClickQuota(t *testing.T) {
+	r := mocks.NewUsageRepo(t)
+	// the free plan has 10 monthly clicks, the team plan has no limit
+	r.On("ClickUsages", []string{"full", "free", "team"}).Return([]repo.ClickUsage{
+		{UrlID: "full", UserID: "1", MonthlyClicks: 10},
+		{UrlID: "free", UserID: "2", MonthlyClicks: 9},
+		{UrlID: "team", UserID: "3", Plan: "team", MonthlyClicks: 1000},
+	}, nil).Once()
+	r.On("ClickUsages", []string{"alias"}).Return(nil, errors.New("unexpected")).Once()
+
+	s := plan.New(r, plans, slog.Default())
+
+	over, err := s.OverClickQuota([]string{"full", "free", "team"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"full": true}, over)
+
+	_, err = s.OverClickQuota([]string{"alias"})
+	assert.ErrorIs(t, err, service.ErrInternalError)
+}
+
+func TestPlanService_TrackClicks(t *testing.T) {
+	r := mocks.NewUsageRepo(t)
+	r.On("AddClicks", map[string]int64{"alias": 3}).Return(nil).Once()
+	r.On("AddClicks", map[string]int64{"alias": 1}).Return(errors.New("unexpected")).Once()
+
+	s := plan.New(r, plans, slog.Default())
+
+	assert.NoError(t, s.TrackClicks(map[string]int64{"alias": 3}))
+	assert.ErrorIs(t, s.TrackClicks(map[string]int64{"alias": 1}), service.ErrInternalError)
+}
+
+func TestPlanService_Usage(t *testing.T) {
+	r := mocks.NewUsageRepo(t)
+	r.On("ByUserID", "1234").Return(&repo.Usage{Links: 2, Aliases: 1, MonthlyClicks: 7}, nil).Once()
+
+	s := plan.New(r, plans, slog.Default())
+
+	got, err := s.Usage("1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "free", got.Plan)
+	assert.Equal(t, int64(2), got.Links.Used)
+	assert.Equal(t, int64(2), got.Links.Limit)
+	assert.Equal(t, int64(7), got.MonthlyClicks.Used)
+	assert.Equal(t, int64(10), got.MonthlyClicks.Limit)
+}
+
+func TestPlanService_LinkLimits(t *testing.T) {
+	r := mocks.NewUsageRepo(t)
+	s := plan.New(r, plans, slog.Default())
+
+	r.On("ByUserID", "1234").Return(&repo.Usage{Plan: "team", Links: 50}, nil).Once()
+	limits, err := s.LinkLimits("1234")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.LinkLimits{MaxLinks: 100}, limits)
+
+	r.On("ByUserID", "notfound").Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.LinkLimits("notfound")
+	assert.ErrorIs(t, err, service.ErrRelatedResourceNotFound)
+}
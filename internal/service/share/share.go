@@ -0,0 +1,185 @@
+package share
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/util/nanoid"
+
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name=ShareRepo
+type ShareRepo interface {
+	Create(share *model.StatsShare, userID string) error
+	ByUrlID(urlID, userID string) ([]model.StatsShare, error)
+	ByTokenHash(tokenHash string) (*model.StatsShare, error)
+	Delete(id, urlID, userID string) error
+}
+
+//go:generate mockery --name=StatsGetter
+type StatsGetter interface {
+	Stats(urlID, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error)
+	Breakdown(urlID, userID, dimension string, includeBots bool) ([]repo.DimensionCount, error)
+}
+
+// ShareService manages public share tokens of url stats. Shared stats are read on behalf of the url owner
+type ShareService struct {
+	repo  ShareRepo
+	stats StatsGetter
+	log   *slog.Logger
+}
+
+func New(repo ShareRepo, stats StatsGetter, log *slog.Logger) *ShareService {
+	return &ShareService{repo, stats, log}
+}
+
+const idAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+const idSize = 12
+
+var idGenerator = nanoid.New(idAlphabet, idSize)
+
+// Create shares stats of the user's url. The returned token is never stored as is
+func (s *ShareService) Create(urlID, userID string, shareDto *dto.CreateShare) (*model.StatsShare, string, error) {
+	log := s.log.With(slog.String("op", "service.share.Create"))
+
+	token, err := generateToken()
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+		return nil, "", service.ErrInternalError
+	}
+	share := &model.StatsShare{
+		UrlID:          urlID,
+		TokenHash:      hashToken(token),
+		HideBreakdowns: shareDto.HideBreakdowns,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+GenerateID:
+	id, err := idGenerator.ID()
+	if err != nil {
+		log.Error("failed to generate id", sl.Err(err))
+		return nil, "", service.ErrInternalError
+	}
+	share.ID = id
+
+	if err := s.repo.Create(share, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return nil, "", service.ErrUrlNotFound
+		}
+		if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23505" { // 23505 = unique_violation
+			goto GenerateID
+		}
+		log.Error("failed to create share", sl.Err(err))
+		return nil, "", service.ErrInternalError
+	}
+
+	log.Info("share successfully created")
+	return share, token, nil
+}
+
+// ByUrlID returns shares of the user's url
+func (s *ShareService) ByUrlID(urlID, userID string) ([]model.StatsShare, error) {
+	log := s.log.With(slog.String("op", "service.share.ByUrlID"))
+
+	shares, err := s.repo.ByUrlID(urlID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return nil, service.ErrUrlNotFound
+		}
+		log.Error("failed to get shares", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("shares successfully received")
+	return shares, nil
+}
+
+// Revoke deletes the share, its token stops working immediately
+func (s *ShareService) Revoke(id, urlID, userID string) error {
+	log := s.log.With(slog.String("op", "service.share.Revoke"))
+
+	if err := s.repo.Delete(id, urlID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("share not found")
+			return service.ErrShareNotFound
+		}
+		log.Error("failed to revoke share", sl.Err(err))
+		return service.ErrInternalError
+	}
+
+	log.Info("share successfully revoked")
+	return nil
+}
+
+// Stats returns the same series as the owner gets for the query
+func (s *ShareService) Stats(token string, query *dto.StatsQuery) ([]repo.DailyCount, error) {
+	share, err := s.byToken(token, "service.share.Stats")
+	if err != nil {
+		return nil, err
+	}
+
+	// no need for logs
+	return s.stats.Stats(share.UrlID, share.Url.UserID, query)
+}
+
+// Breakdown returns clicks by dimension unless the owner hid breakdowns of the share
+func (s *ShareService) Breakdown(token, dimension string, includeBots bool) ([]repo.DimensionCount, error) {
+	share, err := s.byToken(token, "service.share.Breakdown")
+	if err != nil {
+		return nil, err
+	}
+	if share.HideBreakdowns {
+		s.log.Info("breakdowns are hidden", slog.String("op", "service.share.Breakdown"))
+		return nil, service.ErrBreakdownsNotShared
+	}
+
+	// no need for logs
+	return s.stats.Breakdown(share.UrlID, share.Url.UserID, dimension, includeBots)
+}
+
+func (s *ShareService) byToken(token, op string) (*model.StatsShare, error) {
+	log := s.log.With(slog.String("op", op))
+
+	if token == "" {
+		log.Info("token is empty")
+		return nil, service.ErrShareNotFound
+	}
+
+	share, err := s.repo.ByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("share not found")
+			return nil, service.ErrShareNotFound
+		}
+		log.Error("failed to get share", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	return share, nil
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package share_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/service/share"
+	"url-shortener/internal/service/share/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestShareService_Create(t *testing.T) {
+	r := mocks.NewShareRepo(t)
+	s := share.New(r, mocks.NewStatsGetter(t), slog.Default())
+
+	var saved *model.StatsShare
+	r.On("Create", mock.AnythingOfType("*model.StatsShare"), "5678").Run(func(args mock.Arguments) {
+		saved = args.Get(0).(*model.StatsShare)
+	}).Return(nil).Once()
+	created, token, err := s.Create("1234", "5678", &dto.CreateShare{HideBreakdowns: true})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.True(t, created.HideBreakdowns)
+	// only the hash of the token is stored
+	assert.NotEmpty(t, token)
+	assert.NotContains(t, saved.TokenHash, token)
+
+	r.On("Create", mock.Anything, "5678").Return(gorm.ErrRecordNotFound).Once()
+	_, _, err = s.Create("1234", "5678", &dto.CreateShare{})
+	assert.Equal(t, service.ErrUrlNotFound, err)
+}
+
+func TestShareService_Revoke(t *testing.T) {
+	r := mocks.NewShareRepo(t)
+	s := share.New(r, mocks.NewStatsGetter(t), slog.Default())
+
+	r.On("Delete", "abc", "1234", "5678").Return(nil).Once()
+	assert.NoError(t, s.Revoke("abc", "1234", "5678"))
+
+	r.On("Delete", "abc", "1234", "5678").Return(gorm.ErrRecordNotFound).Once()
+	assert.Equal(t, service.ErrShareNotFound, s.Revoke("abc", "1234", "5678"))
+
+	r.On("Delete", "abc", "1234", "5678").Return(errors.New("unexpected")).Once()
+	assert.Equal(t, service.ErrInternalError, s.Revoke("abc", "1234", "5678"))
+}
+
+func TestShareService_Public(t *testing.T) {
+	r := mocks.NewShareRepo(t)
+	stats := mocks.NewStatsGetter(t)
+	s := share.New(r, stats, slog.Default())
+
+	var token string
+	var shared *model.StatsShare
+	r.On("Create", mock.Anything, "5678").Run(func(args mock.Arguments) {
+		shared = args.Get(0).(*model.StatsShare)
+		shared.Url = model.Url{ID: "1234", UserID: "5678"}
+	}).Return(nil).Twice()
+	_, token, err := s.Create("1234", "5678", &dto.CreateShare{})
+	require.NoError(t, err)
+	r.On("ByTokenHash", shared.TokenHash).Return(shared, nil)
+
+	// stats are read on behalf of the owner
+	series := []repo.DailyCount{{Count: 3}}
+	query := &dto.StatsQuery{Granularity: "week"}
+	stats.On("Stats", "1234", "5678", query).Return(series, nil).Once()
+	got, err := s.Stats(token, query)
+	assert.NoError(t, err)
+	assert.Equal(t, series, got)
+
+	breakdown := []repo.DimensionCount{{Value: "Firefox", Count: 3}}
+	stats.On("Breakdown", "1234", "5678", "browser", false).Return(breakdown, nil).Once()
+	gotBreakdown, err := s.Breakdown(token, "browser", false)
+	assert.NoError(t, err)
+	assert.Equal(t, breakdown, gotBreakdown)
+
+	// breakdowns of this share are hidden
+	_, hidden, err := s.Create("1234", "5678", &dto.CreateShare{HideBreakdowns: true})
+	require.NoError(t, err)
+	r.On("ByTokenHash", shared.TokenHash).Return(shared, nil)
+	_, err = s.Breakdown(hidden, "browser", false)
+	assert.Equal(t, service.ErrBreakdownsNotShared, err)
+
+	r.On("ByTokenHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.Stats("unknown", query)
+	assert.Equal(t, service.ErrShareNotFound, err)
+	_, err = s.Stats("", query)
+	assert.Equal(t, service.ErrShareNotFound, err)
+}
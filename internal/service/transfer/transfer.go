@@ -0,0 +1,228 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/util/nanoid"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name=TransferRepo
+type TransferRepo interface {
+	Create(transfer *model.UrlTransfer) error
+	ByID(id string) (*model.UrlTransfer, error)
+	PendingByUserID(userID string) ([]model.UrlTransfer, error)
+	Accept(id, toUserID string, limits repo.LinkLimits) error
+	Close(id, userID, status string) error
+}
+
+//go:generate mockery --name=UserGetter
+type UserGetter interface {
+	ByEmail(email string, withContext ...bool) (*model.User, error)
+}
+
+//go:generate mockery --name=QuotaChecker
+type QuotaChecker interface {
+	LinkLimits(userID string) (repo.LinkLimits, error)
+}
+
+type TransferService struct {
+	repo        TransferRepo
+	userService UserGetter
+	log         *slog.Logger
+	quota       QuotaChecker
+}
+
+type Option func(s *TransferService)
+
+// WithQuota makes Accept check limits of the recipient's plan
+func WithQuota(quota QuotaChecker) Option {
+	return func(s *TransferService) {
+		s.quota = quota
+	}
+}
+
+func New(repo TransferRepo, userService UserGetter, log *slog.Logger, opts ...Option) *TransferService {
+	s := &TransferService{repo: repo, userService: userService, log: log}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+const idAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+const idSize = 12
+
+var idGenerator = nanoid.New(idAlphabet, idSize)
+
+// Create creates a pending transfer of the user's urls to the user with the given email
+func (s *TransferService) Create(transferDto *dto.CreateTransfer, userID string) (*model.UrlTransfer, error) {
+	log := s.log.With(slog.String("op", "service.transfer.Create"))
+
+	if err := service.Validate.Struct(transferDto); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+
+	recipient, err := s.userService.ByEmail(transferDto.Email)
+	if err != nil {
+		// no need for logs
+		return nil, err
+	}
+	if recipient.ID == userID {
+		log.Info("transfer to yourself")
+		return nil, service.ErrTransferToSelf
+	}
+
+	transfer := &model.UrlTransfer{FromUserID: userID, ToUserID: recipient.ID, Status: model.TransferPending}
+	seen := make(map[string]bool, len(transferDto.UrlIDs))
+	for _, urlID := range transferDto.UrlIDs {
+		if seen[urlID] {
+			continue
+		}
+		seen[urlID] = true
+		transfer.Items = append(transfer.Items, model.UrlTransferItem{UrlID: urlID})
+	}
+
+GenerateID:
+	id, err := idGenerator.ID()
+	if err != nil {
+		log.Error("failed to generate id", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	transfer.ID = id
+	for i := range transfer.Items {
+		transfer.Items[i].TransferID = id
+	}
+
+	if err := s.repo.Create(transfer); err != nil {
+		log.Error("failed to create transfer", sl.Err(err))
+		if errors.Is(err, repo.ErrUrlsNotOwned) {
+			return nil, service.ErrTransferUrlsNotOwned
+		}
+		if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23505" { // 23505 = unique_violation
+			goto GenerateID
+		}
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("transfer successfully created")
+	return s.ByID(transfer.ID, userID)
+}
+
+// ByID returns the transfer if the user is its sender or recipient
+func (s *TransferService) ByID(id, userID string) (*model.UrlTransfer, error) {
+	log := s.log.With(slog.String("op", "service.transfer.ByID"))
+
+	if id == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+
+	transfer, err := s.repo.ByID(id)
+	if err != nil {
+		log.Error("failed to get transfer", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrTransferNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+	if transfer.FromUserID != userID && transfer.ToUserID != userID {
+		log.Info("transfer belongs to other users")
+		return nil, service.ErrTransferNotFound
+	}
+
+	log.Info("got transfer by id successfully")
+	return transfer, nil
+}
+
+// Pending returns incoming and outgoing pending transfers of the user
+func (s *TransferService) Pending(userID string) ([]model.UrlTransfer, error) {
+	log := s.log.With(slog.String("op", "service.transfer.Pending"))
+
+	transfers, err := s.repo.PendingByUserID(userID)
+	if err != nil {
+		log.Error("failed to get transfers", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("got pending transfers successfully")
+	return transfers, nil
+}
+
+// Accept moves ownership of the transfer's urls to the recipient
+func (s *TransferService) Accept(id, userID string) (*model.UrlTransfer, error) {
+	log := s.log.With(slog.String("op", "service.transfer.Accept"))
+
+	if id == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+
+	var limits repo.LinkLimits
+	if s.quota != nil {
+		var err error
+		if limits, err = s.quota.LinkLimits(userID); err != nil {
+			// no need for logs
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Accept(id, userID, limits); err != nil {
+		if errors.Is(err, repo.ErrLinkQuotaExceeded) {
+			log.Info("link quota exceeded")
+			return nil, service.ErrLinkQuotaExceeded
+		}
+		if errors.Is(err, repo.ErrAliasQuotaExceeded) {
+			log.Info("alias quota exceeded")
+			return nil, service.ErrAliasQuotaExceeded
+		}
+		log.Error("failed to accept transfer", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrTransferNotFound
+		}
+		if errors.Is(err, repo.ErrUrlsNotOwned) {
+			return nil, service.ErrTransferUrlsNotOwned
+		}
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("transfer successfully accepted")
+	return s.ByID(id, userID)
+}
+
+// Close declines the transfer for the recipient or cancels it for the sender
+func (s *TransferService) Close(id, userID string) error {
+	log := s.log.With(slog.String("op", "service.transfer.Close"))
+
+	transfer, err := s.ByID(id, userID)
+	if err != nil {
+		// no need for logs
+		return err
+	}
+
+	status := model.TransferCancelled
+	if transfer.ToUserID == userID {
+		status = model.TransferDeclined
+	}
+
+	if err := s.repo.Close(id, userID, status); err != nil {
+		log.Error("failed to close transfer", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return service.ErrTransferNotFound
+		}
+		return service.ErrInternalError
+	}
+
+	log.Info("transfer successfully closed", slog.String("status", status))
+	return nil
+}
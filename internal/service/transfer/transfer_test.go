@@ -0,0 +1,230 @@
+package transfer_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/service/transfer"
+	"url-shortener/internal/service/transfer/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestTransferService_Create(t *testing.T) {
+	recipient := &model.User{ID: "5678", Email: "bob@example.com"}
+
+	tests := []struct {
+		name        string
+		transferDto *dto.CreateTransfer
+		userID      string
+		mockSetup   func(r *mocks.TransferRepo, u *mocks.UserGetter)
+		wantItems   int
+		wantErr     error
+	}{
+		{
+			name:        "success",
+			transferDto: &dto.CreateTransfer{Email: recipient.Email, UrlIDs: []string{"a", "b", "a"}},
+			userID:      "1234",
+			mockSetup: func(r *mocks.TransferRepo, u *mocks.UserGetter) {
+				u.On("ByEmail", recipient.Email).Return(recipient, nil).Once()
+				r.On("Create", mock.AnythingOfType("*model.UrlTransfer")).Return(nil).Once()
+				r.On("ByID", mock.Anything).Return(&model.UrlTransfer{
+					FromUserID: "1234",
+					ToUserID:   recipient.ID,
+					Items:      []model.UrlTransferItem{{UrlID: "a"}, {UrlID: "b"}},
+				}, nil).Once()
+			},
+			wantItems: 2,
+		},
+		{
+			name:        "invalid email",
+			transferDto: &dto.CreateTransfer{Email: "bob", UrlIDs: []string{"a"}},
+			userID:      "1234",
+			wantErr:     service.ErrValidation,
+		},
+		{
+			name:        "without urls",
+			transferDto: &dto.CreateTransfer{Email: recipient.Email},
+			userID:      "1234",
+			wantErr:     service.ErrValidation,
+		},
+		{
+			name:        "recipient not found",
+			transferDto: &dto.CreateTransfer{Email: recipient.Email, UrlIDs: []string{"a"}},
+			userID:      "1234",
+			mockSetup: func(r *mocks.TransferRepo, u *mocks.UserGetter) {
+				u.On("ByEmail", recipient.Email).Return(nil, service.ErrUserNotFound).Once()
+			},
+			wantErr: service.ErrUserNotFound,
+		},
+		{
+			name:        "to yourself",
+			transferDto: &dto.CreateTransfer{Email: recipient.Email, UrlIDs: []string{"a"}},
+			userID:      recipient.ID,
+			mockSetup: func(r *mocks.TransferRepo, u *mocks.UserGetter) {
+				u.On("ByEmail", recipient.Email).Return(recipient, nil).Once()
+			},
+			wantErr: service.ErrTransferToSelf,
+		},
+		{
+			name:        "urls of another user",
+			transferDto: &dto.CreateTransfer{Email: recipient.Email, UrlIDs: []string{"a"}},
+			userID:      "1234",
+			mockSetup: func(r *mocks.TransferRepo, u *mocks.UserGetter) {
+				u.On("ByEmail", recipient.Email).Return(recipient, nil).Once()
+				r.On("Create", mock.Anything).Return(repo.ErrUrlsNotOwned).Once()
+			},
+			wantErr: service.ErrTransferUrlsNotOwned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewTransferRepo(t)
+			u := mocks.NewUserGetter(t)
+			if tt.mockSetup != nil {
+				tt.mockSetup(r, u)
+			}
+
+			s := transfer.New(r, u, slog.Default())
+
+			got, err := s.Create(tt.transferDto, tt.userID)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				created := r.Calls[0].Arguments.Get(0).(*model.UrlTransfer)
+				assert.Len(t, created.Items, tt.wantItems)
+				assert.Equal(t, model.TransferPending, created.Status)
+				assert.Equal(t, recipient.ID, got.ToUserID)
+			}
+		})
+	}
+}
+
+func TestTransferService_Accept(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		mockSetup func(r *mocks.TransferRepo)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			id:   "1234",
+			mockSetup: func(r *mocks.TransferRepo) {
+				r.On("Accept", "1234", "5678", repo.LinkLimits{}).Return(nil).Once()
+				r.On("ByID", "1234").Return(&model.UrlTransfer{ID: "1234", ToUserID: "5678", Status: model.TransferAccepted}, nil).Once()
+			},
+		},
+		{
+			name:    "empty id",
+			wantErr: service.ErrValidation,
+		},
+		{
+			name: "not found",
+			id:   "1234",
+			mockSetup: func(r *mocks.TransferRepo) {
+				r.On("Accept", "1234", "5678", repo.LinkLimits{}).Return(gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrTransferNotFound,
+		},
+		{
+			name: "urls changed owner",
+			id:   "1234",
+			mockSetup: func(r *mocks.TransferRepo) {
+				r.On("Accept", "1234", "5678", repo.LinkLimits{}).Return(repo.ErrUrlsNotOwned).Once()
+			},
+			wantErr: service.ErrTransferUrlsNotOwned,
+		},
+		{
+			name: "unexpected error",
+			id:   "1234",
+			mockSetup: func(r *mocks.TransferRepo) {
+				r.On("Accept", "1234", "5678", repo.LinkLimits{}).Return(errors.New("unexpected")).Once()
+			},
+			wantErr: service.ErrInternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewTransferRepo(t)
+			if tt.mockSetup != nil {
+				tt.mockSetup(r)
+			}
+
+			s := transfer.New(r, mocks.NewUserGetter(t), slog.Default())
+
+			got, err := s.Accept(tt.id, "5678")
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				assert.Equal(t, model.TransferAccepted, got.Status)
+			}
+		})
+	}
+}
+
+func TestTransferService_AcceptWithQuota(t *testing.T) {
+	r := mocks.NewTransferRepo(t)
+	quota := mocks.NewQuotaChecker(t)
+	limits := repo.LinkLimits{MaxLinks: 2, MaxAliases: 1}
+
+	s := transfer.New(r, mocks.NewUserGetter(t), slog.Default(), transfer.WithQuota(quota))
+
+	// limits are checked by the repo in the transaction of the transfer
+	quota.On("LinkLimits", "5678").Return(limits, nil).Twice()
+	r.On("Accept", "1234", "5678", limits).Return(repo.ErrLinkQuotaExceeded).Once()
+	_, err := s.Accept("1234", "5678")
+	assert.ErrorIs(t, err, service.ErrLinkQuotaExceeded)
+
+	r.On("Accept", "1234", "5678", limits).Return(repo.ErrAliasQuotaExceeded).Once()
+	_, err = s.Accept("1234", "5678")
+	assert.ErrorIs(t, err, service.ErrAliasQuotaExceeded)
+}
+
+func TestTransferService_Close(t *testing.T) {
+	pending := &model.UrlTransfer{ID: "1234", FromUserID: "1", ToUserID: "2", Status: model.TransferPending}
+
+	tests := []struct {
+		name       string
+		userID     string
+		mockSetup  func(r *mocks.TransferRepo)
+		wantStatus string
+		wantErr    error
+	}{
+		{
+			name:       "cancelled by sender",
+			userID:     "1",
+			wantStatus: model.TransferCancelled,
+		},
+		{
+			name:       "declined by recipient",
+			userID:     "2",
+			wantStatus: model.TransferDeclined,
+		},
+		{
+			name:    "stranger",
+			userID:  "3",
+			wantErr: service.ErrTransferNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewTransferRepo(t)
+			r.On("ByID", pending.ID).Return(pending, nil).Once()
+			if tt.wantStatus != "" {
+				r.On("Close", pending.ID, tt.userID, tt.wantStatus).Return(nil).Once()
+			}
+
+			s := transfer.New(r, mocks.NewUserGetter(t), slog.Default())
+
+			err := s.Close(pending.ID, tt.userID)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
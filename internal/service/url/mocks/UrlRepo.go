@@ -6,6 +6,8 @@ import (
 	model "url-shortener/internal/model"
 
 	mock "github.com/stretchr/testify/mock"
+
+	repo "url-shortener/internal/database/repo"
 )
 
 // UrlRepo is an autogenerated mock type for the UrlRepo type
@@ -13,6 +15,36 @@ type UrlRepo struct {
 	mock.Mock
 }
 
+// ByClaimToken provides a mock function with given fields: id, tokenHash
+func (_m *UrlRepo) ByClaimToken(id string, tokenHash string) (*model.Url, error) {
+	ret := _m.Called(id, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ByClaimToken")
+	}
+
+	var r0 *model.Url
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (*model.Url, error)); ok {
+		return rf(id, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) *model.Url); ok {
+		r0 = rf(id, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Url)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ByID provides a mock function with given fields: id
 func (_m *UrlRepo) ByID(id string) (*model.Url, error) {
 	ret := _m.Called(id)
@@ -73,17 +105,35 @@ func (_m *UrlRepo) ByUserID(id string, limit int, offset int) ([]model.Url, erro
 	return r0, r1
 }
 
-// Create provides a mock function with given fields: _a0
-func (_m *UrlRepo) Create(_a0 *model.Url) error {
-	ret := _m.Called(_a0)
+// Claim provides a mock function with given fields: id, tokenHash, userID, limits
+func (_m *UrlRepo) Claim(id string, tokenHash string, userID string, limits repo.LinkLimits) error {
+	ret := _m.Called(id, tokenHash, userID, limits)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Claim")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, repo.LinkLimits) error); ok {
+		r0 = rf(id, tokenHash, userID, limits)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: _a0, limits
+func (_m *UrlRepo) Create(_a0 *model.Url, limits repo.LinkLimits) error {
+	ret := _m.Called(_a0, limits)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*model.Url) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(*model.Url, repo.LinkLimits) error); ok {
+		r0 = rf(_a0, limits)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -92,16 +142,46 @@ func (_m *UrlRepo) Create(_a0 *model.Url) error {
 }
 
 // Delete provides a mock function with given fields: id, userID
-func (_m *UrlRepo) Delete(id string, userID string) error {
+func (_m *UrlRepo) Delete(id string, userID string) (*model.Url, error) {
 	ret := _m.Called(id, userID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
+	var r0 *model.Url
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (*model.Url, error)); ok {
+		return rf(id, userID)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) *model.Url); ok {
+		r0 = rf(id, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Url)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAnonymous provides a mock function with given fields: id, tokenHash
+func (_m *UrlRepo) DeleteAnonymous(id string, tokenHash string) error {
+	ret := _m.Called(id, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAnonymous")
+	}
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(id, userID)
+		r0 = rf(id, tokenHash)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -109,6 +189,94 @@ func (_m *UrlRepo) Delete(id string, userID string) error {
 	return r0
 }
 
+// DeleteExpired provides a mock function with no fields
+func (_m *UrlRepo) DeleteExpired() (int64, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (int64, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: id, userID
+func (_m *UrlRepo) History(id string, userID string) ([]repo.UrlHistoryEntry, error) {
+	ret := _m.Called(id, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 []repo.UrlHistoryEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) ([]repo.UrlHistoryEntry, error)); ok {
+		return rf(id, userID)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) []repo.UrlHistoryEntry); ok {
+		r0 = rf(id, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.UrlHistoryEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HistoryByID provides a mock function with given fields: historyID, urlID, userID
+func (_m *UrlRepo) HistoryByID(historyID int64, urlID string, userID string) (*model.UrlHistory, error) {
+	ret := _m.Called(historyID, urlID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HistoryByID")
+	}
+
+	var r0 *model.UrlHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string, string) (*model.UrlHistory, error)); ok {
+		return rf(historyID, urlID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string, string) *model.UrlHistory); ok {
+		r0 = rf(historyID, urlID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UrlHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string, string) error); ok {
+		r1 = rf(historyID, urlID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LinkByID provides a mock function with given fields: id
 func (_m *UrlRepo) LinkByID(id string) (string, error) {
 	ret := _m.Called(id)
@@ -137,6 +305,66 @@ func (_m *UrlRepo) LinkByID(id string) (string, error) {
 	return r0, r1
 }
 
+// SetDedupWindow provides a mock function with given fields: id, userID, window
+func (_m *UrlRepo) SetDedupWindow(id string, userID string, window *int) (*model.Url, error) {
+	ret := _m.Called(id, userID, window)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDedupWindow")
+	}
+
+	var r0 *model.Url
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, *int) (*model.Url, error)); ok {
+		return rf(id, userID, window)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, *int) *model.Url); ok {
+		r0 = rf(id, userID, window)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Url)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, *int) error); ok {
+		r1 = rf(id, userID, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLink provides a mock function with given fields: id, userID, link, action
+func (_m *UrlRepo) UpdateLink(id string, userID string, link string, action string) (*model.Url, error) {
+	ret := _m.Called(id, userID, link, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLink")
+	}
+
+	var r0 *model.Url
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string) (*model.Url, error)); ok {
+		return rf(id, userID, link, action)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string, string) *model.Url); ok {
+		r0 = rf(id, userID, link, action)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Url)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string, string) error); ok {
+		r1 = rf(id, userID, link, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUrlRepo creates a new instance of UrlRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUrlRepo(t interface {
@@ -1,9 +1,16 @@
 package url
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
+	"unicode/utf8"
+	"url-shortener/internal/database/repo"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/pg"
 	"url-shortener/internal/model"
@@ -12,27 +19,87 @@ import (
 	"url-shortener/internal/util/nanoid"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
 //go:generate mockery --name=UrlRepo
 type UrlRepo interface {
-	Create(url *model.Url) error
+	Create(url *model.Url, limits repo.LinkLimits) error
 	ByID(id string) (*model.Url, error)
 	LinkByID(id string) (string, error)
 	ByUserID(id string, limit int, offset int) ([]model.Url, error)
-	Delete(id string, userID string) error
+	Delete(id string, userID string) (*model.Url, error)
+	UpdateLink(id, userID, link, action string) (*model.Url, error)
+	SetDedupWindow(id, userID string, window *int) (*model.Url, error)
+	History(id, userID string) ([]repo.UrlHistoryEntry, error)
+	HistoryByID(historyID int64, urlID, userID string) (*model.UrlHistory, error)
+	ByClaimToken(id, tokenHash string) (*model.Url, error)
+	Claim(id, tokenHash, userID string, limits repo.LinkLimits) error
+	DeleteAnonymous(id, tokenHash string) error
+	DeleteExpired() (int64, error)
+}
+
+//go:generate mockery --name=EventPublisher
+type EventPublisher interface {
+	PublishUrlEvent(event string, url *model.Url)
+}
+
+//go:generate mockery --name=QuotaChecker
+type QuotaChecker interface {
+	LinkLimits(userID string) (repo.LinkLimits, error)
 }
 
 type UrlService struct {
-	repo UrlRepo
-	log  *slog.Logger
+	repo         UrlRepo
+	log          *slog.Logger
+	anonymousTTL time.Duration
+	// maxLinkLength is the limit of destinations in characters
+	maxLinkLength int
+	quota         QuotaChecker
+	events        EventPublisher
 }
 
-func New(repo UrlRepo, log *slog.Logger) *UrlService {
-	return &UrlService{repo, log}
+type Option func(s *UrlService)
+
+// WithAnonymousTTL sets how long anonymous urls live unless claimed
+func WithAnonymousTTL(ttl time.Duration) Option {
+	return func(s *UrlService) {
+		s.anonymousTTL = ttl
+	}
 }
 
+// WithMaxLinkLength sets the limit of destinations in characters
+func WithMaxLinkLength(length int) Option {
+	return func(s *UrlService) {
+		s.maxLinkLength = length
+	}
+}
+
+// WithQuota makes Create and Claim check limits of the user's plan
+func WithQuota(quota QuotaChecker) Option {
+	return func(s *UrlService) {
+		s.quota = quota
+	}
+}
+
+// WithEventPublisher makes changes of users' urls published, e.g. to webhooks
+func WithEventPublisher(events EventPublisher) Option {
+	return func(s *UrlService) {
+		s.events = events
+	}
+}
+
+func New(repo UrlRepo, log *slog.Logger, opts ...Option) *UrlService {
+	s := &UrlService{repo: repo, log: log, anonymousTTL: 30 * 24 * time.Hour, maxLinkLength: defaultMaxLinkLength}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+const defaultMaxLinkLength = 2048
+
 const idAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 const idSize = 8
 
@@ -45,8 +112,68 @@ func (s *UrlService) Create(urlDto *dto.CreateUrl, userID string) (*model.Url, e
 		log.Info("validation failed", sl.Err(err))
 		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
 	}
+	if err := s.validateLink(urlDto.Link); err != nil {
+		log.Info("link is too long")
+		return nil, err
+	}
+
+	// limits are checked by the repo in the transaction of the creation, so concurrent creations don't go over them
+	var limits repo.LinkLimits
+	if s.quota != nil {
+		var err error
+		if limits, err = s.quota.LinkLimits(userID); err != nil {
+			// no need for logs
+			return nil, err
+		}
+	}
+
+	url, err := s.create(log, urlDto.Model(userID), limits)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(model.EventUrlCreated, url)
+	return url, nil
+}
 
-	url := urlDto.Model(userID)
+// CreateAnonymous creates an url without owner that expires unless claimed.
+// The returned claim token lets its holder manage the url and is never stored as is
+func (s *UrlService) CreateAnonymous(urlDto *dto.CreateUrl) (*model.Url, string, error) {
+	log := s.log.With(slog.String("op", "service.url.CreateAnonymous"))
+
+	if err := service.Validate.Struct(urlDto); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, "", service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+	if err := s.validateLink(urlDto.Link); err != nil {
+		log.Info("link is too long")
+		return nil, "", err
+	}
+	if urlDto.Alias != "" {
+		log.Info("alias without authorization")
+		return nil, "", fmt.Errorf("%w%s", service.ErrValidation, "field Alias requires authorization")
+	}
+
+	token, err := generateClaimToken()
+	if err != nil {
+		log.Error("failed to generate claim token", sl.Err(err))
+		return nil, "", service.ErrInternalError
+	}
+
+	url := urlDto.Model("")
+	url.ClaimTokenHash = hashClaimToken(token)
+	expiresAt := time.Now().Add(s.anonymousTTL)
+	url.ExpiresAt = &expiresAt
+
+	url, err = s.create(log, url, repo.LinkLimits{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return url, token, nil
+}
+
+func (s *UrlService) create(log *slog.Logger, url *model.Url, limits repo.LinkLimits) (*model.Url, error) {
 	autogeneration := url.ID == ""
 
 GenerateID:
@@ -59,7 +186,15 @@ GenerateID:
 		url.ID = id
 	}
 
-	if err := s.repo.Create(url); err != nil {
+	if err := s.repo.Create(url, limits); err != nil {
+		if errors.Is(err, repo.ErrLinkQuotaExceeded) {
+			log.Info("link quota exceeded")
+			return nil, service.ErrLinkQuotaExceeded
+		}
+		if errors.Is(err, repo.ErrAliasQuotaExceeded) {
+			log.Info("alias quota exceeded")
+			return nil, service.ErrAliasQuotaExceeded
+		}
 		log.Error("failed to create url", sl.Err(err))
 		if pgErr := pg.ParsePGError(err); pgErr != nil {
 			if pgErr.Code == "23503" { // 23503 = foreign_key_violation
@@ -145,11 +280,276 @@ func (s *UrlService) Delete(id, userID string) error {
 		return fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
 	}
 
-	if err := s.repo.Delete(id, userID); err != nil {
+	url, err := s.repo.Delete(id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// deleting is idempotent
+			log.Info("url not found")
+			return nil
+		}
 		log.Error("failed to delete url", sl.Err(err))
 		return service.ErrInternalError
 	}
 
+	s.publish(model.EventUrlDeleted, url)
 	log.Info("url successfully deleted")
 	return nil
 }
+
+func (s *UrlService) Update(id, userID string, urlDto *dto.UpdateUrl) (*model.Url, error) {
+	log := s.log.With(slog.String("op", "service.url.Update"))
+
+	if id == "" || userID == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+	if err := service.Validate.Struct(urlDto); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+	if err := s.validateLink(urlDto.Link); err != nil {
+		log.Info("link is too long")
+		return nil, err
+	}
+
+	url, err := s.repo.UpdateLink(id, userID, urlDto.Link, model.HistoryUpdate)
+	if err != nil {
+		log.Error("failed to update url", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrUrlNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+
+	s.publish(model.EventUrlUpdated, url)
+	log.Info("url successfully updated")
+	return url, nil
+}
+
+func (s *UrlService) History(id, userID string) ([]repo.UrlHistoryEntry, error) {
+	log := s.log.With(slog.String("op", "service.url.History"))
+
+	if id == "" || userID == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+
+	history, err := s.repo.History(id, userID)
+	if err != nil {
+		log.Error("failed to get url history", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	if len(history) == 0 {
+		log.Info("url history not found")
+		return nil, service.ErrUrlHistoryNotFound
+	}
+
+	log.Info("got url history successfully")
+	return history, nil
+}
+
+// Rollback makes the destination set by the history entry live again
+func (s *UrlService) Rollback(id string, historyID int64, userID string) (*model.Url, error) {
+	log := s.log.With(slog.String("op", "service.url.Rollback"))
+
+	if id == "" || userID == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+
+	entry, err := s.repo.HistoryByID(historyID, id, userID)
+	if err != nil {
+		log.Error("failed to get history entry", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrUrlHistoryNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+	if entry.Field != "link" {
+		log.Info("history entry isn't a destination change")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "only destination changes can be rolled back")
+	}
+
+	url, err := s.repo.UpdateLink(id, userID, entry.NewValue, model.HistoryRollback)
+	if err != nil {
+		log.Error("failed to roll back url", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrUrlNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+
+	s.publish(model.EventUrlUpdated, url)
+	log.Info("url successfully rolled back")
+	return url, nil
+}
+
+// ByClaimToken returns the anonymous url if the token is its claim token
+func (s *UrlService) ByClaimToken(id, token string) (*model.Url, error) {
+	log := s.log.With(slog.String("op", "service.url.ByClaimToken"))
+
+	if id == "" || token == "" {
+		log.Info("id or token is empty")
+		return nil, service.ErrInvalidClaimToken
+	}
+
+	url, err := s.repo.ByClaimToken(id, hashClaimToken(token))
+	if err != nil {
+		log.Info("failed to get url by claim token", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrInvalidClaimToken
+		}
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("got url by claim token successfully")
+	return url, nil
+}
+
+// Claim moves the anonymous url into the user's account
+func (s *UrlService) Claim(id, userID string, claimDto *dto.ClaimUrl) (*model.Url, error) {
+	log := s.log.With(slog.String("op", "service.url.Claim"))
+
+	if id == "" || userID == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+	if err := service.Validate.Struct(claimDto); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+
+	var limits repo.LinkLimits
+	if s.quota != nil {
+		var err error
+		if limits, err = s.quota.LinkLimits(userID); err != nil {
+			// no need for logs
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Claim(id, hashClaimToken(claimDto.Token), userID, limits); err != nil {
+		log.Info("failed to claim url", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrInvalidClaimToken
+		}
+		if errors.Is(err, repo.ErrLinkQuotaExceeded) {
+			return nil, service.ErrLinkQuotaExceeded
+		}
+		if errors.Is(err, repo.ErrAliasQuotaExceeded) {
+			return nil, service.ErrAliasQuotaExceeded
+		}
+		if pgErr := pg.ParsePGError(err); pgErr != nil && pgErr.Code == "23503" { // 23503 = foreign_key_violation
+			return nil, service.ErrRelatedResourceNotFound
+		}
+		return nil, service.ErrInternalError
+	}
+
+	url, err := s.ByID(id)
+	if err != nil {
+		// no need for logs
+		return nil, err
+	}
+
+	log.Info("url successfully claimed")
+	s.publish(model.EventUrlUpdated, url)
+	return url, nil
+}
+
+// SetDedupWindow overrides the global de-duplication window of clicks of the user's url
+func (s *UrlService) SetDedupWindow(id, userID string, dedup *dto.UrlDedup) (*model.Url, error) {
+	log := s.log.With(slog.String("op", "service.url.SetDedupWindow"))
+
+	if id == "" || userID == "" {
+		log.Info("id is empty")
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, "id is a required")
+	}
+	if err := service.Validate.Struct(dedup); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+
+	url, err := s.repo.SetDedupWindow(id, userID, dedup.Window)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("url not found")
+			return nil, service.ErrUrlNotFound
+		}
+		log.Error("failed to set dedup window", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	s.publish(model.EventUrlUpdated, url)
+	log.Info("dedup window successfully set")
+	return url, nil
+}
+
+func (s *UrlService) DeleteAnonymous(id, token string) error {
+	log := s.log.With(slog.String("op", "service.url.DeleteAnonymous"))
+
+	if id == "" || token == "" {
+		log.Info("id or token is empty")
+		return service.ErrInvalidClaimToken
+	}
+
+	if err := s.repo.DeleteAnonymous(id, hashClaimToken(token)); err != nil {
+		log.Info("failed to delete anonymous url", sl.Err(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return service.ErrInvalidClaimToken
+		}
+		return service.ErrInternalError
+	}
+
+	log.Info("anonymous url successfully deleted")
+	return nil
+}
+
+func (s *UrlService) CleanupExpired() (*cron.Cron, error) {
+	log := s.log.With(slog.String("op", "service.url.CleanupExpired"))
+
+	c := cron.New()
+
+	// Run hourly
+	_, err := c.AddFunc("0 * * * *", func() {
+		deleted, err := s.repo.DeleteExpired()
+		if err != nil {
+			log.Error("failed to delete expired urls", sl.Err(err))
+			return
+		}
+		log.Info("expired urls deleted", slog.Int64("count", deleted))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Start()
+
+	return c, nil
+}
+
+func generateClaimToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashClaimToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *UrlService) publish(event string, url *model.Url) {
+	if s.events != nil {
+		s.events.PublishUrlEvent(event, url)
+	}
+}
+
+// validateLink checks the length of the destination in characters, so links with non-ASCII paths aren't cut shorter
+func (s *UrlService) validateLink(link string) error {
+	if utf8.RuneCountInString(link) > s.maxLinkLength {
+		return fmt.Errorf("%w%s", service.ErrValidation, fmt.Sprintf("field Link must be at most %d characters long", s.maxLinkLength))
+	}
+	return nil
+}
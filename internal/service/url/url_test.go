@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
 	"url-shortener/internal/model"
 	"url-shortener/internal/model/dto"
 	"url-shortener/internal/service"
@@ -31,23 +33,23 @@ func TestUrlService_Create(t *testing.T) {
 			name:      "success with alias",
 			urlDto:    &dto.CreateUrl{Alias: "g", Link: "https://google.com"},
 			userID:    "1234",
-			mockSetup: func(r *mocks.UrlRepo) { r.On("Create", mock.Anything).Return(nil).Once() },
+			mockSetup: func(r *mocks.UrlRepo) { r.On("Create", mock.Anything, mock.Anything).Return(nil).Once() },
 		},
 		{
 			name:      "success without alias",
 			urlDto:    &dto.CreateUrl{Link: "https://google.com"},
 			userID:    "1234",
-			mockSetup: func(r *mocks.UrlRepo) { r.On("Create", mock.Anything).Return(nil).Once() },
+			mockSetup: func(r *mocks.UrlRepo) { r.On("Create", mock.Anything, mock.Anything).Return(nil).Once() },
 		},
 		{
 			name:   "success with regenerated id",
 			urlDto: &dto.CreateUrl{Link: "https://google.com"},
 			userID: "1234",
 			mockSetup: func(r *mocks.UrlRepo) {
-				r.On("Create", mock.Anything).
+				r.On("Create", mock.Anything, mock.Anything).
 					Return(&pgconn.PgError{Code: "23505"}). // 23505 = unique_violation
 					Once()
-				r.On("Create", mock.Anything).Return(nil).Once()
+				r.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
 			},
 		},
 		{
@@ -74,12 +76,24 @@ func TestUrlService_Create(t *testing.T) {
 			userID:  "1234",
 			wantErr: service.ErrValidation,
 		},
+		{
+			name:      "long url",
+			urlDto:    &dto.CreateUrl{Link: "https://google.com/?q=" + strings.Repeat("0", 1000)},
+			userID:    "1234",
+			mockSetup: func(r *mocks.UrlRepo) { r.On("Create", mock.Anything, mock.Anything).Return(nil).Once() },
+		},
+		{
+			name:    "too long url",
+			urlDto:  &dto.CreateUrl{Link: "https://google.com/?q=" + strings.Repeat("0", 2048)},
+			userID:  "1234",
+			wantErr: service.ErrValidation,
+		},
 		{
 			name:   "user id that doesn't exist",
 			urlDto: &dto.CreateUrl{Link: "https://google.com"},
 			userID: "notfound",
 			mockSetup: func(r *mocks.UrlRepo) {
-				r.On("Create", mock.Anything).
+				r.On("Create", mock.Anything, mock.Anything).
 					Return(&pgconn.PgError{Code: "23503"}). // 23503 = foreign_key_violation
 					Once()
 			},
@@ -233,9 +247,18 @@ func TestUrlService_Delete(t *testing.T) {
 		wantErr   error
 	}{
 		{
-			name:      "success",
-			args:      args{id: "1234", userID: "1234"},
-			mockSetup: func(r *mocks.UrlRepo) { r.On("Delete", mock.Anything, mock.Anything).Return(nil).Once() },
+			name: "success",
+			args: args{id: "1234", userID: "1234"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("Delete", mock.Anything, mock.Anything).Return(&model.Url{ID: "1234", UserID: "1234"}, nil).Once()
+			},
+		},
+		{
+			name: "not found",
+			args: args{id: "1234", userID: "1234"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("Delete", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
 		},
 		{
 			name:    "empty id",
@@ -251,7 +274,7 @@ func TestUrlService_Delete(t *testing.T) {
 			name: "unexpected error",
 			args: args{id: "1234", userID: "1234"},
 			mockSetup: func(r *mocks.UrlRepo) {
-				r.On("Delete", mock.Anything, mock.Anything).Return(errors.New("unexpected")).Once()
+				r.On("Delete", mock.Anything, mock.Anything).Return(nil, errors.New("unexpected")).Once()
 			},
 			wantErr: service.ErrInternalError,
 		},
@@ -271,3 +294,399 @@ func TestUrlService_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestUrlService_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		urlDto    *dto.UpdateUrl
+		mockSetup func(r *mocks.UrlRepo)
+		wantErr   error
+	}{
+		{
+			name:   "success",
+			id:     "1234",
+			urlDto: &dto.UpdateUrl{Link: "https://example.com"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("UpdateLink", "1234", "1", "https://example.com", model.HistoryUpdate).
+					Return(&model.Url{ID: "1234", Link: "https://example.com"}, nil).
+					Once()
+			},
+		},
+		{
+			name:    "empty id",
+			urlDto:  &dto.UpdateUrl{Link: "https://example.com"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "invalid url",
+			id:      "1234",
+			urlDto:  &dto.UpdateUrl{Link: "noturl"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:   "not found",
+			id:     "1234",
+			urlDto: &dto.UpdateUrl{Link: "https://example.com"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("UpdateLink", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrUrlNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUrlRepo(t)
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(repo)
+			}
+
+			s := url.New(repo, slog.Default())
+
+			got, err := s.Update(tt.id, "1", tt.urlDto)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				assert.Equal(t, tt.urlDto.Link, got.Link)
+			}
+		})
+	}
+}
+
+func TestUrlService_History(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(r *mocks.UrlRepo)
+		wantLen   int
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("History", "1234", "1").Return([]repo.UrlHistoryEntry{
+					{UrlHistory: model.UrlHistory{ID: 2, Action: model.HistoryUpdate}, Clicks: 3},
+					{UrlHistory: model.UrlHistory{ID: 1, Action: model.HistoryCreate}, Clicks: 10},
+				}, nil).Once()
+			},
+			wantLen: 2,
+		},
+		{
+			name: "url of another user",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("History", "1234", "1").Return(nil, nil).Once()
+			},
+			wantErr: service.ErrUrlHistoryNotFound,
+		},
+		{
+			name: "unexpected error",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("History", "1234", "1").Return(nil, errors.New("unexpected")).Once()
+			},
+			wantErr: service.ErrInternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUrlRepo(t)
+			tt.mockSetup(repo)
+
+			s := url.New(repo, slog.Default())
+
+			got, err := s.History("1234", "1")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Len(t, got, tt.wantLen)
+		})
+	}
+}
+
+func TestUrlService_Rollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(r *mocks.UrlRepo)
+		wantLink  string
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("HistoryByID", int64(1), "1234", "1").
+					Return(&model.UrlHistory{ID: 1, Field: "link", NewValue: "https://google.com"}, nil).
+					Once()
+				r.On("UpdateLink", "1234", "1", "https://google.com", model.HistoryRollback).
+					Return(&model.Url{ID: "1234", Link: "https://google.com"}, nil).
+					Once()
+			},
+			wantLink: "https://google.com",
+		},
+		{
+			name: "history entry not found",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("HistoryByID", int64(1), "1234", "1").Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrUrlHistoryNotFound,
+		},
+		{
+			name: "url deleted meanwhile",
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("HistoryByID", int64(1), "1234", "1").
+					Return(&model.UrlHistory{ID: 1, Field: "link", NewValue: "https://google.com"}, nil).
+					Once()
+				r.On("UpdateLink", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrUrlNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUrlRepo(t)
+			tt.mockSetup(repo)
+
+			s := url.New(repo, slog.Default())
+
+			got, err := s.Rollback("1234", 1, "1")
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				assert.Equal(t, tt.wantLink, got.Link)
+			}
+		})
+	}
+}
+
+func TestUrlService_CreateAnonymous(t *testing.T) {
+	tests := []struct {
+		name      string
+		urlDto    *dto.CreateUrl
+		mockSetup func(r *mocks.UrlRepo)
+		wantErr   error
+	}{
+		{
+			name:   "success",
+			urlDto: &dto.CreateUrl{Link: "https://google.com"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("Create", mock.AnythingOfType("*model.Url"), repo.LinkLimits{}).Return(nil).Once()
+			},
+		},
+		{
+			name:    "with alias",
+			urlDto:  &dto.CreateUrl{Alias: "g", Link: "https://google.com"},
+			wantErr: service.ErrValidation,
+		},
+		{
+			name:    "invalid url",
+			urlDto:  &dto.CreateUrl{Link: "noturl"},
+			wantErr: service.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUrlRepo(t)
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(repo)
+			}
+
+			s := url.New(repo, slog.Default(), url.WithAnonymousTTL(time.Hour))
+
+			got, token, err := s.CreateAnonymous(tt.urlDto)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				assert.Empty(t, got.UserID)
+				assert.NotEmpty(t, token)
+				assert.NotEmpty(t, got.ClaimTokenHash)
+				assert.NotContains(t, got.ClaimTokenHash, token)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), *got.ExpiresAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestUrlService_Claim(t *testing.T) {
+	tests := []struct {
+		name      string
+		claimDto  *dto.ClaimUrl
+		mockSetup func(r *mocks.UrlRepo)
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			claimDto: &dto.ClaimUrl{Token: "token"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("Claim", "1234", mock.Anything, "1", mock.Anything).Return(nil).Once()
+				r.On("ByID", "1234").Return(&model.Url{ID: "1234", UserID: "1"}, nil).Once()
+			},
+		},
+		{
+			name:     "without token",
+			claimDto: &dto.ClaimUrl{},
+			wantErr:  service.ErrValidation,
+		},
+		{
+			name:     "invalid token",
+			claimDto: &dto.ClaimUrl{Token: "token"},
+			mockSetup: func(r *mocks.UrlRepo) {
+				r.On("Claim", "1234", mock.Anything, "1", mock.Anything).Return(gorm.ErrRecordNotFound).Once()
+			},
+			wantErr: service.ErrInvalidClaimToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUrlRepo(t)
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(repo)
+			}
+
+			s := url.New(repo, slog.Default())
+
+			got, err := s.Claim("1234", "1", tt.claimDto)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if err == nil {
+				assert.Equal(t, "1", got.UserID)
+			}
+		})
+	}
+}
+
+func TestUrlService_ByClaimToken(t *testing.T) {
+	repo := mocks.NewUrlRepo(t)
+	s := url.New(repo, slog.Default())
+
+	// the same token always gives the same hash
+	var hash string
+	repo.On("ByClaimToken", "1234", mock.Anything).
+		Run(func(args mock.Arguments) { hash = args.String(1) }).
+		Return(&model.Url{ID: "1234"}, nil).
+		Once()
+	_, err := s.ByClaimToken("1234", "token")
+	assert.NoError(t, err)
+
+	repo.On("ByClaimToken", "1234", hash).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.ByClaimToken("1234", "token")
+	assert.ErrorIs(t, err, service.ErrInvalidClaimToken)
+
+	_, err = s.ByClaimToken("1234", "")
+	assert.ErrorIs(t, err, service.ErrInvalidClaimToken)
+}
+
+func TestUrlService_CreateWithQuota(t *testing.T) {
+	urlRepo := mocks.NewUrlRepo(t)
+	quota := mocks.NewQuotaChecker(t)
+	limits := repo.LinkLimits{MaxLinks: 2, MaxAliases: 1}
+
+	s := url.New(urlRepo, slog.Default(), url.WithQuota(quota))
+
+	// limits are checked by the repo in the transaction of the creation
+	quota.On("LinkLimits", "1234").Return(limits, nil).Times(3)
+	urlRepo.On("Create", mock.MatchedBy(func(u *model.Url) bool { return u.CustomAlias }), limits).Return(repo.ErrAliasQuotaExceeded).Once()
+	_, err := s.Create(&dto.CreateUrl{Alias: "g", Link: "https://google.com"}, "1234")
+	assert.ErrorIs(t, err, service.ErrAliasQuotaExceeded)
+
+	urlRepo.On("Create", mock.Anything, limits).Return(repo.ErrLinkQuotaExceeded).Once()
+	_, err = s.Create(&dto.CreateUrl{Link: "https://google.com"}, "1234")
+	assert.ErrorIs(t, err, service.ErrLinkQuotaExceeded)
+
+	urlRepo.On("Create", mock.Anything, limits).Return(nil).Once()
+	_, err = s.Create(&dto.CreateUrl{Link: "https://google.com"}, "1234")
+	assert.NoError(t, err)
+
+	quota.On("LinkLimits", "5678").Return(repo.LinkLimits{}, service.ErrRelatedResourceNotFound).Once()
+	_, err = s.Create(&dto.CreateUrl{Link: "https://google.com"}, "5678")
+	assert.ErrorIs(t, err, service.ErrRelatedResourceNotFound)
+}
+
+func TestUrlService_ClaimWithQuota(t *testing.T) {
+	urlRepo := mocks.NewUrlRepo(t)
+	quota := mocks.NewQuotaChecker(t)
+	limits := repo.LinkLimits{MaxLinks: 2, MaxAliases: 1}
+
+	s := url.New(urlRepo, slog.Default(), url.WithQuota(quota))
+
+	// limits are checked by the repo in the transaction of the claim
+	quota.On("LinkLimits", "1").Return(limits, nil).Twice()
+	urlRepo.On("Claim", "1234", mock.Anything, "1", limits).Return(repo.ErrLinkQuotaExceeded).Once()
+	_, err := s.Claim("1234", "1", &dto.ClaimUrl{Token: "token"})
+	assert.ErrorIs(t, err, service.ErrLinkQuotaExceeded)
+
+	urlRepo.On("Claim", "1234", mock.Anything, "1", limits).Return(repo.ErrAliasQuotaExceeded).Once()
+	_, err = s.Claim("1234", "1", &dto.ClaimUrl{Token: "token"})
+	assert.ErrorIs(t, err, service.ErrAliasQuotaExceeded)
+
+	quota.On("LinkLimits", "2").Return(repo.LinkLimits{}, service.ErrRelatedResourceNotFound).Once()
+	_, err = s.Claim("1234", "2", &dto.ClaimUrl{Token: "token"})
+	assert.ErrorIs(t, err, service.ErrRelatedResourceNotFound)
+}
+
+func TestUrlService_Events(t *testing.T) {
+	repo := mocks.NewUrlRepo(t)
+	events := mocks.NewEventPublisher(t)
+
+	s := url.New(repo, slog.Default(), url.WithEventPublisher(events))
+
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+	events.On("PublishUrlEvent", model.EventUrlCreated, mock.MatchedBy(func(u *model.Url) bool { return u.UserID == "1234" })).Return().Once()
+	_, err := s.Create(&dto.CreateUrl{Link: "https://google.com"}, "1234")
+	assert.NoError(t, err)
+
+	updated := &model.Url{ID: "g", Link: "https://example.com", UserID: "1234"}
+	repo.On("UpdateLink", "g", "1234", "https://example.com", model.HistoryUpdate).Return(updated, nil).Once()
+	events.On("PublishUrlEvent", model.EventUrlUpdated, updated).Return().Once()
+	_, err = s.Update("g", "1234", &dto.UpdateUrl{Link: "https://example.com"})
+	assert.NoError(t, err)
+
+	repo.On("Delete", "g", "1234").Return(updated, nil).Once()
+	events.On("PublishUrlEvent", model.EventUrlDeleted, updated).Return().Once()
+	assert.NoError(t, s.Delete("g", "1234"))
+
+	// urls that weren't deleted aren't published
+	repo.On("Delete", "g", "1234").Return(nil, gorm.ErrRecordNotFound).Once()
+	assert.NoError(t, s.Delete("g", "1234"))
+
+	claimed := &model.Url{ID: "c", Link: "https://example.com", UserID: "1234"}
+	repo.On("Claim", "c", mock.Anything, "1234", mock.Anything).Return(nil).Once()
+	repo.On("ByID", "c").Return(claimed, nil).Once()
+	events.On("PublishUrlEvent", model.EventUrlUpdated, claimed).Return().Once()
+	_, err = s.Claim("c", "1234", &dto.ClaimUrl{Token: "token"})
+	assert.NoError(t, err)
+}
+
+func TestUrlService_SetDedupWindow(t *testing.T) {
+	repo := mocks.NewUrlRepo(t)
+	s := url.New(repo, slog.Default())
+
+	window := 60
+	repo.On("SetDedupWindow", "g", "1234", &window).Return(&model.Url{ID: "g", DedupWindow: &window}, nil).Once()
+	u, err := s.SetDedupWindow("g", "1234", &dto.UrlDedup{Window: &window})
+	assert.NoError(t, err)
+	assert.Equal(t, &window, u.DedupWindow)
+
+	// empty window resets it to the global one
+	repo.On("SetDedupWindow", "g", "1234", (*int)(nil)).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.SetDedupWindow("g", "1234", &dto.UrlDedup{})
+	assert.ErrorIs(t, err, service.ErrUrlNotFound)
+
+	negative := -1
+	_, err = s.SetDedupWindow("g", "1234", &dto.UrlDedup{Window: &negative})
+	assert.ErrorIs(t, err, service.ErrValidation)
+}
+
+func TestUrlService_CreateTooLongLinkMessage(t *testing.T) {
+	repo := mocks.NewUrlRepo(t)
+	s := url.New(repo, slog.Default(), url.WithMaxLinkLength(32))
+
+	_, err := s.Create(&dto.CreateUrl{Link: "https://google.com/?q=" + strings.Repeat("0", 32)}, "1234")
+	assert.ErrorIs(t, err, service.ErrValidation)
+	assert.Equal(t, "field Link must be at most 32 characters long", err.Error())
+
+	// the limit is in characters, not bytes
+	link := "https://google.com/?q=" + strings.Repeat("ü", 10)
+	repo.On("UpdateLink", "g", "1234", link, model.HistoryUpdate).Return(&model.Url{ID: "g", Link: link}, nil).Once()
+	_, err = s.Update("g", "1234", &dto.UpdateUrl{Link: link})
+	assert.NoError(t, err)
+	_, err = s.Update("g", "1234", &dto.UpdateUrl{Link: link + "ü"})
+	assert.ErrorIs(t, err, service.ErrValidation)
+}
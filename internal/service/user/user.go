@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/pg"
 	"url-shortener/internal/model"
@@ -25,12 +26,28 @@ type UserRepo interface {
 }
 
 type UserService struct {
-	repo UserRepo
-	log  *slog.Logger
+	repo   UserRepo
+	log    *slog.Logger
+	admins map[string]bool
 }
 
-func New(repo UserRepo, log *slog.Logger) *UserService {
-	return &UserService{repo, log}
+type Option func(s *UserService)
+
+// WithAdmins makes users with the emails admins
+func WithAdmins(emails []string) Option {
+	return func(s *UserService) {
+		for _, email := range emails {
+			s.admins[strings.ToLower(strings.TrimSpace(email))] = true
+		}
+	}
+}
+
+func New(repo UserRepo, log *slog.Logger, opts ...Option) *UserService {
+	s := &UserService{repo: repo, log: log, admins: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // TODO: maybe add to config
@@ -145,3 +162,18 @@ func (s *UserService) ById(id string, withContext ...bool) (*model.User, error)
 	log.Info("user found by id successfully")
 	return user, nil
 }
+
+// IsAdmin checks that the user's email is one of the admins'
+func (s *UserService) IsAdmin(id string) (bool, error) {
+	if len(s.admins) == 0 {
+		return false, nil
+	}
+
+	user, err := s.ById(id)
+	if err != nil {
+		// no need for logs
+		return false, err
+	}
+
+	return s.admins[strings.ToLower(user.Email)], nil
+}
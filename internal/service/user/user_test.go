@@ -261,3 +261,30 @@ func TestUserService_ById(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_IsAdmin(t *testing.T) {
+	repo := mocks.NewUserRepo(t)
+	repo.On("ById", "1234").Return(&model.User{ID: "1234", Email: "Admin@Email.com"}, nil).Once()
+	repo.On("ById", "5678").Return(&model.User{ID: "5678", Email: "example@email.com"}, nil).Once()
+	repo.On("ById", "404").Return(nil, gorm.ErrRecordNotFound).Once()
+
+	s := user.New(repo, slog.Default(), user.WithAdmins([]string{" admin@email.com"}))
+
+	isAdmin, err := s.IsAdmin("1234")
+	if err != nil || !isAdmin {
+		t.Errorf("UserService.IsAdmin() = %v, %v, want true", isAdmin, err)
+	}
+	isAdmin, err = s.IsAdmin("5678")
+	if err != nil || isAdmin {
+		t.Errorf("UserService.IsAdmin() = %v, %v, want false", isAdmin, err)
+	}
+	if _, err = s.IsAdmin("404"); !errors.Is(err, service.ErrUserNotFound) {
+		t.Errorf("UserService.IsAdmin() error = %v, wantErr %v", err, service.ErrUserNotFound)
+	}
+
+	// nobody is an admin without the admins
+	isAdmin, err = user.New(repo, slog.Default()).IsAdmin("1234")
+	if err != nil || isAdmin {
+		t.Errorf("UserService.IsAdmin() = %v, %v, want false", isAdmin, err)
+	}
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/model"
+)
+
+// Headers of deliveries
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const maxErrorLength = 1024
+
+// ErrForbiddenAddress is returned by deliveries to endpoints resolved to internal addresses
+var ErrForbiddenAddress = errors.New("endpoint resolves to a forbidden address")
+
+// newClient returns the client of deliveries. Endpoints are set by users, so it doesn't dial loopback, private
+// and link-local addresses, e.g. the metadata service of the cloud. Addresses are checked after they're resolved,
+// so hosts resolved to internal addresses are rejected too. Proxies aren't used, they'd dial instead of the client
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// Run delivers due deliveries by the configured number of workers until the context is done.
+// Instances share the queue, a delivery is claimed by one of them at a time
+func (s *WebhookService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(s.cfg.Workers)
+	for range s.cfg.Workers {
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) work(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// a full batch means more deliveries are probably due
+		for ctx.Err() == nil {
+			if s.DeliverDue(ctx) < s.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue makes an attempt of each delivery of a claimed batch and returns the size of the batch.
+// Deliveries interrupted by the context are attempted again when their claim expires
+func (s *WebhookService) DeliverDue(ctx context.Context) int {
+	log := s.log.With(slog.String("op", "service.webhook.DeliverDue"))
+
+	// the claim outlives attempts of the whole batch
+	lease := s.cfg.Timeout * time.Duration(s.cfg.BatchSize+1)
+	deliveries, err := s.repo.ClaimDue(s.now().UTC(), lease, s.cfg.BatchSize)
+	if err != nil {
+		log.Error("failed to claim deliveries", sl.Err(err))
+		return 0
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		s.attempt(ctx, log, &deliveries[i])
+	}
+
+	return len(deliveries)
+}
+
+func (s *WebhookService) attempt(ctx context.Context, log *slog.Logger, delivery *model.WebhookDelivery) {
+	status, err := s.send(ctx, delivery)
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := s.now().UTC()
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+	case delivery.Attempts >= s.cfg.MaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = truncate(err.Error(), maxErrorLength)
+	default:
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		delivery.Error = truncate(err.Error(), maxErrorLength)
+	}
+
+	if err := s.repo.Finish(delivery); err != nil {
+		log.Error("failed to save delivery", sl.Err(err), slog.Int64("delivery", delivery.ID))
+		return
+	}
+	if delivery.Status == model.DeliveryFailed {
+		log.Warn("delivery failed", slog.Int64("delivery", delivery.ID), slog.String("webhook", delivery.WebhookID), slog.String("error", delivery.Error))
+	}
+}
+
+// send posts the payload and returns the response status. Statuses other than 2xx are errors
+func (s *WebhookService) send(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.Endpoint, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(delivery.Webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// the connection is reused only if the body is read
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff doubles the delay with every failed attempt
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.cfg.Backoff
+	for i := 1; i < attempts && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.MaxBackoff)
+}
+
+// Sign returns the hex HMAC-SHA256 of the timestamp, a dot and the body keyed by the webhook secret.
+// Receivers compare it with the X-Webhook-Signature header without its "sha256=" prefix
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncate cuts the string to n characters. Invalid UTF-8 is dropped, Postgres rejects it
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
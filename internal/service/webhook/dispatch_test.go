@@ -0,0 +1,198 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+	"url-shortener/internal/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service/webhook"
+	"url-shortener/internal/service/webhook/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testWebhooks = config.Webhooks{BatchSize: 10, Timeout: time.Second, MaxAttempts: 3, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}
+
+func TestWebhookService_DeliverDue(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	received := make(chan *http.Request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// the signature covers the timestamp and the body
+		want := "sha256=" + webhook.Sign("secret", r.Header.Get(webhook.HeaderTimestamp), body)
+		if r.Header.Get(webhook.HeaderSignature) != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- r
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer receiver.Close()
+
+	delivery := func(attempts int) model.WebhookDelivery {
+		return model.WebhookDelivery{
+			ID:        1,
+			WebhookID: "w1",
+			Event:     model.EventUrlCreated,
+			Payload:   `{"event":"url.created"}`,
+			Status:    model.DeliveryPending,
+			Attempts:  attempts,
+			Webhook:   model.Webhook{ID: "w1", Endpoint: receiver.URL, Secret: "secret"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		status      int
+		attempts    int
+		wantStatus  string
+		wantBackoff time.Duration
+	}{
+		{name: "success", status: http.StatusNoContent, attempts: 1, wantStatus: model.DeliverySucceeded},
+		{name: "retry", status: http.StatusInternalServerError, attempts: 1, wantStatus: model.DeliveryPending, wantBackoff: time.Minute},
+		{name: "backoff doubles", status: http.StatusInternalServerError, attempts: 2, wantStatus: model.DeliveryPending, wantBackoff: 2 * time.Minute},
+		{name: "redirect", status: http.StatusFound, attempts: 1, wantStatus: model.DeliveryPending, wantBackoff: time.Minute},
+		{name: "out of attempts", status: http.StatusBadGateway, attempts: 3, wantStatus: model.DeliveryFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status.Store(int32(tt.status))
+			r := mocks.NewWebhookRepo(t)
+			s := webhook.New(r, testWebhooks, slog.Default(), webhook.WithHTTPClient(receiver.Client()))
+
+			r.On("ClaimDue", mock.Anything, mock.Anything, 10).Return([]model.WebhookDelivery{delivery(tt.attempts)}, nil).Once()
+			r.On("Finish", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+				if d.Status != tt.wantStatus || d.ResponseStatus != tt.status || d.LastAttemptAt == nil {
+					return false
+				}
+				if tt.wantStatus == model.DeliverySucceeded {
+					return d.Error == ""
+				}
+				if tt.wantStatus == model.DeliveryPending {
+					backoff := d.NextAttemptAt.Sub(*d.LastAttemptAt)
+					if backoff != tt.wantBackoff {
+						return false
+					}
+				}
+				return strings.Contains(d.Error, "unexpected response status")
+			})).Return(nil).Once()
+
+			assert.Equal(t, 1, s.DeliverDue(context.Background()))
+
+			req := <-received
+			assert.Equal(t, model.EventUrlCreated, req.Header.Get(webhook.HeaderEvent))
+			assert.Equal(t, "1", req.Header.Get(webhook.HeaderDelivery))
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestWebhookService_DeliverDueUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	endpoint := receiver.URL
+	receiver.Close()
+
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, testWebhooks, slog.Default(), webhook.WithHTTPClient(&http.Client{}))
+
+	r.On("ClaimDue", mock.Anything, mock.Anything, 10).Return([]model.WebhookDelivery{{
+		ID:       1,
+		Status:   model.DeliveryPending,
+		Attempts: 1,
+		Webhook:  model.Webhook{Endpoint: endpoint, Secret: "secret"},
+	}}, nil).Once()
+	r.On("Finish", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		return d.Status == model.DeliveryPending && d.ResponseStatus == 0 && d.Error != ""
+	})).Return(nil).Once()
+
+	assert.Equal(t, 1, s.DeliverDue(context.Background()))
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestWebhookService_DeliverDueLongError(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New(strings.Repeat("é", 2000) + "\xff")
+	})}
+
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, testWebhooks, slog.Default(), webhook.WithHTTPClient(client))
+	// the client of the caller isn't changed
+	assert.Zero(t, client.Timeout)
+	assert.Nil(t, client.CheckRedirect)
+
+	r.On("ClaimDue", mock.Anything, mock.Anything, 10).Return([]model.WebhookDelivery{{
+		ID:       1,
+		Status:   model.DeliveryPending,
+		Attempts: 1,
+		Webhook:  model.Webhook{Endpoint: "https://example.com/hook", Secret: "secret"},
+	}}, nil).Once()
+	// the error is cut on a character boundary
+	r.On("Finish", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		return utf8.ValidString(d.Error) && utf8.RuneCountInString(d.Error) == 1024 && strings.HasSuffix(d.Error, "é")
+	})).Return(nil).Once()
+
+	assert.Equal(t, 1, s.DeliverDue(context.Background()))
+}
+
+func TestWebhookService_DeliverDueForbiddenAddress(t *testing.T) {
+	var received atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Store(true)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+	}{
+		{name: "loopback", endpoint: receiver.URL},
+		{name: "localhost", endpoint: strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)},
+		{name: "private", endpoint: "http://10.0.0.1"},
+		{name: "metadata", endpoint: "http://169.254.169.254/latest/meta-data"},
+		{name: "unspecified", endpoint: "http://0.0.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewWebhookRepo(t)
+			s := webhook.New(r, testWebhooks, slog.Default())
+
+			r.On("ClaimDue", mock.Anything, mock.Anything, 10).Return([]model.WebhookDelivery{{
+				ID:       1,
+				Status:   model.DeliveryPending,
+				Attempts: 1,
+				Webhook:  model.Webhook{Endpoint: tt.endpoint, Secret: "secret"},
+			}}, nil).Once()
+			r.On("Finish", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+				return d.Status == model.DeliveryPending && d.ResponseStatus == 0 && strings.Contains(d.Error, webhook.ErrForbiddenAddress.Error())
+			})).Return(nil).Once()
+
+			assert.Equal(t, 1, s.DeliverDue(context.Background()))
+			assert.False(t, received.Load())
+		})
+	}
+}
+
+func TestWebhookService_DeliverDueCancelled(t *testing.T) {
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, testWebhooks, slog.Default())
+
+	// interrupted deliveries are attempted again when their claim expires
+	r.On("ClaimDue", mock.Anything, 11*time.Second, 10).Return([]model.WebhookDelivery{{ID: 1, Webhook: model.Webhook{Endpoint: "http://localhost"}}}, nil).Once()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, 1, s.DeliverDue(ctx))
+}
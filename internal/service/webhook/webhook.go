@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pg"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/util/nanoid"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name=WebhookRepo
+type WebhookRepo interface {
+	Create(webhook *model.Webhook) error
+	ByUserID(userID string) ([]model.Webhook, error)
+	Delete(id, userID string) error
+	Subscribers(userID, event string) ([]string, error)
+	UrlSubscribers(event string, urlIDs []string) ([]repo.UrlWebhook, error)
+	AdvanceMilestone(urlID string, milestones []int64) (*model.Url, []int64, error)
+	Enqueue(deliveries []*model.WebhookDelivery) error
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	Finish(delivery *model.WebhookDelivery) error
+	Deliveries(webhookID, userID string, limit int) ([]model.WebhookDelivery, error)
+	Redeliver(id int64, webhookID, userID string, now time.Time) (*model.WebhookDelivery, error)
+}
+
+// WebhookService manages webhooks of users and queues events of their urls for delivery, see Run
+type WebhookService struct {
+	repo   WebhookRepo
+	cfg    config.Webhooks
+	log    *slog.Logger
+	client *http.Client
+	now    func() time.Time
+}
+
+type Option func(s *WebhookService)
+
+// WithHTTPClient replaces the client of deliveries, e.g. to reach a test server on a loopback address.
+// The client is copied, so its timeout and redirect policy are overridden only for deliveries
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *WebhookService) {
+		c := *client
+		s.client = &c
+	}
+}
+
+var defaultWebhooks = config.Webhooks{
+	Workers:      2,
+	PollInterval: time.Second,
+	BatchSize:    20,
+	Timeout:      10 * time.Second,
+	MaxAttempts:  8,
+	Backoff:      30 * time.Second,
+	MaxBackoff:   6 * time.Hour,
+}
+
+func New(repo WebhookRepo, cfg config.Webhooks, log *slog.Logger, opts ...Option) *WebhookService {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWebhooks.Workers
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultWebhooks.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhooks.BatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhooks.Timeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhooks.MaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultWebhooks.Backoff
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = max(defaultWebhooks.MaxBackoff, cfg.Backoff)
+	}
+	slices.Sort(cfg.Milestones)
+
+	s := &WebhookService{repo: repo, cfg: cfg, log: log, client: newClient(), now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.client.Timeout = cfg.Timeout
+	// a redirect is a failed delivery, the endpoint has to be updated
+	s.client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return s
+}
+
+// maxDeliveries limits the delivery log returned at once
+const maxDeliveries = 100
+
+const idAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+const idSize = 12
+
+var idGenerator = nanoid.New(idAlphabet, idSize)
+
+// Create registers the user's endpoint. The returned secret signs deliveries
+func (s *WebhookService) Create(webhookDto *dto.CreateWebhook, userID string) (*model.Webhook, error) {
+	log := s.log.With(slog.String("op", "service.webhook.Create"))
+
+	if err := service.Validate.Struct(webhookDto); err != nil {
+		log.Info("validation failed", sl.Err(err))
+		return nil, service.PrettyValidationError(err.(validator.ValidationErrors))
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	events := slices.Clone(webhookDto.Events)
+	slices.Sort(events)
+	webhook := &model.Webhook{
+		UserID:    userID,
+		Endpoint:  webhookDto.Endpoint,
+		Secret:    hex.EncodeToString(secret),
+		Events:    strings.Join(slices.Compact(events), ","),
+		CreatedAt: s.now().UTC(),
+	}
+
+GenerateID:
+	id, err := idGenerator.ID()
+	if err != nil {
+		log.Error("failed to generate id", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+	webhook.ID = id
+
+	if err := s.repo.Create(webhook); err != nil {
+		log.Error("failed to create webhook", sl.Err(err))
+		if pgErr := pg.ParsePGError(err); pgErr != nil {
+			if pgErr.Code == "23503" { // 23503 = foreign_key_violation
+				return nil, service.ErrRelatedResourceNotFound
+			}
+			if pgErr.Code == "23505" { // 23505 = unique_violation
+				goto GenerateID
+			}
+		}
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("webhook successfully created")
+	return webhook, nil
+}
+
+func (s *WebhookService) ByUserID(userID string) ([]model.Webhook, error) {
+	log := s.log.With(slog.String("op", "service.webhook.ByUserID"))
+
+	webhooks, err := s.repo.ByUserID(userID)
+	if err != nil {
+		log.Error("failed to get webhooks", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("got webhooks successfully")
+	return webhooks, nil
+}
+
+// Delete deletes the webhook with its delivery log, queued deliveries are dropped
+func (s *WebhookService) Delete(id, userID string) error {
+	log := s.log.With(slog.String("op", "service.webhook.Delete"))
+
+	if err := s.repo.Delete(id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("webhook not found")
+			return service.ErrWebhookNotFound
+		}
+		log.Error("failed to delete webhook", sl.Err(err))
+		return service.ErrInternalError
+	}
+
+	log.Info("webhook successfully deleted")
+	return nil
+}
+
+// Deliveries returns the latest deliveries of the user's webhook
+func (s *WebhookService) Deliveries(webhookID, userID string, limit int) ([]model.WebhookDelivery, error) {
+	log := s.log.With(slog.String("op", "service.webhook.Deliveries"))
+
+	if limit < 1 || limit > maxDeliveries {
+		log.Info("invalid limit", slog.Int("limit", limit))
+		return nil, fmt.Errorf("%w%s", service.ErrValidation, fmt.Sprintf("limit must be between 1 and %d", maxDeliveries))
+	}
+
+	deliveries, err := s.repo.Deliveries(webhookID, userID, limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("webhook not found")
+			return nil, service.ErrWebhookNotFound
+		}
+		log.Error("failed to get deliveries", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("got deliveries successfully")
+	return deliveries, nil
+}
+
+// Redeliver queues the event of the delivery again, whatever the status of the delivery is
+func (s *WebhookService) Redeliver(id int64, webhookID, userID string) (*model.WebhookDelivery, error) {
+	log := s.log.With(slog.String("op", "service.webhook.Redeliver"))
+
+	delivery, err := s.repo.Redeliver(id, webhookID, userID, s.now().UTC())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info("delivery not found")
+			return nil, service.ErrWebhookDeliveryNotFound
+		}
+		log.Error("failed to redeliver", sl.Err(err))
+		return nil, service.ErrInternalError
+	}
+
+	log.Info("delivery successfully queued")
+	return delivery, nil
+}
+
+// PublishUrlEvent queues the event of the url for webhooks of its owner. Anonymous urls have no webhooks
+func (s *WebhookService) PublishUrlEvent(event string, url *model.Url) {
+	if url.UserID == "" {
+		return
+	}
+	log := s.log.With(slog.String("op", "service.webhook.PublishUrlEvent"), slog.String("event", event))
+
+	webhookIDs, err := s.repo.Subscribers(url.UserID, event)
+	if err != nil {
+		log.Error("failed to get subscribers", sl.Err(err))
+		return
+	}
+
+	s.enqueue(log, s.deliveries(log, event, dto.ToPublicUrl(url), webhookIDs...))
+}
+
+// Publish queues click.recorded events of the clicks and click.milestone events of urls whose total hits reached milestones
+func (s *WebhookService) Publish(clicks []*model.ClickStat) {
+	log := s.log.With(slog.String("op", "service.webhook.Publish"))
+
+	urlIDs := make([]string, 0, len(clicks))
+	for _, click := range clicks {
+		urlIDs = append(urlIDs, click.UrlID)
+	}
+	slices.Sort(urlIDs)
+	urlIDs = slices.Compact(urlIDs)
+
+	subscribers, err := s.repo.UrlSubscribers(model.EventClickRecorded, urlIDs)
+	if err != nil {
+		log.Error("failed to get subscribers", sl.Err(err))
+	}
+	webhookIDs := byUrl(subscribers)
+	var deliveries []*model.WebhookDelivery
+	for _, click := range clicks {
+		deliveries = append(deliveries, s.deliveries(log, model.EventClickRecorded, dto.ToLiveClick(click), webhookIDs[click.UrlID]...)...)
+	}
+	s.enqueue(log, deliveries)
+
+	if len(s.cfg.Milestones) == 0 {
+		return
+	}
+	subscribers, err = s.repo.UrlSubscribers(model.EventClickMilestone, urlIDs)
+	if err != nil {
+		log.Error("failed to get subscribers", sl.Err(err))
+		return
+	}
+	deliveries = nil
+	// milestones are tracked only for urls with subscribers
+	for urlID, webhookIDs := range byUrl(subscribers) {
+		url, reached, err := s.repo.AdvanceMilestone(urlID, s.cfg.Milestones)
+		if err != nil {
+			log.Error("failed to advance milestone", sl.Err(err))
+			continue
+		}
+		for _, milestone := range reached {
+			data := dto.MilestoneReached{Alias: url.ID, Link: url.Link, Milestone: milestone, TotalHits: url.TotalHits}
+			deliveries = append(deliveries, s.deliveries(log, model.EventClickMilestone, data, webhookIDs...)...)
+		}
+	}
+	s.enqueue(log, deliveries)
+}
+
+// deliveries makes one pending delivery of the event per webhook
+func (s *WebhookService) deliveries(log *slog.Logger, event string, data any, webhookIDs ...string) []*model.WebhookDelivery {
+	if len(webhookIDs) == 0 {
+		return nil
+	}
+
+	now := s.now().UTC()
+	payload, err := json.Marshal(dto.WebhookEvent{Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		log.Error("failed to marshal event", sl.Err(err))
+		return nil
+	}
+
+	deliveries := make([]*model.WebhookDelivery, len(webhookIDs))
+	for i, webhookID := range webhookIDs {
+		deliveries[i] = &model.WebhookDelivery{
+			WebhookID:     webhookID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+	}
+	return deliveries
+}
+
+// enqueue saves the deliveries. Events are lost if they can't be queued
+func (s *WebhookService) enqueue(log *slog.Logger, deliveries []*model.WebhookDelivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+	if err := s.repo.Enqueue(deliveries); err != nil {
+		log.Error("failed to queue deliveries", sl.Err(err), slog.Int("deliveries", len(deliveries)))
+	}
+}
+
+func byUrl(subscribers []repo.UrlWebhook) map[string][]string {
+	webhookIDs := make(map[string][]string)
+	for _, sub := range subscribers {
+		webhookIDs[sub.UrlID] = append(webhookIDs[sub.UrlID], sub.WebhookID)
+	}
+	return webhookIDs
+}
@@ -0,0 +1,149 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service"
+	"url-shortener/internal/service/webhook"
+	"url-shortener/internal/service/webhook/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWebhookService_Create(t *testing.T) {
+	tests := []struct {
+		name       string
+		webhookDto *dto.CreateWebhook
+		wantEvents string
+		wantErr    error
+	}{
+		{
+			name:       "success",
+			webhookDto: &dto.CreateWebhook{Endpoint: "https://example.com/hook", Events: []string{model.EventUrlCreated, model.EventClickRecorded, model.EventUrlCreated}},
+			wantEvents: "click.recorded,url.created",
+		},
+		{
+			name:       "unknown event",
+			webhookDto: &dto.CreateWebhook{Endpoint: "https://example.com/hook", Events: []string{"url.viewed"}},
+			wantErr:    service.ErrValidation,
+		},
+		{
+			name:       "without events",
+			webhookDto: &dto.CreateWebhook{Endpoint: "https://example.com/hook"},
+			wantErr:    service.ErrValidation,
+		},
+		{
+			name:       "not http endpoint",
+			webhookDto: &dto.CreateWebhook{Endpoint: "ftp://example.com/hook", Events: []string{model.EventUrlCreated}},
+			wantErr:    service.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewWebhookRepo(t)
+			if tt.wantErr == nil {
+				r.On("Create", mock.AnythingOfType("*model.Webhook")).Return(nil).Once()
+			}
+
+			s := webhook.New(r, config.Webhooks{}, slog.Default())
+			got, err := s.Create(tt.webhookDto, "1234")
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantEvents, got.Events)
+				assert.Len(t, got.Secret, 64)
+				assert.NotEmpty(t, got.ID)
+			}
+		})
+	}
+}
+
+func TestWebhookService_PublishUrlEvent(t *testing.T) {
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, config.Webhooks{}, slog.Default())
+
+	url := &model.Url{ID: "alias", Link: "https://example.com", UserID: "1234"}
+	r.On("Subscribers", "1234", model.EventUrlCreated).Return([]string{"a", "b"}, nil).Once()
+	r.On("Enqueue", mock.MatchedBy(func(d []*model.WebhookDelivery) bool {
+		var event dto.WebhookEvent
+		return len(d) == 2 && d[0].WebhookID == "a" && d[1].WebhookID == "b" &&
+			d[0].Status == model.DeliveryPending &&
+			json.Unmarshal([]byte(d[0].Payload), &event) == nil && event.Event == model.EventUrlCreated
+	})).Return(nil).Once()
+	s.PublishUrlEvent(model.EventUrlCreated, url)
+
+	// nothing is queued without subscribers
+	r.On("Subscribers", "1234", model.EventUrlDeleted).Return(nil, nil).Once()
+	s.PublishUrlEvent(model.EventUrlDeleted, url)
+
+	// anonymous urls have no owner
+	s.PublishUrlEvent(model.EventUrlCreated, &model.Url{ID: "anon"})
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, config.Webhooks{Milestones: []int64{1000, 100}}, slog.Default())
+
+	clicks := []*model.ClickStat{{UrlID: "b"}, {UrlID: "a"}, {UrlID: "a"}}
+	r.On("UrlSubscribers", model.EventClickRecorded, []string{"a", "b"}).
+		Return([]repo.UrlWebhook{{UrlID: "a", WebhookID: "w1"}}, nil).Once()
+	// one delivery per click of the subscribed url
+	r.On("Enqueue", mock.MatchedBy(func(d []*model.WebhookDelivery) bool {
+		return len(d) == 2 && d[0].Event == model.EventClickRecorded
+	})).Return(nil).Once()
+
+	r.On("UrlSubscribers", model.EventClickMilestone, []string{"a", "b"}).
+		Return([]repo.UrlWebhook{{UrlID: "b", WebhookID: "w2"}}, nil).Once()
+	r.On("AdvanceMilestone", "b", []int64{100, 1000}).
+		Return(&model.Url{ID: "b", TotalHits: 1000}, []int64{100, 1000}, nil).Once()
+	r.On("Enqueue", mock.MatchedBy(func(d []*model.WebhookDelivery) bool {
+		var event struct{ Data dto.MilestoneReached }
+		return len(d) == 2 && d[0].Event == model.EventClickMilestone && d[0].WebhookID == "w2" &&
+			json.Unmarshal([]byte(d[1].Payload), &event) == nil && event.Data.Milestone == 1000
+	})).Return(nil).Once()
+
+	s.Publish(clicks)
+}
+
+func TestWebhookService_Deliveries(t *testing.T) {
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, config.Webhooks{}, slog.Default())
+
+	r.On("Deliveries", "w1", "1234", 50).Return([]model.WebhookDelivery{{ID: 1}}, nil).Once()
+	deliveries, err := s.Deliveries("w1", "1234", 50)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	_, err = s.Deliveries("w1", "1234", 1000)
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	r.On("Deliveries", "w1", "5678", 50).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.Deliveries("w1", "5678", 50)
+	assert.Equal(t, service.ErrWebhookNotFound, err)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	r := mocks.NewWebhookRepo(t)
+	s := webhook.New(r, config.Webhooks{}, slog.Default())
+
+	r.On("Redeliver", int64(1), "w1", "1234", mock.Anything).Return(&model.WebhookDelivery{ID: 2, Status: model.DeliveryPending}, nil).Once()
+	delivery, err := s.Redeliver(1, "w1", "1234")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), delivery.ID)
+
+	r.On("Redeliver", int64(1), "w1", "5678", mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = s.Redeliver(1, "w1", "5678")
+	assert.Equal(t, service.ErrWebhookDeliveryNotFound, err)
+
+	r.On("Redeliver", int64(1), "w1", "1234", mock.Anything).Return(nil, errors.New("unexpected")).Once()
+	_, err = s.Redeliver(1, "w1", "1234")
+	assert.Equal(t, service.ErrInternalError, err)
+}
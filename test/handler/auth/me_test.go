@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+	"url-shortener/internal/config"
 	"url-shortener/internal/database/repo"
 	"url-shortener/internal/http/api"
 	"url-shortener/internal/http/handler"
@@ -14,6 +15,7 @@ import (
 	"url-shortener/internal/http/route"
 	"url-shortener/internal/model/dto"
 	"url-shortener/internal/service/auth"
+	"url-shortener/internal/service/plan"
 	"url-shortener/internal/service/user"
 	"url-shortener/internal/testutils/testdb"
 
@@ -33,9 +35,13 @@ func TestMeHandler(t *testing.T) {
 	userService := user.New(userRepo, log)
 	jwtService := auth.NewJWTService("secret", time.Hour)
 	authService := auth.New(userService, jwtService, log)
+	planService := plan.New(repo.NewUsageRepo(db), config.Plans{
+		Default: "free",
+		Tiers:   map[string]config.Plan{"free": {MaxLinks: 10}},
+	}, log)
 
 	r := gin.New()
-	route.Auth(r, log, &handler.Dependencies{AuthService: authService, UserService: userService, JwtService: jwtService})
+	route.Auth(r, log, &handler.Dependencies{AuthService: authService, UserService: userService, JwtService: jwtService, PlanService: planService})
 
 	// create test user
 	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
@@ -88,6 +94,8 @@ func TestMeHandler(t *testing.T) {
 					return
 				}
 				assert.Equal(t, dto.ToPublicUser(user), body.User)
+				assert.Equal(t, "free", body.Usage.Plan)
+				assert.Equal(t, dto.UsageLimit{Used: 0, Limit: 10}, body.Usage.Links)
 			} else {
 				// error
 				var body api.ErrorResponse
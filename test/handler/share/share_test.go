@@ -0,0 +1,172 @@
+package share_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	shared_stats "url-shortener/internal/http/handler/public/shared-stats"
+	"url-shortener/internal/http/handler/share/grant"
+	"url-shortener/internal/http/handler/share/list"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/share"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareHandlers(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users", "urls", "stats_shares", "url_transfers")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	shareRepo := repo.NewStatsShareRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+	shareService := share.New(shareRepo, clickStatService, log)
+
+	// test users
+	alice, aliceToken, err := authService.Register(&dto.CreateUser{Email: "alice@example.com", Password: "12345678"})
+	require.NoError(t, err)
+	bob, bobToken, err := authService.Register(&dto.CreateUser{Email: "bob@example.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url with clicks for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, alice.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{
+		UrlService:       urlService,
+		JwtService:       jwtService,
+		ClickStatService: clickStatService,
+		ShareService:     shareService,
+	}
+	route.Url(r, r, log, deps)
+	route.Share(r, log, deps)
+
+	for range 3 {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+testUrl.ID, nil))
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	errorOf := func(t *testing.T, res *httptest.ResponseRecorder) string {
+		var body api.ErrorResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		return body.Error
+	}
+
+	t.Run("url of another user", func(t *testing.T) {
+		res := do(http.MethodPost, "/url/"+testUrl.ID+"/share", bobToken, "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Equal(t, "url not found", errorOf(t, res))
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		res := do(http.MethodGet, "/public/stats/notfound", "", "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Equal(t, "share not found", errorOf(t, res))
+	})
+
+	t.Run("share and revoke", func(t *testing.T) {
+		res := do(http.MethodPost, "/url/"+testUrl.ID+"/share", aliceToken, "")
+		require.Equal(t, http.StatusCreated, res.Code)
+		var created grant.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+		require.NotEmpty(t, created.Token)
+		assert.False(t, created.HideBreakdowns)
+
+		// the same series as the owner gets
+		res = do(http.MethodGet, "/public/stats/"+created.Token, "", "")
+		require.Equal(t, http.StatusOK, res.Code)
+		var stats shared_stats.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &stats))
+		want, err := clickStatService.Stats(testUrl.ID, alice.ID, &dto.StatsQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, want[len(want)-1].Count, stats[len(stats)-1].Count)
+		assert.Equal(t, int64(3), stats[len(stats)-1].Count)
+
+		res = do(http.MethodGet, "/public/stats/"+created.Token+"/breakdown/browser", "", "")
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = do(http.MethodGet, "/url/"+testUrl.ID+"/share", aliceToken, "")
+		require.Equal(t, http.StatusOK, res.Code)
+		var shares list.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &shares))
+		require.Len(t, shares, 1)
+		assert.Equal(t, created.ID, shares[0].ID)
+
+		// only the owner revokes
+		res = do(http.MethodDelete, "/url/"+testUrl.ID+"/share/"+created.ID, bobToken, "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		res = do(http.MethodDelete, "/url/"+testUrl.ID+"/share/"+created.ID, aliceToken, "")
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = do(http.MethodGet, "/public/stats/"+created.Token, "", "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("hidden breakdowns", func(t *testing.T) {
+		res := do(http.MethodPost, "/url/"+testUrl.ID+"/share", aliceToken, `{"hideBreakdowns":true}`)
+		require.Equal(t, http.StatusCreated, res.Code)
+		var created grant.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+		assert.True(t, created.HideBreakdowns)
+
+		res = do(http.MethodGet, "/public/stats/"+created.Token, "", "")
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = do(http.MethodGet, "/public/stats/"+created.Token+"/breakdown/browser", "", "")
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		assert.Equal(t, "breakdowns aren't shared", errorOf(t, res))
+	})
+
+	t.Run("transferred url", func(t *testing.T) {
+		res := do(http.MethodPost, "/url/"+testUrl.ID+"/share", aliceToken, "")
+		require.Equal(t, http.StatusCreated, res.Code)
+		var created grant.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+
+		transferRepo := repo.NewUrlTransferRepo(db)
+		transfer := &model.UrlTransfer{ID: "transfer", FromUserID: alice.ID, ToUserID: bob.ID, Items: []model.UrlTransferItem{{UrlID: testUrl.ID}}}
+		require.NoError(t, transferRepo.Create(transfer))
+		require.NoError(t, transferRepo.Accept(transfer.ID, bob.ID, repo.LinkLimits{}))
+
+		// shares of the sender don't show stats of the recipient
+		res = do(http.MethodGet, "/public/stats/"+created.Token, "", "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
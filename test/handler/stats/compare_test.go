@@ -0,0 +1,128 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/stats/compare"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareHandler(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test users
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	other, _, err := authService.Register(&dto.CreateUser{Email: "other@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// urls for test
+	variantA, err := urlService.Create(&dto.CreateUrl{Link: "https://example.com/a"}, user.ID)
+	require.NoError(t, err)
+	variantB, err := urlService.Create(&dto.CreateUrl{Link: "https://example.com/b"}, user.ID)
+	require.NoError(t, err)
+	otherUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://example.org"}, other.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService}
+	route.Url(r, r, log, deps)
+	route.Stats(r, log, deps)
+
+	// clicks for test
+	for alias, clicks := range map[string]int{variantA.ID: 3, variantB.ID: 1} {
+		for range clicks {
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+			require.Equal(t, http.StatusFound, res.Code)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:     "success",
+			query:    "?ids=" + variantA.ID + "," + variantB.ID + "," + variantA.ID + "&granularity=week",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "no ids",
+			query:     "?ids=",
+			wantCode:  http.StatusBadRequest,
+			wantError: "field UrlIDs is a required field",
+		},
+		{
+			name:      "other user's url",
+			query:     "?ids=" + variantA.ID + "," + otherUrl.ID,
+			wantCode:  http.StatusNotFound,
+			wantError: "url statistics not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stats/compare"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+
+			assert.Equal(t, tt.wantCode, res.Code)
+
+			if tt.wantError == "" {
+				var body compare.SuccessResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, "week", body.Granularity)
+				assert.Equal(t, int64(4), body.TotalClicks)
+				// repeated ids are compared once, series have the same buckets
+				require.Len(t, body.Urls, 2)
+				assert.Equal(t, variantA.ID, body.Urls[0].Alias)
+				assert.Equal(t, int64(3), body.Urls[0].TotalClicks)
+				require.NotNil(t, body.Urls[0].Share)
+				assert.Equal(t, 75.0, *body.Urls[0].Share)
+				require.NotNil(t, body.Urls[1].Share)
+				assert.Equal(t, 25.0, *body.Urls[1].Share)
+				require.Len(t, body.Urls[1].Series, len(body.Urls[0].Series))
+				for i := range body.Urls[0].Series {
+					assert.True(t, body.Urls[0].Series[i].Day.Equal(body.Urls[1].Series[i].Day))
+				}
+			} else {
+				var body api.ErrorResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantError, body.Error)
+			}
+		})
+	}
+}
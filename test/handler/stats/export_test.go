@@ -0,0 +1,164 @@
+package stats_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/stats/export"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandler(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// urls for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+	otherUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://example.com"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService}
+	route.Url(r, r, log, deps)
+	route.Stats(r, log, deps)
+
+	// clicks for test
+	for _, alias := range []string{testUrl.ID, testUrl.ID, otherUrl.ID} {
+		req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+		req.Header.Set("Referer", "https://www.google.com/search")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+
+	get := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("url series as csv", func(t *testing.T) {
+		res := get(t, "/url/"+testUrl.ID+"/export")
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(res.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 32)
+		assert.Equal(t, []string{"bucket", "clicks", "raw_clicks", "uniques"}, records[0])
+		assert.Equal(t, "2", records[31][1])
+	})
+
+	t.Run("all clicks as ndjson", func(t *testing.T) {
+		res := get(t, "/stats/export?kind=clicks&format=ndjson")
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+
+		var clicks []map[string]any
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			var click map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &click))
+			clicks = append(clicks, click)
+		}
+		require.Len(t, clicks, 3)
+		assert.Equal(t, "google.com", clicks[0]["referrer"])
+	})
+
+	t.Run("no clicks", func(t *testing.T) {
+		res := get(t, "/url/"+testUrl.ID+"/export?kind=clicks&from=2020-01-01&to=2020-01-31")
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "alias,created_at,referrer,browser,os,device,language,country,bot,duplicate", strings.TrimSpace(res.Body.String()))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for path, want := range map[string]string{
+			"/stats/export?format=xlsx":           "query parameter `format` is invalid",
+			"/stats/export?kind=visitors":         "query parameter `kind` is invalid",
+			"/url/notfound/export?kind=clicks":    "url statistics not found",
+			"/url/" + testUrl.ID + "/export?tz=X": "unknown time zone X",
+		} {
+			res := get(t, path)
+			var body api.ErrorResponse
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+			assert.Equal(t, want, body.Error, path)
+		}
+	})
+}
+
+// slowExporter reads clicks slower than the write timeout of the server
+type slowExporter struct {
+	clicks int
+	delay  time.Duration
+}
+
+func (e *slowExporter) ExportSeries(urlID, userID string, query *dto.StatsQuery) ([]repo.DailyCount, error) {
+	return nil, nil
+}
+
+func (e *slowExporter) ExportClicks(urlID, userID string, query *dto.StatsQuery, each func(click *model.ClickStat) error) error {
+	for range e.clicks {
+		time.Sleep(e.delay)
+		if err := each(&model.ClickStat{UrlID: urlID, CreatedAt: time.Now().UTC()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportHandler_WriteTimeout(t *testing.T) {
+	r := gin.New()
+	r.GET("/url/:id/export", func(c *gin.Context) { c.Set("user_id", "1234") }, export.New(slog.Default(), &slowExporter{clicks: 3, delay: 100 * time.Millisecond}))
+
+	server := httptest.NewUnstartedServer(r)
+	server.Config.WriteTimeout = 150 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/url/alias/export?kind=clicks")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// the whole export is written after the timeout
+	rows, err := csv.NewReader(res.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 4)
+}
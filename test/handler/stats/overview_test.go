@@ -0,0 +1,118 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/stats/overview"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverviewHandler(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// urls for test
+	popularUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+	otherUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://example.com"}, user.ID)
+	require.NoError(t, err)
+	_, err = urlService.Create(&dto.CreateUrl{Link: "https://example.org"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService}
+	route.Url(r, r, log, deps)
+	route.Stats(r, log, deps)
+
+	// clicks for test
+	for alias, clicks := range map[string]int{popularUrl.ID: 3, otherUrl.ID: 1} {
+		for range clicks {
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+			require.Equal(t, http.StatusFound, res.Code)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:     "success",
+			query:    "?top=1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "invalid top",
+			query:     "?top=many",
+			wantCode:  http.StatusBadRequest,
+			wantError: "query parameter `top` is invalid",
+		},
+		{
+			name:      "invalid granularity",
+			query:     "?granularity=year",
+			wantCode:  http.StatusBadRequest,
+			wantError: "field Granularity is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stats/overview"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+
+			assert.Equal(t, tt.wantCode, res.Code)
+
+			if tt.wantError == "" {
+				var body overview.SuccessResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, int64(4), body.TotalClicks)
+				assert.Zero(t, body.PreviousTotalClicks)
+				assert.Nil(t, body.ClicksChange)
+				assert.Equal(t, int64(3), body.NewUrls)
+				assert.Len(t, body.Series, 31)
+				assert.Equal(t, []repo.UrlClicks{{ID: popularUrl.ID, Link: "https://google.com", Clicks: 3}}, body.TopUrls)
+			} else {
+				var body api.ErrorResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantError, body.Error)
+			}
+		})
+	}
+}
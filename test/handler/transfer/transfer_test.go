@@ -0,0 +1,176 @@
+package transfer_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/transfer/offer"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/transfer"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferHandlers(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users", "urls", "url_transfers")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	transferRepo := repo.NewUrlTransferRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+	transferService := transfer.New(transferRepo, userService, log)
+
+	// test users
+	alice, aliceToken, err := authService.Register(&dto.CreateUser{Email: "alice@example.com", Password: "12345678"})
+	require.NoError(t, err)
+	bob, bobToken, err := authService.Register(&dto.CreateUser{Email: "bob@example.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url with clicks for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, alice.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{
+		UrlService:       urlService,
+		JwtService:       jwtService,
+		ClickStatService: clickStatService,
+		TransferService:  transferService,
+	}
+	route.Url(r, r, log, deps)
+	route.Transfer(r, log, deps)
+
+	for range 3 {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+testUrl.ID, nil))
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			body      string
+			wantCode  int
+			wantError string
+		}{
+			{
+				name:      "to yourself",
+				body:      fmt.Sprintf(`{"email":"%s","urlIds":["%s"]}`, alice.Email, testUrl.ID),
+				wantCode:  http.StatusBadRequest,
+				wantError: "can't transfer urls to yourself",
+			},
+			{
+				name:      "recipient not found",
+				body:      fmt.Sprintf(`{"email":"nobody@example.com","urlIds":["%s"]}`, testUrl.ID),
+				wantCode:  http.StatusNotFound,
+				wantError: "user not found",
+			},
+			{
+				name:      "url of another user",
+				body:      fmt.Sprintf(`{"email":"%s","urlIds":["notfound"]}`, bob.Email),
+				wantCode:  http.StatusConflict,
+				wantError: "some urls are not owned by the sender",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				res := do(http.MethodPost, "/transfer", aliceToken, tt.body)
+				assert.Equal(t, tt.wantCode, res.Code)
+
+				var body api.ErrorResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantError, body.Error)
+			})
+		}
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		res := do(http.MethodPost, "/transfer", aliceToken, fmt.Sprintf(`{"email":"%s","urlIds":["%s"]}`, bob.Email, testUrl.ID))
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		var created offer.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+		assert.Equal(t, model.TransferPending, created.Status)
+
+		// still owned by the sender until accepted
+		u, err := urlService.ByID(testUrl.ID)
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, u.UserID)
+
+		// sender can't accept
+		res = do(http.MethodPost, "/transfer/"+created.ID+"/accept", aliceToken, "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = do(http.MethodPost, "/transfer/"+created.ID+"/accept", bobToken, "")
+		require.Equal(t, http.StatusOK, res.Code)
+
+		u, err = urlService.ByID(testUrl.ID)
+		require.NoError(t, err)
+		assert.Equal(t, bob.ID, u.UserID)
+		assert.Equal(t, int64(3), u.TotalHits)
+
+		stats, err := clickStatService.Stats(testUrl.ID, bob.ID, &dto.StatsQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stats[len(stats)-1].Count)
+
+		// accepted transfer is not pending anymore
+		res = do(http.MethodPost, "/transfer/"+created.ID+"/accept", bobToken, "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		res := do(http.MethodPost, "/transfer", bobToken, fmt.Sprintf(`{"email":"%s","urlIds":["%s"]}`, alice.Email, testUrl.ID))
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		var created offer.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+
+		res = do(http.MethodGet, "/transfer", aliceToken, "")
+		require.Equal(t, http.StatusOK, res.Code)
+		var pending []dto.PublicTransfer
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &pending))
+		require.Len(t, pending, 1)
+		assert.Equal(t, created.ID, pending[0].ID)
+
+		res = do(http.MethodDelete, "/transfer/"+created.ID, aliceToken, "")
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = do(http.MethodPost, "/transfer/"+created.ID+"/accept", aliceToken, "")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
@@ -0,0 +1,127 @@
+package url_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/anonymous/manage"
+	"url-shortener/internal/http/handler/url/create"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/lib/ratelimit"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymousUrl(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users", "urls")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log, url.WithAnonymousTTL(time.Hour))
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	_, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{
+		UrlService:       urlService,
+		JwtService:       jwtService,
+		ClickStatService: clickStatService,
+		AnonymousLimiter: ratelimit.New(2, time.Hour),
+	}
+	route.Url(r, r, log, deps)
+	route.Anonymous(r, log, deps)
+
+	do := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	errorOf := func(res *httptest.ResponseRecorder) string {
+		var body api.ErrorResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		return body.Error
+	}
+
+	// create
+	res := do(http.MethodPost, "/url", `{"link":"https://google.com"}`, nil)
+	require.Equal(t, http.StatusCreated, res.Code)
+	var created create.AnonymousSuccessResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ClaimToken)
+	require.NotNil(t, created.ExpiresAt)
+
+	// redirect works
+	res = do(http.MethodGet, "/"+created.Alias, "", nil)
+	assert.Equal(t, http.StatusFound, res.Code)
+
+	// alias isn't allowed
+	res = do(http.MethodPost, "/url", `{"alias":"g","link":"https://google.com"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "field Alias requires authorization", errorOf(res))
+
+	// rate limit
+	res = do(http.MethodPost, "/url", `{"link":"https://google.com"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+
+	// manage by token
+	res = do(http.MethodGet, "/anonymous/"+created.Alias, "", map[string]string{"X-Claim-Token": created.ClaimToken})
+	require.Equal(t, http.StatusOK, res.Code)
+	var managed manage.SuccessResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &managed))
+	assert.Equal(t, int64(1), managed.Url.TotalHits)
+	assert.Equal(t, int64(1), managed.Stats[len(managed.Stats)-1].Count)
+
+	res = do(http.MethodGet, "/anonymous/"+created.Alias, "", map[string]string{"X-Claim-Token": "invalid"})
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Equal(t, "invalid claim token", errorOf(res))
+
+	// claim
+	auth := map[string]string{"Authorization": "Bearer " + token}
+	res = do(http.MethodPost, "/url/"+created.Alias+"/claim", `{"token":"invalid"}`, auth)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	res = do(http.MethodPost, "/url/"+created.Alias+"/claim", `{"token":"`+created.ClaimToken+`"}`, auth)
+	require.Equal(t, http.StatusOK, res.Code)
+	var claimed dto.PublicUrl
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &claimed))
+	assert.Nil(t, claimed.ExpiresAt)
+
+	// the token doesn't work after claiming
+	res = do(http.MethodDelete, "/anonymous/"+created.Alias, "", map[string]string{"X-Claim-Token": created.ClaimToken})
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	// owner has stats now
+	res = do(http.MethodGet, "/url/"+created.Alias, "", auth)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
@@ -0,0 +1,131 @@
+package url_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/url/breakdown"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakdownHandler(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	route.Url(r, r, log, &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService})
+
+	// clicks for test
+	visits := []struct {
+		referrer  string
+		userAgent string
+	}{
+		{"https://www.google.com/search", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+		{"https://google.com/", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"},
+		{"", "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"},
+	}
+	for _, visit := range visits {
+		req := httptest.NewRequest(http.MethodGet, "/"+testUrl.ID, nil)
+		req.Header.Set("Referer", visit.referrer)
+		req.Header.Set("User-Agent", visit.userAgent)
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+
+	tests := []struct {
+		name      string
+		dimension string
+		want      breakdown.SuccessResponse
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:      "referrer",
+			dimension: "referrer",
+			want:      breakdown.SuccessResponse{{Value: "google.com", Count: 2}, {Value: "unknown", Count: 1}},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "device",
+			dimension: "device",
+			want:      breakdown.SuccessResponse{{Value: "desktop", Count: 2}, {Value: "mobile", Count: 1}},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "language",
+			dimension: "language",
+			want:      breakdown.SuccessResponse{{Value: "en", Count: 3}},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "country without geoip database",
+			dimension: "country",
+			want:      breakdown.SuccessResponse{{Value: "unknown", Count: 3}},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "unknown dimension",
+			dimension: "ip",
+			wantCode:  http.StatusBadRequest,
+			wantError: "unknown dimension ip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"/breakdown/"+tt.dimension, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+
+			assert.Equal(t, tt.wantCode, res.Code)
+
+			if tt.wantError == "" {
+				var body breakdown.SuccessResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.want, body)
+			} else {
+				var body api.ErrorResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantError, body.Error)
+			}
+		})
+	}
+}
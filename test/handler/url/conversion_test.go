@@ -0,0 +1,136 @@
+package url_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/api"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/conversion/track"
+	"url-shortener/internal/http/handler/url/stats"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversionHandlers(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://example.com/signup"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService}
+	route.Url(r, r, log, deps)
+	route.Conversion(r, log, deps)
+
+	// clicks of two visitors, the redirect passes the token of the conversion beacon to the landing page
+	var tokens []string
+	for range 2 {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+testUrl.ID, nil))
+		require.Equal(t, http.StatusFound, res.Code)
+		assert.Empty(t, res.Result().Cookies())
+
+		location, err := neturl.Parse(res.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/signup", location.Path)
+		token := location.Query().Get(api.ClickTokenParam)
+		require.NotEmpty(t, token)
+		tokens = append(tokens, token)
+	}
+	// HEAD requests don't get it
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodHead, "/"+testUrl.ID, nil))
+	assert.Equal(t, testUrl.Link, res.Header().Get("Location"))
+
+	convert := func(method string, token string) *httptest.ResponseRecorder {
+		target := "/c/" + testUrl.ID
+		if token != "" {
+			target += "?" + api.ClickTokenParam + "=" + neturl.QueryEscape(token)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(method, target, nil))
+		return res
+	}
+
+	t.Run("api", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			token          string
+			wantAttributed bool
+		}{
+			{name: "first visitor", token: tokens[0], wantAttributed: true},
+			{name: "repeated conversion", token: tokens[0], wantAttributed: true},
+			{name: "no token", wantAttributed: false},
+			{name: "invalid token", token: "invalid", wantAttributed: false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				res := convert(http.MethodPost, tt.token)
+				require.Equal(t, http.StatusOK, res.Code)
+
+				var body track.SuccessResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantAttributed, body.Attributed)
+			})
+		}
+	})
+
+	t.Run("pixel", func(t *testing.T) {
+		res := convert(http.MethodGet, tokens[1])
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "image/gif", res.Header().Get("Content-Type"))
+		assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+
+		// the pixel is returned without the token too
+		res = convert(http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var body stats.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		// each counted click converts once
+		today := body[len(body)-1]
+		assert.Equal(t, int64(2), today.Count)
+		assert.Equal(t, int64(2), today.Conversions)
+	})
+}
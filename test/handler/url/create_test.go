@@ -103,6 +103,19 @@ func TestCreateHandler(t *testing.T) {
 			wantCode:   http.StatusBadRequest,
 			wantError:  "field Link is not valid",
 		},
+		{
+			name:       "long link",
+			body:       `{"link":"https://google.com/?q=` + strings.Repeat("0", 1000) + `"}`,
+			authHeader: "Bearer " + token,
+			wantCode:   http.StatusCreated,
+		},
+		{
+			name:       "too long link",
+			body:       `{"link":"https://google.com/?q=` + strings.Repeat("0", 2048) + `"}`,
+			authHeader: "Bearer " + token,
+			wantCode:   http.StatusBadRequest,
+			wantError:  "field Link must be at most 2048 characters long",
+		},
 		{
 			name:       "hacked token with user id that doesn't exist",
 			body:       `{"link":"https://google.com"}`,
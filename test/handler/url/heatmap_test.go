@@ -0,0 +1,107 @@
+package url_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/url/heatmap"
+	"url-shortener/internal/http/handler/url/top"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatmapAndTopHandlers(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	clickStatService := clickstat.New(clickStatRepo, log)
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	route.Url(r, r, log, &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService})
+
+	// clicks for test
+	for _, referrer := range []string{"https://www.google.com/search", "https://google.com/", "https://t.co/abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/"+testUrl.ID, nil)
+		req.Header.Set("Referer", referrer)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+	now := time.Now()
+
+	t.Run("heatmap", func(t *testing.T) {
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		local := now.In(tokyo)
+
+		req := httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"/heatmap?tz=Asia/Tokyo", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var body heatmap.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		assert.Equal(t, "Asia/Tokyo", body.TZ)
+		assert.Equal(t, int64(3), body.Total)
+		// rows start on Monday
+		assert.Equal(t, int64(3), body.Clicks[(int(local.Weekday())+6)%7][local.Hour()])
+
+		// other users' urls aren't found
+		req = httptest.NewRequest(http.MethodGet, "/url/notfound/heatmap", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("top", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"/top?top=1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var body top.SuccessResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		assert.Equal(t, []repo.DimensionCount{{Value: "google.com", Count: 2}}, body.Referrers)
+		assert.Equal(t, []repo.DimensionCount{{Value: "unknown", Count: 3}}, body.Countries)
+
+		req = httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"/top?top=0", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
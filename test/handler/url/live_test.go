@@ -0,0 +1,107 @@
+package url_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/live"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveHandler(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(urlRepo, log)
+	liveService := live.New(repo.NewLiveRepo(db), config.Live{}, log)
+	defer liveService.Close()
+	clickStatService := clickstat.New(clickStatRepo, log, clickstat.WithClickPublisher(liveService))
+
+	// test user
+	user, token, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	// url for test
+	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService, LiveService: liveService}
+	route.Url(r, r, log, deps)
+	route.Stats(r, log, deps)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	open := func(t *testing.T, path string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("headers", func(t *testing.T) {
+		for _, path := range []string{"/url/" + testUrl.ID + "/live", "/stats/live"} {
+			res := open(t, path)
+			res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		res := open(t, "/stats/live")
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		click, err := http.NewRequest(http.MethodGet, server.URL+"/"+testUrl.ID, nil)
+		require.NoError(t, err)
+		click.Header.Set("Referer", "https://www.google.com/")
+		// the redirect isn't followed
+		clickRes, err := (&http.Transport{}).RoundTrip(click)
+		require.NoError(t, err)
+		clickRes.Body.Close()
+
+		scanner := bufio.NewScanner(res.Body)
+		require.True(t, scanner.Scan())
+		assert.Equal(t, "event:click", scanner.Text())
+		require.True(t, scanner.Scan())
+		var event dto.LiveClick
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "data:")), &event))
+		assert.Equal(t, testUrl.ID, event.Alias)
+		assert.Equal(t, "google.com", event.Referrer)
+	})
+
+	t.Run("url of another user", func(t *testing.T) {
+		res := open(t, "/url/notfound/live")
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
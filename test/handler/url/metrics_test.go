@@ -0,0 +1,68 @@
+package url_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	http_server "url-shortener/internal/http"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectMetrics(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users")
+
+	log := slog.Default()
+
+	// services
+	userService := user.New(repo.NewUserRepo(db), log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	urlService := url.New(repo.NewUrlRepo(db), log)
+	clickStatService := clickstat.New(repo.NewClickStatRepo(db), log)
+
+	user, _, err := authService.Register(&dto.CreateUser{Email: "example@gmail.com", Password: "12345678"})
+	require.NoError(t, err)
+	url, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	r := http_server.NewRouter(log, &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService, Metrics: registry})
+
+	for _, alias := range []string{url.ID, url.ID, "notfound"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+	}
+	// api routes are measured too
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil))
+
+	// metrics aren't served by the public router
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+
+	body := res.Body.String()
+	assert.Contains(t, body, `redirects_total{result="found"} 2`)
+	assert.Contains(t, body, `redirects_total{result="not_found"} 1`)
+	assert.Contains(t, body, `redirect_lookup_duration_seconds_count 3`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/:alias",status="302"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/:alias",status="404"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/v1/auth/me",status="401"} 1`)
+}
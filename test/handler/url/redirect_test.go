@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 	"url-shortener/internal/database/repo"
@@ -91,16 +92,34 @@ func TestRedirectHandler(t *testing.T) {
 				}
 				assert.Equal(t, tt.wantError, body.Error)
 			} else {
-				assert.Equal(t, tt.wantLocation, res.Header().Get("Location"))
+				// the click token is passed to the destination
+				assert.True(t, strings.HasPrefix(res.Header().Get("Location"), tt.wantLocation+"?"+api.ClickTokenParam+"="))
 
 				url, err := urlService.ByID(tt.alias)
 				require.NoError(t, err)
 				assert.Equal(t, tt.wantClicks, url.TotalHits)
 
-				stats, err := clickStatService.Stats(tt.alias, user.ID)
+				stats, err := clickStatService.Stats(tt.alias, user.ID, &dto.StatsQuery{})
 				require.NoError(t, err)
 				assert.Equal(t, tt.wantClicks, stats[len(stats)-1].Count)
 			}
 		})
 	}
+
+	t.Run("head", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, fmt.Sprintf("/%s", url.ID), nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusFound, res.Code)
+		assert.Equal(t, url.Link, res.Header().Get("Location"))
+
+		// the click is recorded as a bot
+		stats, err := clickStatService.Stats(url.ID, user.ID, &dto.StatsQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats[len(stats)-1].Count)
+		stats, err = clickStatService.Stats(url.ID, user.ID, &dto.StatsQuery{IncludeBots: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stats[len(stats)-1].Count)
+	})
 }
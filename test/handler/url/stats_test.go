@@ -46,6 +46,8 @@ func TestStatsHandler(t *testing.T) {
 	// urls for test
 	testUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
 	require.NoError(t, err)
+	unclickedUrl, err := urlService.Create(&dto.CreateUrl{Link: "https://google.com"}, user.ID)
+	require.NoError(t, err)
 
 	r := gin.New()
 	route.Url(r, r, log, &handler.Dependencies{UrlService: urlService, JwtService: jwtService, ClickStatService: clickStatService})
@@ -99,6 +101,8 @@ func TestStatsHandler(t *testing.T) {
 				}
 				t.Log("=================", body)
 				assert.Equal(t, tt.simulateClicks, body[len(body)-1].Count)
+				// every click comes from the same test client
+				assert.Equal(t, int64(1), body[len(body)-1].Uniques)
 			} else {
 				// error
 				var body api.ErrorResponse
@@ -110,4 +114,44 @@ func TestStatsHandler(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("zero-filled series", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url/"+unclickedUrl.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var body successType
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		// the last 30 days by default
+		require.Len(t, body, 30)
+		for _, bucket := range body {
+			assert.Zero(t, bucket.Count)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		today := time.Now().UTC().Format(time.DateOnly)
+		req := httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"?from="+today+"&to="+today+"&granularity=hour&tz=UTC", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var body successType
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		require.Len(t, body, 24)
+		var total int64
+		for _, bucket := range body {
+			total += bucket.Count
+		}
+		assert.Equal(t, int64(123), total)
+
+		req = httptest.NewRequest(http.MethodGet, "/url/"+testUrl.ID+"?granularity=year", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
 }
@@ -0,0 +1,151 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/http/handler"
+	"url-shortener/internal/http/handler/webhook/deliveries"
+	"url-shortener/internal/http/handler/webhook/subscribe"
+	"url-shortener/internal/http/route"
+	"url-shortener/internal/model"
+	"url-shortener/internal/model/dto"
+	"url-shortener/internal/service/auth"
+	clickstat "url-shortener/internal/service/click-stat"
+	"url-shortener/internal/service/url"
+	"url-shortener/internal/service/user"
+	"url-shortener/internal/service/webhook"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type received struct {
+	header http.Header
+	event  dto.WebhookEvent
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	db := testdb.New(t)
+	testdb.TruncateTables(t, "users", "urls", "webhooks")
+
+	log := slog.Default()
+
+	// local receiver of deliveries
+	events := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event dto.WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events <- received{r.Header, event}
+	}))
+	defer receiver.Close()
+
+	// services
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	webhookRepo := repo.NewWebhookRepo(db)
+	userService := user.New(userRepo, log)
+	jwtService := auth.NewJWTService("secret", time.Hour)
+	authService := auth.New(userService, jwtService, log)
+	webhookService := webhook.New(webhookRepo, config.Webhooks{Milestones: []int64{2}}, log, webhook.WithHTTPClient(receiver.Client()))
+	urlService := url.New(urlRepo, log, url.WithEventPublisher(webhookService))
+	clickStatService := clickstat.New(clickStatRepo, log, clickstat.WithClickPublisher(webhookService))
+
+	// test user
+	_, token, err := authService.Register(&dto.CreateUser{Email: "alice@example.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	r := gin.New()
+	deps := &handler.Dependencies{
+		UrlService:       urlService,
+		JwtService:       jwtService,
+		ClickStatService: clickStatService,
+		WebhookService:   webhookService,
+	}
+	route.Url(r, r, log, deps)
+	route.Webhook(r, log, deps)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do(http.MethodPost, "/webhooks", fmt.Sprintf(`{"endpoint":"%s","events":["url.created","click.recorded","click.milestone"]}`, receiver.URL))
+	require.Equal(t, http.StatusCreated, res.Code)
+	var hook subscribe.SuccessResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &hook))
+	require.NotEmpty(t, hook.Secret)
+
+	res = do(http.MethodPost, "/webhooks", `{"endpoint":"not a url","events":["url.created"]}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	// url.created
+	res = do(http.MethodPost, "/url", `{"link":"https://google.com"}`)
+	require.Equal(t, http.StatusCreated, res.Code)
+	var created dto.PublicUrl
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+
+	assert.Equal(t, 1, webhookService.DeliverDue(context.Background()))
+	got := <-events
+	assert.Equal(t, model.EventUrlCreated, got.event.Event)
+	assert.Equal(t, model.EventUrlCreated, got.header.Get(webhook.HeaderEvent))
+
+	// click.recorded of each click and click.milestone of the second one
+	for range 2 {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+created.Alias, nil))
+		require.Equal(t, http.StatusFound, res.Code)
+	}
+	assert.Equal(t, 3, webhookService.DeliverDue(context.Background()))
+	var names []string
+	for range 3 {
+		got := <-events
+		names = append(names, got.event.Event)
+	}
+	assert.ElementsMatch(t, []string{model.EventClickRecorded, model.EventClickRecorded, model.EventClickMilestone}, names)
+
+	// delivery log and redelivery
+	res = do(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	var deliveryLog deliveries.SuccessResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &deliveryLog))
+	require.Len(t, deliveryLog, 4)
+	for _, d := range deliveryLog {
+		assert.Equal(t, model.DeliverySucceeded, d.Status)
+		assert.Equal(t, http.StatusOK, d.ResponseStatus)
+	}
+
+	res = do(http.MethodPost, fmt.Sprintf("/webhooks/%s/deliveries/%d/redeliver", hook.ID, deliveryLog[3].ID), "")
+	require.Equal(t, http.StatusAccepted, res.Code)
+	assert.Equal(t, 1, webhookService.DeliverDue(context.Background()))
+	got = <-events
+	assert.Equal(t, model.EventUrlCreated, got.event.Event)
+
+	res = do(http.MethodPost, fmt.Sprintf("/webhooks/%s/deliveries/%d/redeliver", "notfound", deliveryLog[3].ID), "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = do(http.MethodDelete, "/webhooks/"+hook.ID, "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = do(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
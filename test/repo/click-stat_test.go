@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 	"url-shortener/internal/database/repo"
+	"url-shortener/internal/lib/hll"
 	"url-shortener/internal/lib/pg"
 	"url-shortener/internal/model"
 	"url-shortener/internal/testutils/testdb"
@@ -27,42 +28,373 @@ func TestClickStatRepo(t *testing.T) {
 	require.NoError(t, userRepo.Create(user))
 	// create test url
 	url := &model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}
-	require.NoError(t, urlRepo.Create(url))
+	require.NoError(t, urlRepo.Create(url, repoLinkLimits()))
 
 	t.Run("success", func(t *testing.T) {
-		clickStat := &model.ClickStat{UrlID: url.ID}
+		now := time.Now().UTC()
+		clickStat := &model.ClickStat{UrlID: url.ID, CreatedAt: now}
 		// Create
 		err := repo.Create(clickStat)
 		assert.NoError(t, err)
 
 		// clicks for test
-		for range 50 {
-			err := repo.Create(&model.ClickStat{UrlID: url.ID})
-			require.NoError(t, err)
+		clicks := make([]*model.ClickStat, 50)
+		for i := range clicks {
+			clicks[i] = &model.ClickStat{UrlID: url.ID, CreatedAt: now}
 		}
+		// CreateBatch
+		require.NoError(t, repo.CreateBatch(clicks))
 
-		stats, err := repo.ByUrlID(url.ID, user.ID)
+		today := now.Truncate(24 * time.Hour)
+		week := repoStatsRange(today.AddDate(0, 0, -6), today.AddDate(0, 0, 1), "day")
+		stats, err := repo.ByUrlID(url.ID, user.ID, week)
 		assert.NoError(t, err)
-		today := time.Now().Truncate(24 * time.Hour).UTC()
-		assert.Equal(t, today, stats[len(stats)-1].Day)
+		// empty days are zero-filled
+		require.Len(t, stats, 7)
+		assert.Equal(t, int64(0), stats[0].Count)
+		assert.True(t, today.Equal(stats[len(stats)-1].Day))
 		assert.Equal(t, int64(51), stats[len(stats)-1].Count)
 
-		// CleanupStaleRecords
+		// other users' urls aren't found
+		_, err = repo.ByUrlID(url.ID, "5678", week)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// ByUserID, TopUrls, CreatedUrls
+		stats, err = repo.ByUserID(user.ID, week)
+		assert.NoError(t, err)
+		require.Len(t, stats, 7)
+		assert.Equal(t, int64(51), stats[len(stats)-1].Count)
+		top, err := repo.TopUrls(user.ID, week, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []repoUrlClicks{{ID: url.ID, Link: url.Link, Clicks: 51}}, top)
+		top, err = repo.TopUrls("5678", week, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, top)
+		var exported int
+		err = repo.EachClick(url.ID, user.ID, week, func(click *model.ClickStat) error {
+			exported++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 51, exported)
+		err = repo.EachClick(url.ID, "5678", week, func(click *model.ClickStat) error { return nil })
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		created, err := repo.CreatedUrls(user.ID, week.From, week.To)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), created)
+
+		// hours in another time zone
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		hour := now.Truncate(time.Hour)
+		stats, err = repo.ByUrlIDUnchecked(url.ID, repoStatsRangeIn(hour.Add(-2*time.Hour), hour.Add(time.Hour), "hour", tokyo))
+		assert.NoError(t, err)
+		require.Len(t, stats, 3)
+		assert.True(t, hour.Equal(stats[2].Day))
+		assert.Equal(t, tokyo.String(), stats[2].Day.Location().String())
+		assert.Equal(t, int64(51), stats[2].Count)
+
+		// RollupStaleClicks
+		old := now.AddDate(0, 0, -31)
+		// clicks are moved to the partition of their new month
+		_, err = repo.EnsureClickPartitions(old, now)
+		require.NoError(t, err)
 		err = db.Session(&gorm.Session{AllowGlobalUpdate: true}).
-			Model(&model.ClickStat{}).Update("created_at", time.Now().AddDate(0, 0, -31)).Error
+			Model(&model.ClickStat{}).Update("created_at", old).Error
 		require.NoError(t, err)
-		err = repo.CleanupStaleRecords()
+		require.NoError(t, repo.Create(&model.ClickStat{UrlID: url.ID, CreatedAt: now, Browser: "Firefox"}))
+
+		cutoff := now.AddDate(0, 0, -30).Truncate(24 * time.Hour)
+		// the user is on the default plan
+		moved, err := repo.RollupStaleClicks(repoRetentionScope(cutoff, []string{"team"}, false), 20)
 		assert.NoError(t, err)
+		assert.Zero(t, moved)
+		// in batches
+		for _, want := range []int64{20, 20, 11, 0} {
+			moved, err := repo.RollupStaleClicks(repoRetentionScope(cutoff, []string{"team"}, true), 20)
+			assert.NoError(t, err)
+			assert.Equal(t, want, moved)
+		}
 		var records []model.ClickStat
 		err = db.Model(&model.ClickStat{}).Find(&records).Error
 		assert.NoError(t, err)
-		assert.Len(t, records, 0)
+		assert.Len(t, records, 1)
+
+		// old clicks are rolled up and combined with recent ones
+		var rollups []model.ClickRollup
+		require.NoError(t, db.Find(&rollups).Error)
+		require.Len(t, rollups, 1)
+		assert.Equal(t, int64(51), rollups[0].Clicks)
+
+		stats, err = repo.ByUrlID(url.ID, user.ID, repoStatsRange(old.Truncate(24*time.Hour), today.AddDate(0, 0, 1), "month"))
+		assert.NoError(t, err)
+		var total int64
+		for _, bucket := range stats {
+			total += bucket.Count
+		}
+		assert.Equal(t, int64(52), total)
+
+		breakdown, err := repo.Breakdown(url.ID, user.ID, "browser", false)
+		assert.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "unknown", Count: 51}, {Value: "Firefox", Count: 1}}, breakdown)
+
+		// bots are excluded unless included explicitly
+		require.NoError(t, repo.Create(&model.ClickStat{UrlID: url.ID, CreatedAt: now, Browser: "Googlebot", Device: model.DeviceBot, Bot: true}))
+		breakdown, err = repo.Breakdown(url.ID, user.ID, "browser", false)
+		assert.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "unknown", Count: 51}, {Value: "Firefox", Count: 1}}, breakdown)
+		breakdown, err = repo.Breakdown(url.ID, user.ID, "browser", true)
+		assert.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "unknown", Count: 51}, {Value: "Firefox", Count: 1}, {Value: "Googlebot", Count: 1}}, breakdown)
+	})
+
+	t.Run("visitors", func(t *testing.T) {
+		day := time.Now().UTC().Truncate(24 * time.Hour)
+		first, second := hll.New(), hll.New()
+		for i := range uint64(100) {
+			first.Add(i * 0x9e3779b97f4a7c15)
+			second.Add((i + 50) * 0x9e3779b97f4a7c15)
+		}
+
+		// AddVisitors merges sketches of the same day
+		require.NoError(t, repo.AddVisitors(url.ID, day, first))
+		require.NoError(t, repo.AddVisitors(url.ID, day, second))
+
+		sketches, err := repo.VisitorSketches(url.ID, day.AddDate(0, 0, -1), day)
+		require.NoError(t, err)
+		require.Len(t, sketches, 1)
+
+		merged := hll.New()
+		require.NoError(t, merged.UnmarshalBinary(sketches[0].Sketch))
+		assert.InEpsilon(t, 150, merged.Count(), 0.05)
+	})
+
+	t.Run("hits", func(t *testing.T) {
+		hits := &model.Url{ID: "hits", Link: "https://google.com", UserID: user.ID}
+		require.NoError(t, urlRepo.Create(hits, repoLinkLimits()))
+
+		// total hits are incremented with clicks of humans
+		require.NoError(t, repo.Create(&model.ClickStat{UrlID: hits.ID, CreatedAt: time.Now().UTC()}))
+		require.NoError(t, repo.CreateBatch([]*model.ClickStat{
+			{UrlID: hits.ID, CreatedAt: time.Now().UTC()},
+			{UrlID: hits.ID, CreatedAt: time.Now().UTC()},
+			{UrlID: hits.ID, CreatedAt: time.Now().UTC(), Bot: true},
+		}))
+		stored, err := urlRepo.ByID(hits.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stored.TotalHits)
+
+		drifted, _, err := repo.HitsDrift(100)
+		require.NoError(t, err)
+		assert.NotContains(t, drifted, repoHitsDrift{ID: hits.ID, TotalHits: 3, Clicks: 3})
+
+		// the failed batch doesn't increment hits
+		assert.Error(t, repo.CreateBatch([]*model.ClickStat{{UrlID: hits.ID}, {UrlID: "notfound"}}))
+		stored, err = urlRepo.ByID(hits.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stored.TotalHits)
+
+		require.NoError(t, db.Model(&model.Url{}).Where("id = ?", hits.ID).Update("total_hits", 10).Error)
+		drifted, total, err := repo.HitsDrift(100)
+		require.NoError(t, err)
+		assert.Contains(t, drifted, repoHitsDrift{ID: hits.ID, TotalHits: 10, Clicks: 3})
+		assert.Equal(t, int64(len(drifted)), total)
+
+		// urls are repaired in batches after the id
+		repaired, next, err := repo.RepairHits("", 1)
+		require.NoError(t, err)
+		assert.NotEmpty(t, next)
+		rest, next, err := repo.RepairHits(next, 100)
+		require.NoError(t, err)
+		assert.Empty(t, next)
+		repaired = append(repaired, rest...)
+		assert.Contains(t, repaired, repoHitsDrift{ID: hits.ID, TotalHits: 10, Clicks: 3})
+		stored, err = urlRepo.ByID(hits.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stored.TotalHits)
+
+		drifted, total, err = repo.HitsDrift(100)
+		require.NoError(t, err)
+		assert.Empty(t, drifted)
+		assert.Zero(t, total)
+
+		// hits counted before their clicks were kept become the baseline
+		require.NoError(t, db.Model(&model.Url{}).Where("id = ?", hits.ID).Update("total_hits", 10).Error)
+		require.NoError(t, repo.BaselineHits())
+		drifted, _, err = repo.HitsDrift(100)
+		require.NoError(t, err)
+		assert.Empty(t, drifted)
+
+		require.NoError(t, db.Model(&model.Url{}).Where("id = ?", hits.ID).Update("total_hits", 12).Error)
+		repaired, _, err = repo.RepairHits("", 100)
+		require.NoError(t, err)
+		assert.Contains(t, repaired, repoHitsDrift{ID: hits.ID, TotalHits: 12, Baseline: 7, Clicks: 3})
+		stored, err = urlRepo.ByID(hits.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), stored.TotalHits)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		dups := &model.Url{ID: "dups", Link: "https://google.com", UserID: user.ID}
+		require.NoError(t, urlRepo.Create(dups, repoLinkLimits()))
+
+		now := time.Now().UTC()
+		old := now.AddDate(0, 0, -40)
+		_, err := repo.EnsureClickPartitions(old, now)
+		require.NoError(t, err)
+		require.NoError(t, repo.CreateBatch([]*model.ClickStat{
+			{UrlID: dups.ID, CreatedAt: now, Browser: "Chrome"},
+			{UrlID: dups.ID, CreatedAt: now, Browser: "Chrome", Duplicate: true},
+			{UrlID: dups.ID, CreatedAt: old, Browser: "Chrome"},
+			{UrlID: dups.ID, CreatedAt: old, Browser: "Chrome", Duplicate: true},
+			{UrlID: dups.ID, CreatedAt: old, Browser: "Safari", Duplicate: true},
+		}))
+		// duplicates aren't hits
+		stored, err := urlRepo.ByID(dups.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stored.TotalHits)
+
+		// old clicks are rolled up with their duplicates
+		_, err = repo.RollupStaleClicks(repoRetentionScope(now.AddDate(0, 0, -30), nil, true), 100)
+		require.NoError(t, err)
+
+		stats, err := repo.ByUrlID(dups.ID, user.ID, repoStatsRange(old.Truncate(24*time.Hour), now.AddDate(0, 0, 1), "month"))
+		require.NoError(t, err)
+		var count, raw int64
+		for _, bucket := range stats {
+			count += bucket.Count
+			raw += bucket.Raw
+		}
+		assert.Equal(t, int64(2), count)
+		assert.Equal(t, int64(5), raw)
+
+		// values of duplicates only aren't listed
+		breakdown, err := repo.Breakdown(dups.ID, user.ID, "browser", false)
+		require.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "Chrome", Count: 2}}, breakdown)
+
+		drifted, _, err := repo.HitsDrift(100)
+		require.NoError(t, err)
+		assert.Empty(t, drifted)
+
+		// DedupWindows returns only overrides
+		window := 60
+		_, err = urlRepo.SetDedupWindow(dups.ID, user.ID, &window)
+		require.NoError(t, err)
+		windows, err := repo.DedupWindows([]string{dups.ID, url.ID})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]time.Duration{dups.ID: time.Minute}, windows)
 	})
 
 	t.Run("error", func(t *testing.T) {
 		// Create with url id that doesn't exist
-		err := repo.Create(&model.ClickStat{UrlID: "notfound"})
+		err := repo.Create(&model.ClickStat{UrlID: "notfound", CreatedAt: time.Now().UTC()})
 		assert.Equal(t, "23503", pg.ParsePGError(err).Code) // 23503 = foreign_key_violation
 		assert.Equal(t, "fk_urls_click_stats", pg.ParsePGError(err).ConstraintName)
 	})
+
+	t.Run("heatmap", func(t *testing.T) {
+		hot := &model.Url{ID: "hot", Link: "https://google.com", UserID: user.ID}
+		require.NoError(t, urlRepo.Create(hot, repoLinkLimits()))
+
+		now := time.Now().UTC().Truncate(time.Hour)
+		require.NoError(t, repo.CreateBatch([]*model.ClickStat{
+			{UrlID: hot.ID, CreatedAt: now, ReferrerHost: "google.com", Country: "JP"},
+			{UrlID: hot.ID, CreatedAt: now, ReferrerHost: "google.com", Country: "JP", Duplicate: true},
+			{UrlID: hot.ID, CreatedAt: now, ReferrerHost: "t.co"},
+			{UrlID: hot.ID, CreatedAt: now, ReferrerHost: "google.com", Bot: true},
+		}))
+
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		rng := repoStatsRangeIn(now.Add(-time.Hour), now.Add(time.Hour), "day", tokyo)
+		local := now.In(tokyo)
+		cells, err := repo.Heatmap(hot.ID, user.ID, rng)
+		require.NoError(t, err)
+		assert.Equal(t, []repoHeatmapCell{{Weekday: (int(local.Weekday()) + 6) % 7, Hour: local.Hour(), Count: 2}}, cells)
+		_, err = repo.Heatmap(hot.ID, "5678", rng)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// TopValues is limited
+		top, err := repo.TopValues(hot.ID, user.ID, "referrer", rng, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "google.com", Count: 1}}, top)
+		top, err = repo.TopValues(hot.ID, user.ID, "country", rng, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []repoDimensionCount{{Value: "JP", Count: 1}, {Value: "unknown", Count: 1}}, top)
+	})
+
+	t.Run("partitions", func(t *testing.T) {
+		parts := &model.Url{ID: "parts", Link: "https://google.com", UserID: user.ID}
+		require.NoError(t, urlRepo.Create(parts, repoLinkLimits()))
+
+		month := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		created, err := repo.EnsureClickPartitions(month, month.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 2, created)
+		// existing partitions aren't created again
+		created, err = repo.EnsureClickPartitions(month, month.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		assert.Zero(t, created)
+
+		partitions, err := repo.ClickPartitions()
+		require.NoError(t, err)
+		require.NotEmpty(t, partitions)
+		assert.Equal(t, repoClickPartition{Name: "click_stats_202001", From: month, To: month.AddDate(0, 1, 0)}, partitions[0])
+
+		require.NoError(t, repo.CreateBatch([]*model.ClickStat{
+			{UrlID: parts.ID, CreatedAt: month.Add(time.Hour), Browser: "Chrome"},
+			{UrlID: parts.ID, CreatedAt: month.Add(time.Hour), Browser: "Chrome"},
+			{UrlID: parts.ID, CreatedAt: month.Add(2 * time.Hour), Browser: "Chrome", Duplicate: true},
+		}))
+
+		// clicks of a dropped partition are kept as rollups
+		moved, err := repo.DropClickPartition(partitions[0])
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), moved)
+
+		partitions, err = repo.ClickPartitions()
+		require.NoError(t, err)
+		assert.Equal(t, month.AddDate(0, 1, 0), partitions[0].From)
+
+		stats, err := repo.ByUrlID(parts.ID, user.ID, repoStatsRange(month, month.AddDate(0, 1, 0), "month"))
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, int64(2), stats[0].Count)
+		assert.Equal(t, int64(3), stats[0].Raw)
+
+		// clicks of months without partitions can't be saved
+		err = repo.Create(&model.ClickStat{UrlID: parts.ID, CreatedAt: month.Add(time.Hour)})
+		assert.Error(t, err)
+
+		_, err = repo.DropClickPartition(partitions[0])
+		require.NoError(t, err)
+	})
+}
+
+type repoDimensionCount = repo.DimensionCount
+
+type repoUrlClicks = repo.UrlClicks
+
+type repoHitsDrift = repo.HitsDrift
+
+type repoClickPartition = repo.ClickPartition
+
+type repoHeatmapCell = repo.HeatmapCell
+
+// repoLinkLimits returns no limits, the repo package is shadowed in the test
+func repoLinkLimits() repo.LinkLimits {
+	return repo.LinkLimits{}
+}
+
+func repoRetentionScope(cutoff time.Time, plans []string, exclude bool) repo.RetentionScope {
+	return repo.RetentionScope{Cutoff: cutoff, Plans: plans, Exclude: exclude, DefaultPlan: "free"}
+}
+
+func repoStatsRange(from, to time.Time, granularity string) repo.StatsRange {
+	return repoStatsRangeIn(from, to, granularity, time.UTC)
+}
+
+func repoStatsRangeIn(from, to time.Time, granularity string, loc *time.Location) repo.StatsRange {
+	return repo.StatsRange{From: from, To: to, Granularity: granularity, Location: loc}
 }
@@ -0,0 +1,72 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveRepo(t *testing.T) {
+	db := testdb.New(t)
+
+	testdb.TruncateTables(t, "users")
+
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	repo := repo.NewLiveRepo(db)
+
+	// create test user
+	user := &model.User{ID: "1234", Email: "alice@example.com", Password: "12345678"}
+	require.NoError(t, userRepo.Create(user))
+	// create test urls
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}, repoLinkLimits()))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "anonymous", Link: "https://google.com"}, repoLinkLimits()))
+
+	t.Run("owners", func(t *testing.T) {
+		owners, err := repo.Owners([]string{"alias", "anonymous", "notfound"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"alias": user.ID}, owners)
+
+		isOwner, err := repo.IsOwner("alias", user.ID)
+		assert.NoError(t, err)
+		assert.True(t, isOwner)
+		isOwner, err = repo.IsOwner("alias", "5678")
+		assert.NoError(t, err)
+		assert.False(t, isOwner)
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		payloads := make(chan string, 1)
+		done := make(chan error)
+		go func() {
+			done <- repo.Listen(ctx, "test_clicks", func(payload string) {
+				select {
+				case payloads <- payload:
+				default:
+				}
+			})
+		}()
+
+		// the listener may not be listening yet
+		for {
+			require.NoError(t, repo.Notify("test_clicks", "hello"))
+			select {
+			case payload := <-payloads:
+				assert.Equal(t, "hello", payload)
+				cancel()
+				assert.Error(t, <-done)
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+}
@@ -3,6 +3,7 @@ package repo_test
 import (
 	"strconv"
 	"testing"
+	"time"
 	"url-shortener/internal/database/repo"
 	"url-shortener/internal/lib/pg"
 	"url-shortener/internal/model"
@@ -29,7 +30,7 @@ func TestUrlRepo(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		url := &model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}
 		// Create
-		err := repo.Create(url)
+		err := repo.Create(url, repoLinkLimits())
 		assert.NoError(t, err)
 
 		// ByID
@@ -44,17 +45,20 @@ func TestUrlRepo(t *testing.T) {
 		assert.Equal(t, url.Link, link)
 
 		// Delete
-		err = repo.Delete("alias", "1234")
+		deleted, err := repo.Delete("alias", "1234")
 		assert.NoError(t, err)
+		assert.Equal(t, url.Link, deleted.Link)
 		_, err = repo.ByID("alias")
 		assert.ErrorIs(t, gorm.ErrRecordNotFound, err)
+		_, err = repo.Delete("alias", "1234")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 
 		// create urls for test
 		testUrls := []model.Url{}
 		for i := range 10 {
 			url := model.Url{ID: "alias" + strconv.Itoa(i), Link: "https://google.com", UserID: user.ID}
 			testUrls = append(testUrls, url)
-			err := repo.Create(&url)
+			err := repo.Create(&url, repoLinkLimits())
 			assert.NoError(t, err)
 		}
 		// ByUserID
@@ -69,15 +73,15 @@ func TestUrlRepo(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		// Create
-		err := repo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID})
+		err := repo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}, repoLinkLimits())
 		assert.NoError(t, err)
 
 		// Create duplicate
-		err = repo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID})
+		err = repo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}, repoLinkLimits())
 		assert.Equal(t, "23505", pg.ParsePGError(err).Code)
 
 		// Create with a user ID that does not exist
-		err = repo.Create(&model.Url{ID: "new-alias", Link: "https://google.com", UserID: "notfound"})
+		err = repo.Create(&model.Url{ID: "new-alias", Link: "https://google.com", UserID: "notfound"}, repoLinkLimits())
 		assert.Equal(t, "23503", pg.ParsePGError(err).Code) // 23503 = foreign_key_violation
 		assert.Equal(t, "fk_users_urls", pg.ParsePGError(err).ConstraintName)
 
@@ -86,3 +90,92 @@ func TestUrlRepo(t *testing.T) {
 		assert.ErrorIs(t, gorm.ErrRecordNotFound, err)
 	})
 }
+
+func TestUrlRepo_History(t *testing.T) {
+	db := testdb.New(t)
+
+	testdb.TruncateTables(t, "users", "urls")
+
+	userRepo := repo.NewUserRepo(db)
+	clickStatRepo := repo.NewClickStatRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+
+	user := &model.User{ID: "1234", Email: "alice@example.com", Password: "12345678"}
+	require.NoError(t, userRepo.Create(user))
+	createdAt := time.Now().UTC().AddDate(0, 0, -3)
+	url := &model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID, CreatedAt: createdAt}
+	require.NoError(t, urlRepo.Create(url, repo.LinkLimits{}))
+
+	for range 2 {
+		require.NoError(t, clickStatRepo.Create(&model.ClickStat{UrlID: url.ID, CreatedAt: time.Now().UTC()}))
+	}
+	// rolled up clicks and duplicates of a day after the creation
+	rolledUpDay := time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day()+1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&model.ClickRollup{UrlID: url.ID, Day: rolledUpDay, Clicks: 3, Duplicates: 1}).Error)
+
+	// UpdateLink
+	updated, err := urlRepo.UpdateLink(url.ID, user.ID, "https://example.com", model.HistoryUpdate)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", updated.Link)
+
+	require.NoError(t, clickStatRepo.Create(&model.ClickStat{UrlID: url.ID, CreatedAt: time.Now().UTC()}))
+
+	// same link doesn't make a new entry
+	_, err = urlRepo.UpdateLink(url.ID, user.ID, "https://example.com", model.HistoryUpdate)
+	require.NoError(t, err)
+
+	// url of another user
+	_, err = urlRepo.UpdateLink(url.ID, "notfound", "https://example.com", model.HistoryUpdate)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// History
+	history, err := urlRepo.History(url.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	assert.Equal(t, model.HistoryUpdate, history[0].Action)
+	assert.Equal(t, "https://google.com", history[0].OldValue)
+	assert.Equal(t, "https://example.com", history[0].NewValue)
+	assert.Equal(t, int64(1), history[0].Clicks)
+
+	assert.Equal(t, model.HistoryCreate, history[1].Action)
+	assert.Equal(t, "https://google.com", history[1].NewValue)
+	assert.Equal(t, int64(6), history[1].Clicks)
+	assert.WithinDuration(t, createdAt, history[1].CreatedAt, time.Second)
+
+	// HistoryByID
+	entry, err := urlRepo.HistoryByID(history[1].ID, url.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", entry.NewValue)
+	// entries of urls of other users aren't returned
+	_, err = urlRepo.HistoryByID(history[1].ID, url.ID, "notfound")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	history, err = urlRepo.History(url.ID, "notfound")
+	require.NoError(t, err)
+	assert.Len(t, history, 0)
+
+	// SetDedupWindow
+	window := 60
+	updated, err = urlRepo.SetDedupWindow(url.ID, user.ID, &window)
+	require.NoError(t, err)
+	assert.Equal(t, &window, updated.DedupWindow)
+	// the same window doesn't make a new entry
+	_, err = urlRepo.SetDedupWindow(url.ID, user.ID, &window)
+	require.NoError(t, err)
+	_, err = urlRepo.SetDedupWindow(url.ID, user.ID, nil)
+	require.NoError(t, err)
+
+	history, err = urlRepo.History(url.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.Equal(t, "dedup_window", history[0].Field)
+	assert.Equal(t, "60", history[0].OldValue)
+	assert.Empty(t, history[0].NewValue)
+	assert.Equal(t, "dedup_window", history[1].Field)
+	assert.Empty(t, history[1].OldValue)
+	assert.Equal(t, "60", history[1].NewValue)
+	// settings changes don't take clicks of the destination
+	assert.Equal(t, int64(0), history[1].Clicks)
+	assert.Equal(t, int64(1), history[2].Clicks)
+}
@@ -0,0 +1,112 @@
+package repo_test
+
+import (
+	"testing"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUsageRepo(t *testing.T) {
+	db := testdb.New(t)
+
+	testdb.TruncateTables(t, "users", "urls", "monthly_usages")
+
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	usageRepo := repo.NewUsageRepo(db)
+
+	user := &model.User{ID: "1234", Email: "alice@example.com", Password: "12345678", Plan: "team"}
+	require.NoError(t, userRepo.Create(user))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID, CustomAlias: true}, repo.LinkLimits{}))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "generated", Link: "https://google.com", UserID: user.ID}, repo.LinkLimits{}))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "anonymous", Link: "https://google.com"}, repo.LinkLimits{}))
+
+	// AddClicks
+	require.NoError(t, usageRepo.AddClicks(map[string]int64{"alias": 2, "generated": 1, "anonymous": 5}))
+	require.NoError(t, usageRepo.AddClicks(map[string]int64{}))
+
+	// ClickUsages
+	usages, err := usageRepo.ClickUsages([]string{"alias", "generated", "anonymous"})
+	require.NoError(t, err)
+	// the anonymous url has no owner
+	require.Len(t, usages, 2)
+	for _, usage := range usages {
+		assert.Equal(t, user.ID, usage.UserID)
+		assert.Equal(t, "team", usage.Plan)
+		assert.Equal(t, int64(3), usage.MonthlyClicks)
+	}
+
+	// ByUserID
+	got, err := usageRepo.ByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &repo.Usage{Plan: "team", Links: 2, Aliases: 1, MonthlyClicks: 3}, got)
+
+	_, err = usageRepo.ByUserID("notfound")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMovedLinksQuota(t *testing.T) {
+	db := testdb.New(t)
+
+	testdb.TruncateTables(t, "users", "urls", "url_transfers")
+
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	transferRepo := repo.NewUrlTransferRepo(db)
+
+	alice := &model.User{ID: "1234", Email: "alice@example.com", Password: "12345678"}
+	bob := &model.User{ID: "5678", Email: "bob@example.com", Password: "12345678"}
+	require.NoError(t, userRepo.Create(alice))
+	require.NoError(t, userRepo.Create(bob))
+	// bob has one url with an alias
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "bobs", Link: "https://google.com", UserID: bob.ID, CustomAlias: true}, repo.LinkLimits{}))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "anonymous", Link: "https://google.com", ClaimTokenHash: "hash"}, repo.LinkLimits{}))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: alice.ID, CustomAlias: true}, repo.LinkLimits{}))
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "generated", Link: "https://google.com", UserID: alice.ID}, repo.LinkLimits{}))
+
+	t.Run("claim", func(t *testing.T) {
+		err := urlRepo.Claim("anonymous", "hash", bob.ID, repo.LinkLimits{MaxLinks: 1})
+		assert.ErrorIs(t, err, repo.ErrLinkQuotaExceeded)
+		// the claim is rolled back
+		url, err := urlRepo.ByID("anonymous")
+		require.NoError(t, err)
+		assert.Empty(t, url.UserID)
+
+		// urls without aliases aren't limited by the alias limit
+		require.NoError(t, urlRepo.Claim("anonymous", "hash", bob.ID, repo.LinkLimits{MaxLinks: 2, MaxAliases: 1}))
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		transfer := &model.UrlTransfer{ID: "transfer", FromUserID: alice.ID, ToUserID: bob.ID, Items: []model.UrlTransferItem{{UrlID: "alias"}, {UrlID: "generated"}}}
+		require.NoError(t, transferRepo.Create(transfer))
+
+		err := transferRepo.Accept("transfer", bob.ID, repo.LinkLimits{MaxLinks: 10, MaxAliases: 1})
+		assert.ErrorIs(t, err, repo.ErrAliasQuotaExceeded)
+		err = transferRepo.Accept("transfer", bob.ID, repo.LinkLimits{MaxLinks: 3})
+		assert.ErrorIs(t, err, repo.ErrLinkQuotaExceeded)
+		// the urls stay with the sender
+		url, err := urlRepo.ByID("alias")
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, url.UserID)
+
+		require.NoError(t, transferRepo.Accept("transfer", bob.ID, repo.LinkLimits{MaxLinks: 4, MaxAliases: 2}))
+	})
+
+	t.Run("create", func(t *testing.T) {
+		err := urlRepo.Create(&model.Url{ID: "new", Link: "https://google.com", UserID: bob.ID}, repo.LinkLimits{MaxLinks: 4})
+		assert.ErrorIs(t, err, repo.ErrLinkQuotaExceeded)
+		// the creation is rolled back
+		_, err = urlRepo.ByID("new")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = urlRepo.Create(&model.Url{ID: "new", Link: "https://google.com", UserID: bob.ID, CustomAlias: true}, repo.LinkLimits{MaxLinks: 5, MaxAliases: 2})
+		assert.ErrorIs(t, err, repo.ErrAliasQuotaExceeded)
+
+		require.NoError(t, urlRepo.Create(&model.Url{ID: "new", Link: "https://google.com", UserID: bob.ID}, repo.LinkLimits{MaxLinks: 5, MaxAliases: 2}))
+	})
+}
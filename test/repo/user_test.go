@@ -33,7 +33,7 @@ func TestUserRepo(t *testing.T) {
 		urls := make([]model.Url, 20)
 		for i := range 20 {
 			urls[i] = model.Url{ID: "a" + strconv.Itoa(i), Link: "https://google.com", UserID: user.ID}
-			err := urlRepo.Create(&urls[i])
+			err := urlRepo.Create(&urls[i], repo.LinkLimits{})
 			require.NoError(t, err)
 		}
 
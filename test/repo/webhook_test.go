@@ -0,0 +1,116 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+	"url-shortener/internal/database/repo"
+	"url-shortener/internal/model"
+	"url-shortener/internal/testutils/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWebhookRepo(t *testing.T) {
+	db := testdb.New(t)
+
+	testdb.TruncateTables(t, "users")
+
+	userRepo := repo.NewUserRepo(db)
+	urlRepo := repo.NewUrlRepo(db)
+	repo := repo.NewWebhookRepo(db)
+
+	// create test user
+	user := &model.User{ID: "1234", Email: "alice@example.com", Password: "12345678"}
+	require.NoError(t, userRepo.Create(user))
+	// create test url
+	require.NoError(t, urlRepo.Create(&model.Url{ID: "alias", Link: "https://google.com", UserID: user.ID}, repoLinkLimits()))
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	hook := &model.Webhook{ID: "w1", UserID: user.ID, Endpoint: "https://example.com", Secret: "secret", Events: "click.recorded,url.created", CreatedAt: now}
+	require.NoError(t, repo.Create(hook))
+	require.NoError(t, repo.Create(&model.Webhook{ID: "w2", UserID: user.ID, Endpoint: "https://example.com", Secret: "secret", Events: "url.deleted", CreatedAt: now}))
+
+	t.Run("subscribers", func(t *testing.T) {
+		ids, err := repo.Subscribers(user.ID, model.EventUrlCreated)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"w1"}, ids)
+
+		subscribers, err := repo.UrlSubscribers(model.EventClickRecorded, []string{"alias", "notfound"})
+		assert.NoError(t, err)
+		assert.Equal(t, []repoUrlWebhook{{UrlID: "alias", WebhookID: "w1"}}, subscribers)
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		deliveries := []*model.WebhookDelivery{
+			{WebhookID: "w1", Event: model.EventUrlCreated, Payload: "{}", Status: model.DeliveryPending, NextAttemptAt: now, CreatedAt: now},
+			{WebhookID: "w1", Event: model.EventUrlCreated, Payload: "{}", Status: model.DeliveryPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now},
+		}
+		require.NoError(t, repo.Enqueue(deliveries))
+
+		// only due deliveries are claimed, once until the lease ends
+		claimed, err := repo.ClaimDue(now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, deliveries[0].ID, claimed[0].ID)
+		assert.Equal(t, 1, claimed[0].Attempts)
+		assert.Equal(t, hook.Endpoint, claimed[0].Webhook.Endpoint)
+
+		claimed, err = repo.ClaimDue(now, time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		claimed, err = repo.ClaimDue(now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, 2, claimed[0].Attempts)
+
+		delivery := claimed[0]
+		delivery.Status = model.DeliverySucceeded
+		delivery.ResponseStatus = 200
+		delivery.LastAttemptAt = &now
+		require.NoError(t, repo.Finish(&delivery))
+
+		log, err := repo.Deliveries("w1", user.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, log, 2)
+		assert.Equal(t, model.DeliverySucceeded, log[1].Status)
+
+		_, err = repo.Deliveries("w1", "5678", 10)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		redelivered, err := repo.Redeliver(delivery.ID, "w1", user.ID, now)
+		require.NoError(t, err)
+		assert.Equal(t, model.DeliveryPending, redelivered.Status)
+		assert.NotEqual(t, delivery.ID, redelivered.ID)
+
+		_, err = repo.Redeliver(delivery.ID, "w1", "5678", now)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("milestones", func(t *testing.T) {
+		require.NoError(t, db.Model(&model.Url{}).Where("id = ?", "alias").Update("total_hits", 150).Error)
+
+		url, reached, err := repo.AdvanceMilestone("alias", []int64{10, 100, 1000})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(150), url.TotalHits)
+		assert.Equal(t, []int64{10, 100}, reached)
+
+		// each milestone is reported once
+		_, reached, err = repo.AdvanceMilestone("alias", []int64{10, 100, 1000})
+		assert.NoError(t, err)
+		assert.Empty(t, reached)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, repo.Delete("w1", user.ID))
+		assert.ErrorIs(t, repo.Delete("w1", user.ID), gorm.ErrRecordNotFound)
+
+		var deliveries int64
+		require.NoError(t, db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", "w1").Count(&deliveries).Error)
+		assert.Zero(t, deliveries)
+	})
+}
+
+type repoUrlWebhook = repo.UrlWebhook